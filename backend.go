@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Backend is the method set shared by all store implementations. Store talks to a redis
+// server, MemoryStore keeps the data in process. Code that depends on Backend instead of
+// *Store can be tested against a MemoryStore.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Backend interface {
+	Set(key string, value interface{}) error
+	SetWithTTL(key string, value interface{}, ttl int) error
+	SetWithExpiry(key string, value interface{}, ttl time.Duration) error
+	SetNX(key string, value interface{}, ttl time.Duration) (bool, error)
+	SetXX(key string, value interface{}, ttl time.Duration) (bool, error)
+	SetKeepTTL(key string, value interface{}) error
+	GetSet(key string, value interface{}) (interface{}, bool, error)
+	GetSetInto(key string, value interface{}, dst interface{}) (bool, error)
+	GetDel(key string) (interface{}, error)
+	GetDelInto(key string, dst interface{}) error
+	Get(key string) (interface{}, error)
+	GetInto(key string, dst interface{}) error
+	Delete(keys ...string) error
+	Unlink(keys ...string) error
+	MGet(keys ...string) ([]interface{}, []bool, error)
+	MGetInto(dst interface{}, keys ...string) ([]bool, error)
+	MSet(values map[string]interface{}) error
+	MSetNX(values map[string]interface{}) (bool, error)
+	Enumerate(cursor int, match string, count int) (int, []string, error)
+	EnumerateKeys(match string, enumFunc EnumFunc) error
+	Keys(ctx context.Context, match string, opts ScanOptions) iter.Seq2[string, error]
+	DecodeValues(values []interface{}) ([]interface{}, error)
+	DecodeValuesInto(values []interface{}, dst interface{}) error
+
+	HashSet(hash, key string, value interface{}, ttl ...time.Duration) error
+	HashGet(hash, key string) (interface{}, error)
+	HashGetInto(hash, key string, dst interface{}) error
+	HashGetAllInto(hash string, dst interface{}) error
+	HashGetFields(hash string) ([]string, error)
+	HashGetValues(hash string) ([]interface{}, error)
+	HashGetValuesInto(hash string, dst interface{}) error
+	HashSize(hash string) (int, error)
+	HashDeleteField(hash, field string) (int, error)
+	HashEnumerateFields(hash string, enumerate FieldsEnumFunc) error
+	HashEnumerateValues(hash string, enumerate ValuesEnumFunc) error
+
+	ListPush(list, key string, value interface{}) error
+	ListPushLeft(list string, values ...interface{}) (int, error)
+	ListPushRight(list string, values ...interface{}) (int, error)
+	ListPopLeft(list string) (interface{}, error)
+	ListPopLeftInto(list string, dst interface{}) error
+	ListPopRight(list string) (interface{}, error)
+	ListPopRightInto(list string, dst interface{}) error
+	ListIndex(list string, index int) (interface{}, error)
+	ListIndexInto(list string, index int, dst interface{}) error
+	ListRange(list string, start, stop int) ([]interface{}, error)
+	ListRangeInto(list string, start, stop int, dst interface{}) error
+	ListLen(list string) (int, error)
+	ListSet(list string, index int, value interface{}) error
+	ListRemove(list string, count int, value interface{}) (int, error)
+	ListTrim(list string, start, stop int) error
+	ListInsert(list string, before bool, pivot, value interface{}) (int, error)
+	ListMove(source, destination string, from, to ListEnd) (interface{}, error)
+	ListMoveInto(source, destination string, from, to ListEnd, dst interface{}) error
+	ListBlockingPop(ctx context.Context, timeout time.Duration, lists ...string) (string, interface{}, error)
+	ListBlockingPopInto(ctx context.Context, timeout time.Duration, dst interface{}, lists ...string) (string, error)
+	ListBlockingPopRight(ctx context.Context, timeout time.Duration, lists ...string) (string, interface{}, error)
+	ListBlockingPopRightInto(ctx context.Context, timeout time.Duration, dst interface{}, lists ...string) (string, error)
+	ListBlockingMove(ctx context.Context, timeout time.Duration, source, destination string, from, to ListEnd) (interface{}, error)
+	ListBlockingMoveInto(ctx context.Context, timeout time.Duration, source, destination string, from, to ListEnd, dst interface{}) error
+
+	SetSet(set string, member string) (int, error)
+	SetDelete(set string, member string) (int, error)
+	SetAdd(set string, members ...interface{}) (int, error)
+	SetRemove(set string, members ...interface{}) (int, error)
+	SetMembers(set string) ([]interface{}, error)
+	SetMembersInto(set string, dst interface{}) error
+	SetIsMember(set string, member interface{}) (bool, error)
+	SetSize(set string) (int, error)
+	SetRandomMember(set string) (interface{}, error)
+	SetRandomMemberInto(set string, dst interface{}) error
+	SetPop(set string) (interface{}, error)
+	SetPopInto(set string, dst interface{}) error
+	SetMove(source, destination string, member interface{}) (bool, error)
+	SetInter(sets ...string) ([]interface{}, error)
+	SetInterInto(dst interface{}, sets ...string) error
+	SetUnion(sets ...string) ([]interface{}, error)
+	SetUnionInto(dst interface{}, sets ...string) error
+	SetDiff(sets ...string) ([]interface{}, error)
+	SetDiffInto(dst interface{}, sets ...string) error
+	SetInterStore(destination string, sets ...string) (int, error)
+	SetUnionStore(destination string, sets ...string) (int, error)
+	SetDiffStore(destination string, sets ...string) (int, error)
+
+	SortedSetSet(set string, score float64, value interface{}, ttl ...time.Duration) (int, error)
+	SortedSetSize(set string, scoreMin float64, scoreMax float64) (int, error)
+	SortedSetGet(set string, scoreMin float64, scoreMax float64) ([]interface{}, error)
+	SortedSetGetInto(set string, scoreMin float64, scoreMax float64, dst interface{}) error
+	SortedSetGetAsc(set string, scoreMin float64, scoreMax float64) ([]interface{}, error)
+	SortedSetGetAscInto(set string, scoreMin float64, scoreMax float64, dst interface{}) error
+	SortedSetGetDesc(set string, scoreMin float64, scoreMax float64) ([]interface{}, error)
+	SortedSetGetDescInto(set string, scoreMin float64, scoreMax float64, dst interface{}) error
+	SortedSetDeleteByScore(key string, scoreMin float64, scoreMax float64) (int, error)
+	SortedSetDeleteAll(key string) (int, error)
+	SortedSetGetAll(key string) ([]interface{}, error)
+	SortedSetGetAllInto(key string, dst interface{}) error
+
+	Incr(key string) (int64, error)
+	Decr(key string) (int64, error)
+	IncrBy(key string, n int64) (int64, error)
+	IncrByFloat(key string, f float64) (float64, error)
+	HashIncrBy(hash, field string, n int64) (int64, error)
+	HashIncrByFloat(hash, field string, f float64) (float64, error)
+
+	Expire(key string, ttl time.Duration) (bool, error)
+	ExpireAt(key string, at time.Time) (bool, error)
+	TTL(key string) (time.Duration, error)
+	Persist(key string) (bool, error)
+
+	NewBatch() *Batch
+	Tx(watchKeys []string, fn func(tx *Tx) error) error
+
+	Context() context.Context
+	Close() error
+}
+
+var (
+	_ Backend = (*Store)(nil)
+	_ Backend = (*MemoryStore)(nil)
+)
@@ -1,8 +1,13 @@
 package store
 
 import (
+	"errors"
+	"fmt"
 	"github.com/garyburd/redigo/redis"
-	"github.com/vmihailenco/msgpack"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
 )
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
@@ -11,61 +16,172 @@ import (
 // Returns error if operation isn't successfull.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) Set(key string, value interface{}) error {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	if err := conn.Err(); err != nil {
+	b, err := s.encode(value)
+	if err != nil {
 		return err
 	}
 
-	b, err := msgpack.Marshal(value)
+	_, err = s.do("SET", key, b)
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Store a value with ttl.
+//
+// Deprecated: SetWithTTL takes the ttl in seconds, use SetWithExpiry.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetWithTTL(key string, value interface{}, ttl int) error {
+	b, err := s.encode(value)
 	if err != nil {
 		return err
 	}
 
-	_, err := conn.Do("SET", key, b)
+	_, err = s.do("SETEX", key, ttl, b)
 	return err
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
-// Store a value with ttl.
+// SetWithExpiry stores a value that expires after ttl, which must be positive. The ttl has
+// millisecond precision, shorter durations are rounded up.
 ////////////////////////////////////////////////////////////////////////////////////////////////
-func (s *Store) SetWithTTL(key string, value interface{}, ttl int) error {
-	conn := s.Pool.Get()
-	defer conn.Close()
+func (s *Store) SetWithExpiry(key string, value interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("store: invalid ttl %v, must be positive", ttl)
+	}
 
-	if err := conn.Err(); err != nil {
-		return err
+	_, err := s.set(key, value, ttl)
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// SetNX stores a value only if key does not exist and reports whether it has been stored. A
+// positive ttl lets the value expire, 0 stores it without expiry.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	return s.set(key, value, ttl, "NX")
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// SetXX stores a value only if key exists and reports whether it has been stored. A positive
+// ttl lets the value expire, 0 discards the time to live of key.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetXX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	return s.set(key, value, ttl, "XX")
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// SetKeepTTL stores a value like Set, but keeps the time to live of an existing key.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetKeepTTL(key string, value interface{}) error {
+	_, err := s.set(key, value, 0, "KEEPTTL")
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// set runs SET with the given options and reports whether the value has been stored.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) set(key string, value interface{}, ttl time.Duration, opts ...interface{}) (bool, error) {
+	px, err := expiryArgs(ttl)
+	if err != nil {
+		return false, err
 	}
 
-	b, err := msgpack.Marshal(value)
+	b, err := s.encode(value)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	_, err = conn.Do("SETEX", key, ttl, b)
-	return err
+	args := append([]interface{}{key, b}, px...)
+	reply, err := s.do("SET", append(args, opts...)...)
+	if err != nil {
+		return false, err
+	}
+
+	return reply != nil, nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
-// Get a stored value. A missing value will return nil, nil.
+// expiryArgs returns the PX option of ttl, none for 0. The milliseconds are rounded up.
 ////////////////////////////////////////////////////////////////////////////////////////////////
-func (s *Store) Get(key string) (interface{}, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
+func expiryArgs(ttl time.Duration) ([]interface{}, error) {
+	switch {
+	case ttl < 0:
+		return nil, fmt.Errorf("store: invalid ttl %v, must not be negative", ttl)
+	case ttl == 0:
+		return nil, nil
+	}
+
+	return []interface{}{"PX", millis(ttl)}, nil
+}
+
+// millis returns d in milliseconds, rounded up.
+func millis(d time.Duration) int64 {
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// GetSet atomically replaces the value of key and returns the previous value. found is false
+// if key did not exist; the value is stored regardless. The time to live is discarded.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) GetSet(key string, value interface{}) (old interface{}, found bool, err error) {
+	found, err = s.GetSetInto(key, value, &old)
+	return old, found, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// GetSetInto replaces the value of key like GetSet and decodes the previous value into the
+// value pointed to by dst, which is left untouched if key did not exist.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) GetSetInto(key string, value interface{}, dst interface{}) (found bool, err error) {
+	b, err := s.encode(value)
+	if err != nil {
+		return false, err
+	}
+
+	data, err := s.do("GETSET", key, b)
+	if err != nil || data == nil {
+		return false, err
+	}
 
-	if err := conn.Err(); err != nil {
+	return true, s.decode(key, "", data, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// GetDel atomically gets and deletes the value of key. A missing value will return
+// ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) GetDel(key string) (interface{}, error) {
+	var out interface{}
+	if err := s.GetDelInto(key, &out); err != nil {
 		return nil, err
 	}
 
-	data, err := conn.Do("GET", key)
-	b, err := redis.Bytes(data, err)
+	return out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// GetDelInto gets and deletes the value of key like GetDel and decodes it into the value
+// pointed to by dst. A missing value will return ErrNotFound and leaves dst untouched.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) GetDelInto(key string, dst interface{}) error {
+	data, err := s.do("GETDEL", key)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if data == nil {
+		return ErrNotFound
 	}
 
+	return s.decode(key, "", data, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Get a stored value. A missing value will return ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) Get(key string) (interface{}, error) {
 	var out interface{}
-	if err = msgpack.Unmarshal(b, &out); err != nil {
+	if err := s.GetInto(key, &out); err != nil {
 		return nil, err
 	}
 
@@ -73,67 +189,216 @@ func (s *Store) Get(key string) (interface{}, error) {
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
-// Delete a value
+// GetInto decodes a stored value into the value pointed to by dst.
+// A missing value will return ErrNotFound and leaves dst untouched.
 ////////////////////////////////////////////////////////////////////////////////////////////////
-func (s *Store) Delete(key string) error {
-	conn := s.Pool.Get()
-	defer conn.Close()
+func (s *Store) GetInto(key string, dst interface{}) error {
+	data, err := s.do("GET", key)
+	if err != nil {
+		return err
+	}
+
+	if data == nil {
+		return ErrNotFound
+	}
+
+	return s.decode(key, "", data, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// MGet gets the values of keys with a single MGET. The values are aligned with keys, found
+// reports which keys exist; the value of a missing key is nil. On a cluster a MGET is sent for
+// every slot.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) MGet(keys ...string) (values []interface{}, found []bool, err error) {
+	found, err = s.MGetInto(&values, keys...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return values, found, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// MGetInto decodes the values of keys into the slice pointed to by dst, which is resized to
+// the number of keys. Elements of missing keys are left at their zero value, found reports
+// which keys exist.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) MGetInto(dst interface{}, keys ...string) (found []bool, err error) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("store: decode target must be a non-nil pointer to a slice, got %T", dst)
+	}
+
+	out := reflect.MakeSlice(rv.Elem().Type(), len(keys), len(keys))
+	found = make([]bool, len(keys))
+
+	if len(keys) > 0 {
+		groups, replies, err := s.doKeys(keys, "MGET", func(idx []int) []interface{} {
+			return keyArgs(keys, idx)
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i, group := range groups {
+			vals, err := redis.Values(replies[i], nil)
+			if err != nil {
+				return nil, err
+			}
+
+			for j, n := range group {
+				if vals[j] == nil {
+					continue
+				}
+
+				found[n] = true
+				if err := s.decode(keys[n], "", vals[j], out.Index(n).Addr().Interface()); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	rv.Elem().Set(out)
+	return found, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// MSet sets all keys to their values with a single MSET, discarding their time to live. No
+// value is written if one fails to encode. On a cluster a MSET is sent for every slot, so the
+// keys of different slots are not set atomically.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) MSet(values map[string]interface{}) error {
+	keys, data, err := s.encodeValues(values)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+
+	_, _, err = s.doKeys(keys, "MSET", func(idx []int) []interface{} {
+		args := make([]interface{}, 0, 2*len(idx))
+		for _, n := range idx {
+			args = append(args, keys[n], data[n])
+		}
+
+		return args
+	})
 
-	_, err := conn.Do("DEL", key)
 	return err
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
-// Enumerate
+// MSetNX sets all keys to their values only if none of them exists and reports whether they
+// have been set. On a cluster all keys must map to the same slot, see TaggedKey.
 ////////////////////////////////////////////////////////////////////////////////////////////////
-func (s *Store) Enumerate(cursor int, match string, count int) (int, []string, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
+func (s *Store) MSetNX(values map[string]interface{}) (bool, error) {
+	keys, data, err := s.encodeValues(values)
+	if err != nil || len(keys) == 0 {
+		return false, err
+	}
 
-	data, err := conn.Do("SCAN", cursor, "MATCH", match, "COUNT", count)
-	if err != nil {
-		return 0, nil, err
+	args := make([]interface{}, 0, 2*len(keys))
+	for n, key := range keys {
+		args = append(args, key, data[n])
 	}
 
-	if data == nil {
-		return 0, nil, nil
+	return redis.Bool(s.do("MSETNX", args...))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// encodeValues encodes the values of a map, returning the sorted keys and the aligned data.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) encodeValues(values map[string]interface{}) ([]string, [][]byte, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
 	}
 
-	vals, err := redis.Values(data, err)
+	sort.Strings(keys)
+
+	data := make([][]byte, len(keys))
+	for n, key := range keys {
+		b, err := s.encode(values[key])
+		if err != nil {
+			return nil, nil, fmt.Errorf("store: encode value of %s: %w", key, err)
+		}
+
+		data[n] = b
+	}
+
+	return keys, data, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Delete removes the keys, missing keys are ignored. On a cluster a DEL is sent for every
+// slot.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) Delete(keys ...string) error {
+	return s.deleteKeys("DEL", keys)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Unlink removes the keys like Delete, but the server reclaims their memory in the background.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) Unlink(keys ...string) error {
+	return s.deleteKeys("UNLINK", keys)
+}
+
+func (s *Store) deleteKeys(cmd string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, _, err := s.doKeys(keys, cmd, func(idx []int) []interface{} {
+		return keyArgs(keys, idx)
+	})
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Enumerate runs a single SCAN step and returns the next cursor, which is 0 once the
+// iteration is complete. It is not supported on a cluster, use Keys instead.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) Enumerate(cursor int, match string, count int) (int, []string, error) {
+	if s.cluster != nil {
+		return 0, nil, errors.New("store: Enumerate is not supported on a cluster, use Keys")
+	}
+
+	next, keys, err := parseScanPage(s.do("SCAN", cursor, "MATCH", match, "COUNT", count))
 	if err != nil {
 		return 0, nil, err
 	}
 
-	return vals[0].(int), vals[1].([]string), nil
+	n, err := strconv.ParseUint(next, 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("store: invalid SCAN cursor %q", next)
+	}
+
+	return int(n), keys, nil
 }
 
 type EnumFunc func(idx int, key string) error
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
-// EnumerateKeys
+// EnumerateKeys calls enumFunc for all keys matching match, idx counting the keys enumerated
+// so far. On a cluster every master is scanned.
+//
+// Deprecated: use Keys, which can be stopped by breaking out of the loop.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) EnumerateKeys(match string, enumFunc EnumFunc) error {
-
-	var (
-		cursor = 0
-		res    []string
-		err    error
-	)
-
-	for cursor != -1 {
-		cursor, res, err = s.Enumerate(cursor, match, 10)
+	idx := 0
+	for key, err := range s.Keys(s.Context(), match, ScanOptions{}) {
 		if err != nil {
 			return err
 		}
 
-		if len(res) > 0 {
-			for idx, key := range res {
-				err := enumFunc(idx, key)
-				if err != nil {
-					return err
-				}
-			}
+		if err := enumFunc(idx, key); err != nil {
+			return err
 		}
+
+		idx++
 	}
 
 	return nil
@@ -143,6 +408,10 @@ func (s *Store) EnumerateKeys(match string, enumFunc EnumFunc) error {
 // Decode Values from Redis Response
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) DecodeValues(values []interface{}) ([]interface{}, error) {
+	return s.decodeValues("", values)
+}
+
+func (s *Store) decodeValues(key string, values []interface{}) ([]interface{}, error) {
 
 	if values == nil {
 		return nil, nil
@@ -150,10 +419,40 @@ func (s *Store) DecodeValues(values []interface{}) ([]interface{}, error) {
 	out := make([]interface{}, len(values))
 
 	for n, val := range values {
-		if err := msgpack.Unmarshal(val.([]byte), &out[n]); err != nil {
+		if err := s.decode(key, "", val, &out[n]); err != nil {
 			return nil, err
 		}
 	}
 
 	return out, nil
 }
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Decode Values from Redis Response into the slice pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) DecodeValuesInto(values []interface{}, dst interface{}) error {
+	return s.decodeValuesInto("", values, dst)
+}
+
+func (s *Store) decodeValuesInto(key string, values []interface{}, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("store: decode target must be a non-nil pointer to a slice, got %T", dst)
+	}
+
+	slice := rv.Elem()
+	if values == nil {
+		slice.Set(reflect.Zero(slice.Type()))
+		return nil
+	}
+
+	out := reflect.MakeSlice(slice.Type(), len(values), len(values))
+	for n, val := range values {
+		if err := s.decode(key, "", val, out.Index(n).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+
+	slice.Set(out)
+	return nil
+}
@@ -1,9 +1,11 @@
 package store
 
 import (
+	"errors"
 	"fmt"
 	"github.com/denkhaus/tcgl/asserts"
 	"testing"
+	"time"
 )
 
 /////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -51,3 +53,149 @@ func TestEnumerate(t *testing.T) {
 	assert.Length(res, 5, "enumerate res return wrong")
 	assert.Equal(cursor, 0, "enumerate cursor return wrong")
 }
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestBasicGetInto
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestBasicGetInto(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	key := "testGetInto"
+	value := codecTestItem{Name: "item", Count: 3, Tags: []string{"x"}}
+
+	err := st.Set(key, value)
+	assert.Nil(err, "Error should be nil.")
+
+	var res codecTestItem
+	err = st.GetInto(key, &res)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(res, value, "GetInto wrong value")
+
+	err = st.Set(key, 42)
+	assert.Nil(err, "Error should be nil.")
+
+	var num int
+	err = st.GetInto(key, &num)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(num, 42, "GetInto wrong int value")
+
+	err = st.Delete(key)
+	assert.Nil(err, "Error should be nil.")
+
+	err = st.GetInto(key, &num)
+	assert.NotNil(err, "GetInto on a missing key should fail.")
+	assert.Equal(num, 42, "GetInto must not touch dst on a missing key")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestMGetMSet
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestMGetMSet(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	err := st.MSet(map[string]interface{}{
+		"testMSet1": codecTestItem{Name: "one", Count: 1},
+		"testMSet2": codecTestItem{Name: "two", Count: 2},
+	})
+	assert.Nil(err, "Error should be nil.")
+
+	var items []codecTestItem
+	found, err := st.MGetInto(&items, "testMSet2", "testMSetMissing", "testMSet1")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(found, []bool{true, false, true}, "found should report the missing key")
+	assert.Equal(items, []codecTestItem{{Name: "two", Count: 2}, {}, {Name: "one", Count: 1}}, "the values should be aligned with the keys")
+
+	assert.Nil(st.Set("testMGetNil", nil), "Error should be nil.")
+	vals, found, err := st.MGet("testMGetNil", "testMSetMissing")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(vals, []interface{}{nil, nil}, "a missing key should have a nil value")
+	assert.Equal(found, []bool{true, false}, "found should tell a stored nil from a missing key")
+
+	vals, found, err = st.MGet()
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(vals, 0, "MGet without keys should return no values")
+	assert.Length(found, 0, "MGet without keys should return no values")
+
+	err = st.MSet(map[string]interface{}{"testMSetValid": 1, "testMSetInvalid": make(chan int)})
+	assert.ErrorMatch(err, "testMSetInvalid", "the error should name the key failing to encode")
+	_, err = st.Get("testMSetValid")
+	assert.True(errors.Is(err, ErrNotFound), "no value should be written if one fails to encode")
+
+	ok, err := st.MSetNX(map[string]interface{}{"testMSetNX": 1, "testMSet1": 1})
+	assert.Nil(err, "Error should be nil.")
+	assert.False(ok, "MSetNX should not set anything if a key exists")
+	_, err = st.Get("testMSetNX")
+	assert.True(errors.Is(err, ErrNotFound), "MSetNX should not set anything if a key exists")
+
+	ok, err = st.MSetNX(map[string]interface{}{"testMSetNX": 1, "testMSetNX2": 2})
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "MSetNX should set keys that don't exist")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestDeleteKeys
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestDeleteKeys(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	assert.Nil(st.MSet(map[string]interface{}{"testDel1": 1, "testDel2": 2, "testDel3": 3}), "Error should be nil.")
+	assert.Nil(st.Delete("testDel1", "testDel2", "testDelMissing"), "Error should be nil.")
+	assert.Nil(st.Unlink("testDel3"), "Error should be nil.")
+	assert.Nil(st.Delete(), "Delete without keys should do nothing")
+
+	_, found, err := st.MGet("testDel1", "testDel2", "testDel3")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(found, []bool{false, false, false}, "all keys should be deleted")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestConditionalSet
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestConditionalSet(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	ok, err := st.SetXX("testCondKey", "value", 0)
+	assert.Nil(err, "Error should be nil.")
+	assert.False(ok, "SetXX should not store a missing key")
+
+	ok, err = st.SetNX("testCondKey", "first", 0)
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "SetNX should store a missing key")
+
+	ok, err = st.SetNX("testCondKey", "second", time.Minute)
+	assert.Nil(err, "Error should be nil.")
+	assert.False(ok, "SetNX should not overwrite a key")
+
+	ok, err = st.SetXX("testCondKey", "third", 0)
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "SetXX should overwrite an existing key")
+
+	old, found, err := st.GetSet("testCondKey", "fourth")
+	assert.Nil(err, "Error should be nil.")
+	assert.True(found, "GetSet should find the previous value")
+	assert.Equal(old, "third", "GetSet should return the previous value")
+
+	old, found, err = st.GetSet("testCondMissing", "value")
+	assert.Nil(err, "Error should be nil.")
+	assert.False(found, "GetSet should report a missing key")
+	assert.Nil(old, "GetSet should return nil for a missing key")
+
+	val, err := st.GetDel("testCondKey")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "fourth", "GetDel should return the value")
+
+	_, err = st.GetDel("testCondKey")
+	assert.True(errors.Is(err, ErrNotFound), "GetDel should delete the key")
+
+	_, err = st.SetNX("testCondKey", "value", -time.Second)
+	assert.NotNil(err, "a negative ttl should fail")
+	assert.NotNil(st.SetWithExpiry("testCondKey", "value", 0), "SetWithExpiry should require a ttl")
+}
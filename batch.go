@@ -0,0 +1,559 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// batchChunk is the number of commands sent before their replies are read.
+const batchChunk = 1000
+
+var errBatchPending = errors.New("store: batch has not been executed")
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Batch queues operations and executes them in a single pipeline on one connection. The
+// queueing methods return a BatchResult that holds the outcome once Exec has returned.
+//
+//	b := st.NewBatch()
+//	for field, user := range users {
+//		b.HashSet("users", field, user)
+//	}
+//	err := b.Exec()
+//
+// Operations are pipelined, not transactional. A Batch must not be used concurrently.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Batch struct {
+	s   *Store
+	ops []*BatchResult
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// BatchResult is the outcome of a queued operation.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type BatchResult struct {
+	s     *Store
+	cmd   string
+	args  []interface{}
+	key   string
+	field string
+	reply interface{}
+	err   error
+	done  bool
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// NewBatch returns an empty batch bound to the context of the store.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) NewBatch() *Batch {
+	return &Batch{s: s}
+}
+
+// Len returns the number of queued operations.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+func (b *Batch) queue(key, field string, cmd string, args ...interface{}) *BatchResult {
+	r := &BatchResult{s: b.s, cmd: cmd, args: args, key: key, field: field}
+	b.ops = append(b.ops, r)
+	return r
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// queueValue queues a command whose last argument is value encoded by the codec. An encoding
+// error is reported by the result and Exec, the command is not sent.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (b *Batch) queueValue(key, field string, value interface{}, cmd string, args ...interface{}) *BatchResult {
+	data, err := b.s.encode(value)
+	r := b.queue(key, field, cmd, append(args, data)...)
+	r.err = err
+	return r
+}
+
+// Set queues Store.Set.
+func (b *Batch) Set(key string, value interface{}) *BatchResult {
+	return b.queueValue(key, "", value, "SET", key)
+}
+
+// SetWithTTL queues Store.SetWithTTL.
+//
+// Deprecated: use SetWithExpiry.
+func (b *Batch) SetWithTTL(key string, value interface{}, ttl int) *BatchResult {
+	return b.queueValue(key, "", value, "SETEX", key, ttl)
+}
+
+// SetWithExpiry queues Store.SetWithExpiry.
+func (b *Batch) SetWithExpiry(key string, value interface{}, ttl time.Duration) *BatchResult {
+	r := b.queueValue(key, "", value, "PSETEX", key, millis(ttl))
+	if ttl <= 0 {
+		r.err = fmt.Errorf("store: invalid ttl %v, must be positive", ttl)
+	}
+
+	return r
+}
+
+// Get queues Store.Get, use Value or Into of the result.
+func (b *Batch) Get(key string) *BatchResult {
+	return b.queue(key, "", "GET", key)
+}
+
+// Delete queues Store.Delete.
+func (b *Batch) Delete(key string) *BatchResult {
+	return b.queue(key, "", "DEL", key)
+}
+
+// HashSet queues Store.HashSet.
+func (b *Batch) HashSet(hash, key string, value interface{}) *BatchResult {
+	return b.queueValue(hash, key, value, "HSET", hash, key)
+}
+
+// HashGet queues Store.HashGet, use Value or Into of the result.
+func (b *Batch) HashGet(hash, key string) *BatchResult {
+	return b.queue(hash, key, "HGET", hash, key)
+}
+
+// HashGetValues queues Store.HashGetValues, use Values or ValuesInto of the result.
+func (b *Batch) HashGetValues(hash string) *BatchResult {
+	return b.queue(hash, "", "HVALS", hash)
+}
+
+// HashSize queues Store.HashSize, use Int of the result.
+func (b *Batch) HashSize(hash string) *BatchResult {
+	return b.queue(hash, "", "HLEN", hash)
+}
+
+// HashIncrBy queues Store.HashIncrBy, use Int of the result.
+func (b *Batch) HashIncrBy(hash, field string, n int64) *BatchResult {
+	return b.queue(hash, field, "HINCRBY", hash, field, n)
+}
+
+// HashDeleteField queues Store.HashDeleteField, use Int of the result.
+func (b *Batch) HashDeleteField(hash, field string) *BatchResult {
+	return b.queue(hash, field, "HDEL", hash, field)
+}
+
+// ListPush queues Store.ListPush.
+//
+// Deprecated: use ListPushLeft.
+func (b *Batch) ListPush(list, key string, value interface{}) *BatchResult {
+	return b.ListPushLeft(list, value)
+}
+
+// ListPushLeft queues Store.ListPushLeft, use Int of the result.
+func (b *Batch) ListPushLeft(list string, values ...interface{}) *BatchResult {
+	return b.queuePush("LPUSH", list, values)
+}
+
+// ListPushRight queues Store.ListPushRight, use Int of the result.
+func (b *Batch) ListPushRight(list string, values ...interface{}) *BatchResult {
+	return b.queuePush("RPUSH", list, values)
+}
+
+// ListRemove queues Store.ListRemove, use Int of the result.
+func (b *Batch) ListRemove(list string, count int, value interface{}) *BatchResult {
+	return b.queueValue(list, "", value, "LREM", list, count)
+}
+
+// ListRange queues Store.ListRange, use Values or ValuesInto of the result.
+func (b *Batch) ListRange(list string, start, stop int) *BatchResult {
+	return b.queue(list, "", "LRANGE", list, start, stop)
+}
+
+// ListLen queues Store.ListLen, use Int of the result.
+func (b *Batch) ListLen(list string) *BatchResult {
+	return b.queue(list, "", "LLEN", list)
+}
+
+func (b *Batch) queuePush(cmd, list string, values []interface{}) *BatchResult {
+	if len(values) == 0 {
+		return b.queue(list, "", "LLEN", list)
+	}
+
+	args := make([]interface{}, 1, len(values)+1)
+	args[0] = list
+	var err error
+	for _, value := range values {
+		data, encErr := b.s.encode(value)
+		if err == nil {
+			err = encErr
+		}
+
+		args = append(args, data)
+	}
+
+	r := b.queue(list, "", cmd, args...)
+	r.err = err
+	return r
+}
+
+// SetSet queues Store.SetSet, use Int of the result.
+//
+// Deprecated: use SetAdd.
+func (b *Batch) SetSet(set string, member string) *BatchResult {
+	return b.queue(set, "", "SADD", set, member)
+}
+
+// SetDelete queues Store.SetDelete, use Int of the result.
+//
+// Deprecated: use SetRemove.
+func (b *Batch) SetDelete(set string, member string) *BatchResult {
+	return b.queue(set, "", "SREM", set, member)
+}
+
+// SetAdd queues Store.SetAdd, use Int of the result.
+func (b *Batch) SetAdd(set string, members ...interface{}) *BatchResult {
+	return b.queueMembers("SADD", set, members)
+}
+
+// SetRemove queues Store.SetRemove, use Int of the result.
+func (b *Batch) SetRemove(set string, members ...interface{}) *BatchResult {
+	return b.queueMembers("SREM", set, members)
+}
+
+// SetMembers queues Store.SetMembers, use Values or ValuesInto of the result.
+func (b *Batch) SetMembers(set string) *BatchResult {
+	return b.queue(set, "", "SMEMBERS", set)
+}
+
+// SetSize queues Store.SetSize, use Int of the result.
+func (b *Batch) SetSize(set string) *BatchResult {
+	return b.queue(set, "", "SCARD", set)
+}
+
+func (b *Batch) queueMembers(cmd, set string, members []interface{}) *BatchResult {
+	if len(members) == 0 {
+		// nothing to send, like Store.SetAdd
+		return &BatchResult{s: b.s, key: set, reply: int64(0), done: true}
+	}
+
+	args, err := b.s.setArgs(set, members)
+	if err != nil {
+		r := b.queue(set, "", cmd, set)
+		r.err = err
+		return r
+	}
+
+	return b.queue(set, "", cmd, args...)
+}
+
+// SortedSetSet queues Store.SortedSetSet, use Int of the result.
+func (b *Batch) SortedSetSet(set string, score float64, value interface{}) *BatchResult {
+	return b.queueValue(set, "", value, "ZADD", set, formatScore(score))
+}
+
+// SortedSetSize queues Store.SortedSetSize, use Int of the result.
+func (b *Batch) SortedSetSize(set string, scoreMin float64, scoreMax float64) *BatchResult {
+	return b.queue(set, "", "ZCOUNT", set, formatScore(scoreMin), formatScore(scoreMax))
+}
+
+// SortedSetGetAsc queues Store.SortedSetGetAsc, use Values or ValuesInto of the result.
+func (b *Batch) SortedSetGetAsc(set string, scoreMin float64, scoreMax float64) *BatchResult {
+	return b.queue(set, "", "ZRANGEBYSCORE", set, formatScore(scoreMin), formatScore(scoreMax))
+}
+
+// SortedSetGetDesc queues Store.SortedSetGetDesc, use Values or ValuesInto of the result.
+func (b *Batch) SortedSetGetDesc(set string, scoreMin float64, scoreMax float64) *BatchResult {
+	return b.queue(set, "", "ZREVRANGEBYSCORE", set, formatScore(scoreMax), formatScore(scoreMin))
+}
+
+// SortedSetDeleteByScore queues Store.SortedSetDeleteByScore, use Int of the result.
+func (b *Batch) SortedSetDeleteByScore(key string, scoreMin float64, scoreMax float64) *BatchResult {
+	return b.queue(key, "", "ZREMRANGEBYSCORE", key, formatScore(scoreMin), formatScore(scoreMax))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Exec sends all queued operations and reads their replies. The batch is empty afterwards and
+// can be reused. Exec returns the first error of any operation; the results report their own
+// errors. On a cluster the operations are pipelined per node.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (b *Batch) Exec() error {
+	ops := b.ops
+	b.ops = nil
+
+	if b.s.cluster != nil {
+		b.s.execCluster(ops)
+	} else {
+		b.s.pipelined(pending(ops), func(fn func(conn redis.Conn) error) error {
+			return b.s.execPool(b.s.Pool, fn)
+		})
+	}
+
+	var err error
+	for _, op := range ops {
+		op.done = true
+		if op.err != nil && err == nil {
+			err = op.err
+		}
+	}
+
+	return err
+}
+
+// pending returns the ops that have not failed while queueing.
+func pending(ops []*BatchResult) []*BatchResult {
+	var res []*BatchResult
+	for _, op := range ops {
+		if op.err == nil {
+			res = append(res, op)
+		}
+	}
+
+	return res
+}
+
+type outcome struct {
+	reply interface{}
+	err   error
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// pipelined runs ops through exec and stores their outcomes. Ops without a reply get the
+// error of exec. The outcomes of a pipeline still running once the store context is done are
+// discarded.
+//
+// If exec runs fn again, as on a sentinel failover, only the ops that have not been executed
+// are sent, which are the ops without a reply and those rejected with READONLY.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) pipelined(ops []*BatchResult, exec func(fn func(conn redis.Conn) error) error) {
+	var (
+		mu        sync.Mutex
+		abandoned bool
+	)
+
+	out := make([]outcome, len(ops))
+	for i := range out {
+		out[i].err = errBatchPending
+	}
+
+	err := exec(func(conn redis.Conn) error {
+		mu.Lock()
+		var todo []int
+		for i, o := range out {
+			if o.err == errBatchPending || isReadOnly(o.err) {
+				todo = append(todo, i)
+			}
+		}
+
+		mu.Unlock()
+
+		sub := make([]*BatchResult, len(todo))
+		for j, i := range todo {
+			sub[j] = ops[i]
+		}
+
+		res, err := pipeline(conn, sub)
+
+		mu.Lock()
+		if !abandoned {
+			for j, i := range todo {
+				if res[j].err != errBatchPending {
+					out[i] = res[j]
+				}
+			}
+		}
+
+		mu.Unlock()
+		return err
+	})
+
+	mu.Lock()
+	abandoned = true
+	mu.Unlock()
+
+	for i, op := range ops {
+		op.reply, op.err = nil, err
+		if out[i].err != errBatchPending {
+			op.reply, op.err = out[i].reply, out[i].err
+		}
+	}
+}
+
+// isReadOnly reports whether err is a READONLY reply of a demoted master.
+func isReadOnly(err error) bool {
+	var rerr redis.Error
+	return errors.As(err, &rerr) && strings.HasPrefix(string(rerr), "READONLY")
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// pipeline sends ops in chunks and returns their outcomes, errBatchPending for ops without a
+// reply. It returns connection errors, and READONLY replies so that a sentinel store is able
+// to retry on the new master.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func pipeline(conn redis.Conn, ops []*BatchResult) ([]outcome, error) {
+	out := make([]outcome, len(ops))
+	for i := range out {
+		out[i].err = errBatchPending
+	}
+
+	for from := 0; from < len(ops); from += batchChunk {
+		to := from + batchChunk
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		for _, op := range ops[from:to] {
+			if err := conn.Send(op.cmd, op.args...); err != nil {
+				return out, err
+			}
+		}
+
+		if err := conn.Flush(); err != nil {
+			return out, err
+		}
+
+		var readonly error
+		for i := from; i < to; i++ {
+			reply, err := conn.Receive()
+			if rerr, isReply := err.(redis.Error); isReply {
+				if isReadOnly(rerr) && readonly == nil {
+					readonly = rerr
+				}
+
+				out[i] = outcome{err: mapError(rerr)}
+				continue
+			}
+
+			if err != nil {
+				return out, err
+			}
+
+			out[i] = outcome{reply: reply}
+		}
+
+		if readonly != nil {
+			return out, readonly
+		}
+	}
+
+	return out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// execCluster pipelines ops on the nodes owning their keys. Operations whose slot has moved
+// are executed one by one, following the redirections.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) execCluster(ops []*BatchResult) {
+	nodes := map[string][]*BatchResult{}
+	var order []string
+
+	for _, op := range pending(ops) {
+		addr, err := s.cluster.route(op.cmd, op.args)
+		if err != nil {
+			op.err = err
+			continue
+		}
+
+		if nodes[addr] == nil {
+			order = append(order, addr)
+		}
+
+		nodes[addr] = append(nodes[addr], op)
+	}
+
+	for _, addr := range order {
+		node := nodes[addr]
+		s.pipelined(node, func(fn func(conn redis.Conn) error) error {
+			return mapError(s.run(s.cluster.pool(addr), fn))
+		})
+
+		for _, op := range node {
+			if kind, slot, target, redirected := parseRedirect(op.err, addr); redirected {
+				if kind == "MOVED" {
+					s.cluster.moved(slot, target)
+				}
+
+				op.reply, op.err = s.doCluster(op.cmd, op.args...)
+			} else if errors.Is(op.err, ErrConnection) {
+				atomic.StoreInt32(&s.cluster.reload, 1)
+			}
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Err returns the error of the operation.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (r *BatchResult) Err() error {
+	if !r.done {
+		return errBatchPending
+	}
+
+	return r.err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Reply returns the raw reply of the operation.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (r *BatchResult) Reply() (interface{}, error) {
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	return r.reply, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Int returns an integer reply, like the number of added members.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (r *BatchResult) Int() (int, error) {
+	return redis.Int(r.Reply())
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Value decodes the value of a Get or HashGet. A missing value will return ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (r *BatchResult) Value() (interface{}, error) {
+	var out interface{}
+	if err := r.Into(&out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Into decodes the value of a Get or HashGet into the value pointed to by dst. A missing value
+// will return ErrNotFound and leaves dst untouched.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (r *BatchResult) Into(dst interface{}) error {
+	reply, err := r.Reply()
+	if err != nil {
+		return err
+	}
+
+	if reply == nil {
+		return ErrNotFound
+	}
+
+	return r.s.decode(r.key, r.field, reply, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Values decodes the values of a HashGetValues or a sorted set range.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (r *BatchResult) Values() ([]interface{}, error) {
+	vals, err := redis.Values(r.Reply())
+	if err != nil {
+		return nil, err
+	}
+
+	return r.s.decodeValues(r.key, vals)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// ValuesInto decodes the values of a HashGetValues or a sorted set range into the slice
+// pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (r *BatchResult) ValuesInto(dst interface{}) error {
+	vals, err := redis.Values(r.Reply())
+	if err != nil {
+		return err
+	}
+
+	return r.s.decodeValuesInto(r.key, vals, dst)
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denkhaus/tcgl/asserts"
+	"math"
+	"testing"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestBatch
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestBatch(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	b := st.NewBatch()
+	for i := 0; i < 2500; i++ {
+		b.HashSet("testBatchHash", fmt.Sprintf("field%d", i), codecTestItem{Name: "item", Count: i})
+	}
+
+	set := b.Set("testBatchKey", "value")
+	get := b.Get("testBatchKey")
+	missing := b.Get("testBatchMissing")
+	field := b.HashGet("testBatchHash", "field42")
+	size := b.HashSize("testBatchHash")
+	zadd := b.SortedSetSet("testBatchZSet", 1, "member")
+	zrange := b.SortedSetGetAsc("testBatchZSet", math.Inf(-1), math.Inf(1))
+	b.Delete("testBatchList")
+	push := b.ListPushRight("testBatchList", "a", "b")
+	lrange := b.ListRange("testBatchList", 0, -1)
+	b.Delete("testBatchCodecSet")
+	sadd := b.SetAdd("testBatchCodecSet", "a", "b")
+	noop := b.SetAdd("testBatchCodecSet")
+	smembers := b.SetMembers("testBatchCodecSet")
+
+	assert.Equal(b.Len(), 2513, "Len should count the queued operations")
+	assert.Equal(get.Err(), errBatchPending, "results should not be available before Exec")
+
+	assert.Nil(b.Exec(), "Error should be nil.")
+	assert.Equal(b.Len(), 0, "Exec should empty the batch")
+	assert.Nil(set.Err(), "Error should be nil.")
+
+	val, err := get.Value()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "value", "Get should see the preceding Set")
+
+	_, err = missing.Value()
+	assert.True(errors.Is(err, ErrNotFound), "a missing value should return ErrNotFound")
+
+	var item codecTestItem
+	assert.Nil(field.Into(&item), "Error should be nil.")
+	assert.Equal(item.Count, 42, "HashGet should decode the field")
+
+	n, err := size.Int()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2500, "all fields should be stored")
+
+	n, err = zadd.Int()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "SortedSetSet should add the member")
+
+	vals, err := zrange.Values()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(vals, []interface{}{"member"}, "SortedSetGetAsc should decode the members")
+
+	n, err = push.Int()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "ListPushRight should push all values")
+
+	vals, err = lrange.Values()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(vals, []interface{}{"a", "b"}, "ListRange should decode the elements")
+
+	n, err = sadd.Int()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "SetAdd should add all members")
+
+	n, err = noop.Int()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "SetAdd without members should add nothing")
+
+	var members []string
+	assert.Nil(smembers.ValuesInto(&members), "Error should be nil.")
+	assert.Length(members, 2, "SetMembers should decode the members")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestBatchErrors
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestBatchErrors(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	assert.Nil(st.Set("testBatchString", "value"), "Error should be nil.")
+
+	b := st.NewBatch()
+	before := b.Set("testBatchBefore", "value")
+	wrong := b.HashSet("testBatchString", "field", "value")
+	invalid := b.Set("testBatchInvalid", make(chan int))
+	after := b.SetSet("testBatchSet", "member")
+
+	err := b.Exec()
+	assert.True(errors.Is(err, ErrWrongType), "Exec should return the first error")
+	assert.Nil(before.Err(), "an error should not affect preceding operations")
+	assert.True(errors.Is(wrong.Err(), ErrWrongType), "the failed operation should report its error")
+	assert.NotNil(invalid.Err(), "an encoding error should be reported")
+	assert.Nil(after.Err(), "an error should not affect following operations")
+
+	_, err = st.Get("testBatchInvalid")
+	assert.True(errors.Is(err, ErrNotFound), "an operation failing to encode should not be sent")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	b = st.WithContext(ctx).NewBatch()
+	res := b.Set("testBatchCancelled", "value")
+	assert.Equal(b.Exec(), context.Canceled, "a cancelled context should fail Exec")
+	assert.Equal(res.Err(), context.Canceled, "a cancelled context should fail the operations")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestBatchCluster
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestBatchCluster(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	fc := newFakeCluster(t)
+
+	st, err := OpenCluster([]string{fc.nodes[0].addr()})
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	low, high := keyInSlots(0, 1000), keyInSlots(12000, 13000)
+	fc.reshard(13000)
+
+	b := st.NewBatch()
+	b.Set(low, "low")
+	b.Set(high, "high")
+	get := b.Get(high)
+
+	assert.Nil(b.Exec(), "Error should be nil.")
+	val, err := get.Value()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "high", "Exec should follow MOVED")
+
+	assert.Equal(countCommands(fc.nodes[0], "SET"), 2, "both keys should end on the first node")
+	assert.Equal(countCommands(fc.nodes[1], "SET"), 1, "the moved key should be sent to the old owner first")
+}
@@ -0,0 +1,233 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"strconv"
+	"time"
+)
+
+// blockingSlack is the time the server is given to reply once the timeout of a blocking
+// command has passed.
+const blockingSlack = 5 * time.Second
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// ListBlockingPop removes and returns the head of the first non-empty list of lists together
+// with the name of the list. If all lists are empty, it waits up to timeout for a value to be
+// pushed, a timeout of 0 waits until ctx is done. ErrNotFound is returned once the timeout has
+// passed and ctx.Err() as soon as ctx is done.
+//
+// Blocking commands run on a connection dialed for the call and closed afterwards, so that
+// waiting consumers do not hold connections of the pool. On a cluster all lists must map to
+// the same slot.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListBlockingPop(ctx context.Context, timeout time.Duration, lists ...string) (string, interface{}, error) {
+	var out interface{}
+	list, err := s.ListBlockingPopInto(ctx, timeout, &out, lists...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return list, out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like ListBlockingPop but decodes the value into the value pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListBlockingPopInto(ctx context.Context, timeout time.Duration, dst interface{}, lists ...string) (string, error) {
+	return s.blockingPop(ctx, "BLPOP", timeout, dst, lists)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like ListBlockingPop but removes the tail of a list.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListBlockingPopRight(ctx context.Context, timeout time.Duration, lists ...string) (string, interface{}, error) {
+	var out interface{}
+	list, err := s.ListBlockingPopRightInto(ctx, timeout, &out, lists...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return list, out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like ListBlockingPopRight but decodes the value into the value pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListBlockingPopRightInto(ctx context.Context, timeout time.Duration, dst interface{}, lists ...string) (string, error) {
+	return s.blockingPop(ctx, "BRPOP", timeout, dst, lists)
+}
+
+func (s *Store) blockingPop(ctx context.Context, cmd string, timeout time.Duration, dst interface{}, lists []string) (string, error) {
+	if len(lists) == 0 {
+		return "", errors.New("store: no list to pop from")
+	}
+
+	args := make([]interface{}, len(lists))
+	for i, list := range lists {
+		args[i] = list
+	}
+
+	reply, err := s.doBlocking(ctx, timeout, cmd, args...)
+	if err != nil {
+		return "", err
+	}
+
+	vals, err := redis.Values(reply, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if len(vals) != 2 {
+		return "", fmt.Errorf("store: invalid %s reply of length %d", cmd, len(vals))
+	}
+
+	list, err := redis.String(vals[0], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return list, s.decode(list, "", vals[1], dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// ListBlockingMove is ListMove waiting up to timeout for source to be non-empty, see
+// ListBlockingPop. It needs redis 6.2 or later.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListBlockingMove(ctx context.Context, timeout time.Duration, source, destination string, from, to ListEnd) (interface{}, error) {
+	var out interface{}
+	if err := s.ListBlockingMoveInto(ctx, timeout, source, destination, from, to, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like ListBlockingMove but decodes the moved value into the value pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListBlockingMoveInto(ctx context.Context, timeout time.Duration, source, destination string, from, to ListEnd, dst interface{}) error {
+	reply, err := s.doBlocking(ctx, timeout, "BLMOVE", source, destination, string(from), string(to))
+	if err != nil {
+		return err
+	}
+
+	return s.decode(source, "", reply, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// doBlocking executes a blocking command with timeout appended to args. A nil reply, sent by
+// the server once the timeout has passed, is returned as ErrNotFound. On a cluster the command
+// is routed by its keys and redirections are followed.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) doBlocking(ctx context.Context, timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	if timeout < 0 {
+		return nil, fmt.Errorf("store: invalid timeout %v, must not be negative", timeout)
+	}
+
+	args = append(args, strconv.FormatFloat(float64(millis(timeout))/1000, 'f', -1, 64))
+
+	readTimeout := time.Duration(0)
+	if timeout > 0 {
+		readTimeout = timeout + blockingSlack
+	}
+
+	if s.cluster == nil {
+		reply, err := s.block(ctx, s.Pool, false, readTimeout, cmd, args)
+		if s.sentinel != nil && s.sentinel.failover(err) {
+			reply, err = s.block(ctx, s.Pool, false, readTimeout, cmd, args)
+		}
+
+		return blockingReply(reply, err)
+	}
+
+	addr, err := s.cluster.route(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+
+	asking := false
+	for redirects := 0; ; redirects++ {
+		reply, err := s.block(ctx, s.cluster.pool(addr), asking, readTimeout, cmd, args)
+		kind, slot, target, redirected := parseRedirect(err, addr)
+		if !redirected {
+			return blockingReply(reply, err)
+		}
+
+		if redirects == maxRedirects {
+			return nil, fmt.Errorf("store: too many cluster redirections for %s: %w", cmd, mapError(err))
+		}
+
+		if kind == "MOVED" {
+			s.cluster.moved(slot, target)
+		}
+
+		addr, asking = target, kind == "ASK"
+	}
+}
+
+func blockingReply(reply interface{}, err error) (interface{}, error) {
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	if reply == nil {
+		return nil, ErrNotFound
+	}
+
+	return reply, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// block executes a command on a connection dialed from pool for this call. The connection is
+// closed as soon as ctx is done, which ends the command and makes block return ctx.Err(). A
+// reply received before is returned, since the server has executed the command.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) block(ctx context.Context, pool *redis.Pool, asking bool, readTimeout time.Duration, cmd string, args []interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	conn, err := pool.Dial()
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if asking {
+		if _, err := conn.Do("ASKING"); err != nil {
+			return nil, err
+		}
+	}
+
+	var reply interface{}
+	if cwt, ok := conn.(redis.ConnWithTimeout); ok {
+		reply, err = cwt.DoWithTimeout(readTimeout, cmd, args...)
+	} else {
+		reply, err = conn.Do(cmd, args...)
+	}
+
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		return nil, err
+	}
+
+	return reply, nil
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"github.com/denkhaus/tcgl/asserts"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestListBlockingPop
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestListBlockingPop(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	ctx := context.Background()
+	assert.Nil(st.Delete("testBlockingA", "testBlockingB"), "Error should be nil.")
+
+	_, err := st.ListPushRight("testBlockingB", "b1", "b2")
+	assert.Nil(err, "Error should be nil.")
+
+	list, val, err := st.ListBlockingPop(ctx, time.Second, "testBlockingA", "testBlockingB")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(list, "testBlockingB", "ListBlockingPop should return the list")
+	assert.Equal(val, "b1", "ListBlockingPop should return the head")
+
+	list, val, err = st.ListBlockingPopRight(ctx, time.Second, "testBlockingA", "testBlockingB")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "b2", "ListBlockingPopRight should return the tail")
+
+	type popped struct {
+		list string
+		item codecTestItem
+		err  error
+	}
+
+	res := make(chan popped, 1)
+	go func() {
+		var p popped
+		p.list, p.err = st.ListBlockingPopInto(ctx, 0, &p.item, "testBlockingA", "testBlockingB")
+		res <- p
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = st.ListPushRight("testBlockingA", codecTestItem{Name: "item", Count: 1})
+	assert.Nil(err, "Error should be nil.")
+
+	p := <-res
+	assert.Nil(p.err, "Error should be nil.")
+	assert.Equal(p.list, "testBlockingA", "ListBlockingPopInto should wait for a push")
+	assert.Equal(p.item.Name, "item", "ListBlockingPopInto should decode the value")
+
+	_, _, err = st.ListBlockingPop(ctx, 50*time.Millisecond, "testBlockingA")
+	assert.True(errors.Is(err, ErrNotFound), "a timeout should return ErrNotFound")
+
+	_, _, err = st.ListBlockingPop(ctx, -time.Second, "testBlockingA")
+	assert.NotNil(err, "a negative timeout should fail")
+
+	_, _, err = st.ListBlockingPop(ctx, time.Second)
+	assert.NotNil(err, "ListBlockingPop without lists should fail")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestListBlockingCancel
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestListBlockingCancel(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := NewMemoryStore(WithMaxActive(1), WithWait(true))
+	defer st.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	res := make(chan error, 1)
+	go func() {
+		_, _, err := st.ListBlockingPop(ctx, 0, "testBlockingCancel")
+		res <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(st.Set("testBlockingKey", "value"), "a blocking pop should not hold a pooled connection")
+
+	cancel()
+	assert.Equal(<-res, context.Canceled, "cancelling the context should end the pop")
+
+	_, err := st.ListPushRight("testBlockingCancel", "value")
+	assert.Nil(err, "Error should be nil.")
+	n, err := st.ListLen("testBlockingCancel")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "a cancelled pop should not remove a value")
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = st.ListBlockingMove(ctx, 0, "testBlockingMissing", "testBlockingCancel", ListLeft, ListRight)
+	assert.Equal(err, context.DeadlineExceeded, "the deadline of the context should end the move")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestListBlockingCancelRace
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestListBlockingCancelRace(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	assert.Nil(st.Delete("testBlockingRace"), "Error should be nil.")
+	_, err := st.ListPushRight("testBlockingRace", "value")
+	assert.Nil(err, "Error should be nil.")
+
+	// The context is cancelled while the reply of the pop is on its way.
+	ctx := &cancelledAfterFirstCheck{Context: context.Background()}
+	_, val, err := st.ListBlockingPop(ctx, time.Second, "testBlockingRace")
+	assert.Nil(err, "a pop with a reply should not return ctx.Err()")
+	assert.Equal(val, "value", "the popped value should be returned")
+}
+
+// cancelledAfterFirstCheck is a context reporting to be cancelled from its second Err call on.
+type cancelledAfterFirstCheck struct {
+	context.Context
+	checks atomic.Int32
+}
+
+func (c *cancelledAfterFirstCheck) Err() error {
+	if c.checks.Add(1) > 1 {
+		return context.Canceled
+	}
+
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestListMove
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestListMove(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	ctx := context.Background()
+	assert.Nil(st.Delete("testMoveSrc", "testMoveDst"), "Error should be nil.")
+
+	_, err := st.ListPushRight("testMoveSrc", 1, 2, 3)
+	assert.Nil(err, "Error should be nil.")
+
+	var n int
+	assert.Nil(st.ListMoveInto("testMoveSrc", "testMoveDst", ListLeft, ListRight, &n), "Error should be nil.")
+	assert.Equal(n, 1, "ListMoveInto should decode the moved value")
+
+	val, err := st.ListBlockingMove(ctx, time.Second, "testMoveSrc", "testMoveDst", ListRight, ListLeft)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, int64(3), "ListBlockingMove should return the moved value")
+
+	var got []int
+	assert.Nil(st.ListRangeInto("testMoveDst", 0, -1, &got), "Error should be nil.")
+	assert.Equal(got, []int{3, 1}, "the values should be pushed to the destination")
+
+	_, err = st.ListMove("testMoveMissing", "testMoveDst", ListLeft, ListLeft)
+	assert.True(errors.Is(err, ErrNotFound), "moving from an empty list should return ErrNotFound")
+
+	err = st.ListBlockingMoveInto(ctx, 50*time.Millisecond, "testMoveMissing", "testMoveDst", ListLeft, ListLeft, &n)
+	assert.True(errors.Is(err, ErrNotFound), "a timeout should return ErrNotFound")
+}
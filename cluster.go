@@ -0,0 +1,577 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ClusterSlots is the number of hash slots of a redis cluster.
+const ClusterSlots = 16384
+
+// maxRedirects bounds the MOVED and ASK redirections followed for a single command.
+const maxRedirects = 5
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// OpenCluster returns a Store for the redis cluster reachable at any of addrs. The slot map is
+// loaded with CLUSTER SLOTS and every command is sent to the master owning the slot of its
+// key. MOVED and ASK redirections are followed and a MOVED reloads the slot map. Keys of a
+// multi-key command must map to the same slot, use TaggedKey to group related keys.
+// WithDB is not supported by redis cluster.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func OpenCluster(addrs []string, opts ...Option) (*Store, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("store: at least one cluster address is required")
+	}
+
+	o := defaultOptions("")
+	o.clusterAddrs = append([]string(nil), addrs...)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return open(o)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// KeySlot returns the cluster hash slot of key. If key contains a hash tag, a non-empty
+// substring between the first "{" and the next "}", only the tag is hashed.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func KeySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	return int(crc16(key) % ClusterSlots)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// TaggedKey prefixes key with the hash tag tag. All keys built with the same tag map to the
+// same cluster slot, so they can be used together in multi-key commands and transactions.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func TaggedKey(tag, key string) string {
+	return "{" + tag + "}:" + key
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// cluster keeps the slot map of a redis cluster and a connection pool per node.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type cluster struct {
+	reload int32
+
+	o *options
+
+	mu    sync.RWMutex
+	seeds []string
+	slots [ClusterSlots]string
+	pools map[string]*redis.Pool
+}
+
+func newCluster(o *options) *cluster {
+	return &cluster{
+		o:     o,
+		seeds: o.clusterAddrs,
+		pools: make(map[string]*redis.Pool),
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// pool returns the connection pool of the node at addr.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *cluster) pool(addr string) *redis.Pool {
+	c.mu.RLock()
+	p := c.pools[addr]
+	c.mu.RUnlock()
+
+	if p != nil {
+		return p
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p = c.pools[addr]; p == nil {
+		p = c.o.newPool(func() (redis.Conn, error) {
+			return c.o.dialAddr(addr)
+		}, func(conn redis.Conn, t time.Time) error {
+			_, err := conn.Do("PING")
+			return err
+		})
+
+		c.pools[addr] = p
+	}
+
+	return p
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// masters returns the addresses of all masters owning slots.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *cluster) masters() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var addrs []string
+	for _, addr := range c.slots {
+		if addr != "" && !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+
+	sort.Strings(addrs)
+	return addrs
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// refresh loads the slot map from the first node that answers CLUSTER SLOTS.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *cluster) refresh() error {
+	var lastErr error
+	for _, addr := range append(c.masters(), c.seeds...) {
+		conn := c.pool(addr).Get()
+		reply, err := conn.Do("CLUSTER", "SLOTS")
+		conn.Close()
+
+		if err == nil {
+			var slots [ClusterSlots]string
+			if err = parseClusterSlots(reply, addr, &slots); err == nil {
+				c.mu.Lock()
+				c.slots = slots
+				c.mu.Unlock()
+				return nil
+			}
+		}
+
+		lastErr = fmt.Errorf("%s: %w", addr, err)
+	}
+
+	atomic.StoreInt32(&c.reload, 1)
+	return fmt.Errorf("store: load cluster slots: %w", lastErr)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// parseClusterSlots fills slots from a CLUSTER SLOTS reply of the node at from.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func parseClusterSlots(reply interface{}, from string, slots *[ClusterSlots]string) error {
+	ranges, err := redis.Values(reply, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range ranges {
+		fields, err := redis.Values(r, nil)
+		if err != nil {
+			return err
+		}
+
+		if len(fields) < 3 {
+			return fmt.Errorf("invalid slot range of length %d", len(fields))
+		}
+
+		start, err := redis.Int(fields[0], nil)
+		if err != nil {
+			return err
+		}
+
+		end, err := redis.Int(fields[1], nil)
+		if err != nil {
+			return err
+		}
+
+		node, err := redis.Values(fields[2], nil)
+		if err != nil || len(node) < 2 {
+			return fmt.Errorf("invalid master of slots %d-%d", start, end)
+		}
+
+		host, _ := redis.String(node[0], nil)
+		port, err := redis.Int(node[1], nil)
+		if err != nil {
+			return err
+		}
+
+		if start < 0 || end >= ClusterSlots || start > end {
+			return fmt.Errorf("invalid slot range %d-%d", start, end)
+		}
+
+		addr := nodeAddr(host, strconv.Itoa(port), from)
+		for slot := start; slot <= end; slot++ {
+			slots[slot] = addr
+		}
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// nodeAddr joins host and port. Nodes announce an empty host if they don't know their own
+// address, the host of the node that has been asked is used then.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func nodeAddr(host, port, from string) string {
+	if host == "" || host == "?" {
+		host, _, _ = net.SplitHostPort(from)
+	}
+
+	return net.JoinHostPort(host, port)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// route returns the node a command should be sent to.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *cluster) route(cmd string, args []interface{}) (string, error) {
+	if atomic.CompareAndSwapInt32(&c.reload, 1, 0) {
+		c.refresh()
+	}
+
+	slot, err := commandSlot(cmd, args)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if slot >= 0 && c.slots[slot] != "" {
+		return c.slots[slot], nil
+	}
+
+	for _, addr := range c.slots {
+		if addr != "" {
+			return addr, nil
+		}
+	}
+
+	return c.seeds[0], nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// moved records the new owner of slot and schedules a reload of the slot map.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *cluster) moved(slot int, addr string) {
+	c.mu.Lock()
+	c.slots[slot] = addr
+	c.mu.Unlock()
+
+	atomic.StoreInt32(&c.reload, 1)
+}
+
+func (c *cluster) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for _, p := range c.pools {
+		if cerr := p.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// doCluster executes a single command on the node owning its key and follows redirections.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) doCluster(cmd string, args ...interface{}) (interface{}, error) {
+	addr, err := s.cluster.route(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+
+	asking := false
+	for redirects := 0; ; redirects++ {
+		var reply interface{}
+		err := s.run(s.cluster.pool(addr), func(conn redis.Conn) (err error) {
+			if asking {
+				if _, err := conn.Do("ASKING"); err != nil {
+					return err
+				}
+			}
+
+			reply, err = conn.Do(cmd, args...)
+			return err
+		})
+
+		if err == nil {
+			return reply, nil
+		}
+
+		kind, slot, target, ok := parseRedirect(err, addr)
+		if !ok {
+			if isConnectionError(err) {
+				atomic.StoreInt32(&s.cluster.reload, 1)
+			}
+
+			return nil, mapError(err)
+		}
+
+		if redirects == maxRedirects {
+			return nil, fmt.Errorf("store: too many cluster redirections for %s: %w", cmd, mapError(err))
+		}
+
+		if kind == "MOVED" {
+			s.cluster.moved(slot, target)
+		}
+
+		addr, asking = target, kind == "ASK"
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// doKeys executes cmd for keys. On a cluster the keys are grouped by slot and a command is
+// pipelined for every group, args returns the arguments of the command for the keys at the
+// given indexes. The groups are returned with their replies.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) doKeys(keys []string, cmd string, args func(idx []int) []interface{}) ([][]int, []interface{}, error) {
+	groups := s.slotGroups(keys)
+	if len(groups) == 1 {
+		reply, err := s.do(cmd, args(groups[0])...)
+		return groups, []interface{}{reply}, err
+	}
+
+	b := s.NewBatch()
+	ops := make([]*BatchResult, len(groups))
+	for i, group := range groups {
+		ops[i] = b.queue(keys[group[0]], "", cmd, args(group)...)
+	}
+
+	if err := b.Exec(); err != nil {
+		return nil, nil, err
+	}
+
+	replies := make([]interface{}, len(ops))
+	for i, op := range ops {
+		replies[i] = op.reply
+	}
+
+	return groups, replies, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// slotGroups returns the indexes of keys grouped by cluster slot in the order of their first
+// key. Outside of a cluster all keys form a single group.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) slotGroups(keys []string) [][]int {
+	if s.cluster == nil {
+		idx := make([]int, len(keys))
+		for i := range idx {
+			idx[i] = i
+		}
+
+		return [][]int{idx}
+	}
+
+	var groups [][]int
+	slots := map[int]int{}
+	for i, key := range keys {
+		slot := KeySlot(key)
+		n, found := slots[slot]
+		if !found {
+			n = len(groups)
+			slots[slot] = n
+			groups = append(groups, nil)
+		}
+
+		groups[n] = append(groups[n], i)
+	}
+
+	return groups
+}
+
+// keyArgs returns the keys at the indexes idx as command arguments.
+func keyArgs(keys []string, idx []int) []interface{} {
+	args := make([]interface{}, len(idx))
+	for i, n := range idx {
+		args[i] = keys[n]
+	}
+
+	return args
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// doNode executes a single command on the cluster node at addr.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) doNode(addr string, cmd string, args ...interface{}) (interface{}, error) {
+	var reply interface{}
+	err := s.run(s.cluster.pool(addr), func(conn redis.Conn) (err error) {
+		reply, err = conn.Do(cmd, args...)
+		return err
+	})
+
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	return reply, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// parseRedirect parses a "MOVED <slot> <addr>" or "ASK <slot> <addr>" error reply.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func parseRedirect(err error, from string) (kind string, slot int, addr string, ok bool) {
+	rerr, isReply := err.(redis.Error)
+	if !isReply {
+		return "", 0, "", false
+	}
+
+	fields := strings.Fields(string(rerr))
+	if len(fields) != 3 || (fields[0] != "MOVED" && fields[0] != "ASK") {
+		return "", 0, "", false
+	}
+
+	slot, err = strconv.Atoi(fields[1])
+	if err != nil || slot < 0 || slot >= ClusterSlots {
+		return "", 0, "", false
+	}
+
+	host, port, err := net.SplitHostPort(fields[2])
+	if err != nil {
+		return "", 0, "", false
+	}
+
+	return fields[0], slot, nodeAddr(host, port, from), true
+}
+
+// keylessCommands don't address a key and can be sent to any master.
+var keylessCommands = map[string]bool{
+	"DBSIZE":   true,
+	"ECHO":     true,
+	"FLUSHALL": true,
+	"FLUSHDB":  true,
+	"INFO":     true,
+	"PING":     true,
+	"SCAN":     true,
+	"SCRIPT":   true,
+	"TIME":     true,
+}
+
+// multiKeyCommands take a key in every argument.
+var multiKeyCommands = map[string]bool{
+	"DEL":         true,
+	"EXISTS":      true,
+	"MGET":        true,
+	"SDIFF":       true,
+	"SDIFFSTORE":  true,
+	"SINTER":      true,
+	"SINTERSTORE": true,
+	"SUNION":      true,
+	"SUNIONSTORE": true,
+	"TOUCH":       true,
+	"UNLINK":      true,
+	"WATCH":       true,
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// commandKeys returns the keys addressed by a command.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func commandKeys(cmd string, args []interface{}) []string {
+	switch {
+	case keylessCommands[cmd] || len(args) == 0:
+		return nil
+	case multiKeyCommands[cmd]:
+		return keyStrings(args)
+	case cmd == "MSET" || cmd == "MSETNX":
+		keys := make([]interface{}, 0, len(args)/2)
+		for i := 0; i < len(args); i += 2 {
+			keys = append(keys, args[i])
+		}
+
+		return keyStrings(keys)
+	case cmd == "BLPOP" || cmd == "BRPOP":
+		return keyStrings(args[:len(args)-1])
+	case cmd == "LMOVE" || cmd == "BLMOVE" || cmd == "RPOPLPUSH" || cmd == "BRPOPLPUSH" || cmd == "SMOVE":
+		if len(args) < 2 {
+			return keyStrings(args)
+		}
+
+		return keyStrings(args[:2])
+	case cmd == "EVAL" || cmd == "EVALSHA":
+		if len(args) < 2 {
+			return nil
+		}
+
+		n, err := strconv.Atoi(fmt.Sprint(args[1]))
+		if err != nil || n <= 0 || len(args) < 2+n {
+			return nil
+		}
+
+		return keyStrings(args[2 : 2+n])
+	}
+
+	return keyStrings(args[:1])
+}
+
+func keyStrings(args []interface{}) []string {
+	keys := make([]string, len(args))
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case string:
+			keys[i] = arg
+		case []byte:
+			keys[i] = string(arg)
+		default:
+			keys[i] = fmt.Sprint(arg)
+		}
+	}
+
+	return keys
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// commandSlot returns the slot of the keys of a command, -1 if the command has no keys. Keys
+// mapping to different slots are rejected like redis does with CROSSSLOT.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func commandSlot(cmd string, args []interface{}) (int, error) {
+	keys := commandKeys(cmd, args)
+	if len(keys) == 0 {
+		return -1, nil
+	}
+
+	slot := KeySlot(keys[0])
+	for _, key := range keys[1:] {
+		if KeySlot(key) != slot {
+			return 0, fmt.Errorf("store: CROSSSLOT keys of %s don't map to the same cluster slot, use TaggedKey", cmd)
+		}
+	}
+
+	return slot, nil
+}
+
+var crc16Table = func() (table [256]uint16) {
+	for i := range table {
+		crc := uint16(i) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+
+		table[i] = crc
+	}
+
+	return table
+}()
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// crc16 is the CRC16-CCITT (XMODEM) checksum used by redis cluster.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^s[i]]
+	}
+
+	return crc
+}
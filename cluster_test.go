@@ -0,0 +1,305 @@
+package store
+
+import (
+	"fmt"
+	"github.com/denkhaus/tcgl/asserts"
+	"github.com/garyburd/redigo/redis"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// fakeCluster is a two node cluster sharing one keyspace. Slots below split are served by the
+// first node, all others by the second one. CLUSTER SLOTS reports the split as well, a slot in
+// migration is answered with ASK by the first node.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+type fakeCluster struct {
+	nodes [2]*fakeServer
+
+	mu        sync.Mutex
+	split     int
+	migrating int
+	data      map[string][]byte
+}
+
+func newFakeCluster(t *testing.T) *fakeCluster {
+	fc := &fakeCluster{split: ClusterSlots / 2, migrating: -1, data: make(map[string][]byte)}
+	for i := range fc.nodes {
+		fc.nodes[i] = newFakeServer(t, fc.handler(i))
+	}
+
+	return fc
+}
+
+func (fc *fakeCluster) reshard(split int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.split = split
+}
+
+func (fc *fakeCluster) migrate(slot int) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.migrating = slot
+}
+
+func (fc *fakeCluster) owner(slot int) int {
+	if slot < fc.split {
+		return 0
+	}
+
+	return 1
+}
+
+func (fc *fakeCluster) handler(node int) func(args []string) interface{} {
+	return func(args []string) interface{} {
+		fc.mu.Lock()
+		defer fc.mu.Unlock()
+
+		switch args[0] {
+		case "CLUSTER":
+			return []interface{}{
+				fc.slotRange(0, fc.split-1, 0),
+				fc.slotRange(fc.split, ClusterSlots-1, 1),
+			}
+		case "SCAN":
+			keys := []interface{}{}
+			for key := range fc.data {
+				if fc.owner(KeySlot(key)) == node {
+					keys = append(keys, []byte(key))
+				}
+			}
+
+			return []interface{}{[]byte("0"), keys}
+		case "GET", "SET", "DEL", "MGET", "MSET":
+			slot := KeySlot(args[1])
+			owner := fc.owner(slot)
+
+			if slot == fc.migrating {
+				if node == 0 {
+					return redis.Error(fmt.Sprintf("ASK %d %s", slot, fc.nodes[1].addr()))
+				}
+
+				cmds := fc.nodes[1].received()
+				if len(cmds) < 2 || cmds[len(cmds)-2][0] != "ASKING" {
+					return redis.Error(fmt.Sprintf("MOVED %d %s", slot, fc.nodes[0].addr()))
+				}
+
+				owner = 1
+			}
+
+			if owner != node {
+				return redis.Error(fmt.Sprintf("MOVED %d %s", slot, fc.nodes[owner].addr()))
+			}
+
+			keys := args[1:]
+			switch args[0] {
+			case "GET", "SET":
+				keys = args[1:2]
+			case "MSET":
+				keys = nil
+				for i := 1; i < len(args); i += 2 {
+					keys = append(keys, args[i])
+				}
+			}
+
+			for _, key := range keys {
+				if KeySlot(key) != slot {
+					return redis.Error("CROSSSLOT Keys in request don't hash to the same slot")
+				}
+			}
+
+			switch args[0] {
+			case "GET":
+				return fc.data[args[1]]
+			case "SET":
+				fc.data[args[1]] = []byte(args[2])
+			case "MGET":
+				vals := make([]interface{}, len(keys))
+				for i, key := range keys {
+					if val, found := fc.data[key]; found {
+						vals[i] = val
+					}
+				}
+
+				return vals
+			case "MSET":
+				for i := 1; i < len(args); i += 2 {
+					fc.data[args[i]] = []byte(args[i+1])
+				}
+			case "DEL":
+				for _, key := range args[1:] {
+					delete(fc.data, key)
+				}
+
+				return len(args) - 1
+			}
+		}
+
+		return okHandler(args)
+	}
+}
+
+func (fc *fakeCluster) slotRange(start, end, node int) interface{} {
+	host, port, _ := net.SplitHostPort(fc.nodes[node].addr())
+	p, _ := strconv.Atoi(port)
+	return []interface{}{start, end, []interface{}{[]byte(host), p, []byte(fmt.Sprintf("node%d", node))}}
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns a key mapping to a slot in [min, max).
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func keyInSlots(min, max int) string {
+	for i := 0; ; i++ {
+		key := fmt.Sprintf("testClusterKey%d", i)
+		if slot := KeySlot(key); slot >= min && slot < max {
+			return key
+		}
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestKeySlot
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestKeySlot(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	assert.Equal(KeySlot("123456789"), 12739, "wrong slot")
+	assert.Equal(KeySlot("foo"), 12182, "wrong slot")
+	assert.Equal(KeySlot("{user1000}.following"), KeySlot("{user1000}.followers"), "hash tags should share a slot")
+	assert.Equal(KeySlot("foo{}{bar}"), int(crc16("foo{}{bar}")%ClusterSlots), "an empty tag should hash the whole key")
+	assert.Equal(KeySlot("foo{{bar}}zap"), KeySlot("{bar"), "the tag should end at the first }")
+	assert.Equal(KeySlot(TaggedKey("user1000", "hash")), KeySlot(TaggedKey("user1000", "zset")), "tagged keys should share a slot")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestClusterRedirect
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestClusterRedirect(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	fc := newFakeCluster(t)
+
+	st, err := OpenCluster([]string{fc.nodes[0].addr()})
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	key := keyInSlots(12000, 13000)
+	err = st.Set(key, "value")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(countCommands(fc.nodes[0], "SET"), 0, "SET should be routed by slot")
+	assert.Equal(countCommands(fc.nodes[1], "SET"), 1, "SET should be routed by slot")
+
+	fc.reshard(13000)
+
+	res, err := st.Get(key)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(res, "value", "Get should follow MOVED")
+
+	_, err = st.Get(key)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(countCommands(fc.nodes[0], "GET"), 2, "GET should go to the new owner")
+	assert.Equal(countCommands(fc.nodes[1], "GET"), 1, "the slot map should have been updated")
+
+	other := keyInSlots(0, 1000)
+	fc.migrate(KeySlot(other))
+
+	err = st.Set(other, "value")
+	assert.Nil(err, "Set should follow ASK")
+	cmds := fc.nodes[1].received()
+	assert.Equal(cmds[len(cmds)-2][0], "ASKING", "ASK should be followed with ASKING")
+	assert.Equal(cmds[len(cmds)-1][0], "SET", "ASK should be followed with ASKING")
+
+	fc.migrate(-1)
+	err = st.Set(other, "value")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(countCommands(fc.nodes[0], "SET"), 2, "ASK should not update the slot map")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestClusterEnumerateKeys
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestClusterEnumerateKeys(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	fc := newFakeCluster(t)
+
+	st, err := OpenCluster([]string{closedAddr(t), fc.nodes[1].addr()})
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	keys := []string{keyInSlots(0, 1000), keyInSlots(15000, ClusterSlots)}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		err := st.Set(key, "value")
+		assert.Nil(err, "Error should be nil.")
+	}
+
+	var res []string
+	err = st.EnumerateKeys("*", func(idx int, key string) error {
+		res = append(res, key)
+		return nil
+	})
+	assert.Nil(err, "Error should be nil.")
+	sort.Strings(res)
+	assert.Equal(res, keys, "EnumerateKeys should scan every master")
+
+	_, _, err = st.Enumerate(0, "*", 10)
+	assert.NotNil(err, "Enumerate is not supported on a cluster")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestClusterCrossSlot
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestClusterCrossSlot(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	fc := newFakeCluster(t)
+
+	st, err := OpenCluster([]string{fc.nodes[0].addr()})
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	_, err = st.do("DEL", keyInSlots(0, 1000), keyInSlots(15000, ClusterSlots))
+	assert.ErrorMatch(err, "CROSSSLOT", "keys of different slots should be rejected")
+
+	n, err := redis.Int(st.do("DEL", TaggedKey("user1000", "hash"), TaggedKey("user1000", "zset")))
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "tagged keys should be deleted together")
+
+	_, err = OpenCluster([]string{fc.nodes[0].addr()}, WithDB(1))
+	assert.ErrorMatch(err, "database 0", "cluster only supports database 0")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestClusterMultiKey
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestClusterMultiKey(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	fc := newFakeCluster(t)
+
+	st, err := OpenCluster([]string{fc.nodes[0].addr()})
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	low, high := keyInSlots(0, 1000), keyInSlots(15000, ClusterSlots)
+	tagged := TaggedKey(low, "other")
+
+	assert.Nil(st.MSet(map[string]interface{}{low: "low", high: "high", tagged: "tagged"}), "Error should be nil.")
+	assert.Equal(countCommands(fc.nodes[0], "MSET"), 1, "keys of the same slot should be set together")
+	assert.Equal(countCommands(fc.nodes[1], "MSET"), 1, "every slot should get a MSET")
+
+	vals, found, err := st.MGet(high, "missing"+high, low, tagged)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(vals, []interface{}{"high", nil, "low", "tagged"}, "the values should be aligned with the keys")
+	assert.Equal(found, []bool{true, false, true, true}, "found should report the missing key")
+
+	assert.Nil(st.Delete(low, high, tagged), "Error should be nil.")
+	assert.Length(fc.data, 0, "the keys of all slots should be deleted")
+
+	_, err = st.MSetNX(map[string]interface{}{low: 1, high: 2})
+	assert.ErrorMatch(err, "CROSSSLOT", "MSetNX should reject keys of different slots")
+}
@@ -0,0 +1,278 @@
+package store
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Codec converts values into the byte representation that is stored in redis and back.
+// Implementations must be safe for concurrent use. The counters, like Store.Incr, store raw
+// decimal numbers, which Unmarshal should decode as numbers; the codecs of this package do.
+// Marshal must not encode other values as decimal numbers, they would be read as counters.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// DefaultCodec is used by a Store without a configured Codec.
+var DefaultCodec Codec = MsgpackCodec{}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		"msgpack": MsgpackCodec{},
+		"json":    JSONCodec{},
+		"gob":     GobCodec{},
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// RegisterCodec makes a Codec available under the given name. It panics if codec is nil
+// or if RegisterCodec is called twice with the same name.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func RegisterCodec(name string, codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	if codec == nil {
+		panic("store: RegisterCodec codec is nil")
+	}
+
+	if _, dup := codecs[name]; dup {
+		panic("store: RegisterCodec called twice for codec " + name)
+	}
+
+	codecs[name] = codec
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// LookupCodec returns the Codec registered under name.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func LookupCodec(name string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	codec, ok := codecs[name]
+	return codec, ok
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// MsgpackCodec encodes values with msgpack. This is the default codec.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	// Marshal encodes integers with a fixed width, the default of msgpack v4, e.g. 48 as
+	// d3 00 00 00 00 00 00 00 30, so no encoded value is a decimal number and anything decimal
+	// is a counter. With UseCompactEncoding the integers 48 to 57 are encoded as the single
+	// bytes '0' to '9' and would be read as counters; TestMsgpackDigits guards this.
+	if isNumber, err := decodeNumber(data, v); isNumber {
+		return err
+	}
+
+	return msgpack.Unmarshal(data, v)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// JSONCodec encodes values with encoding/json. Numbers decoded into an interface{}
+// come back as float64.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// GobCodec encodes values with encoding/gob. Values are always transmitted as interface
+// values, so they can be decoded into an interface{} as well as into their concrete type.
+// Concrete types other than the gob builtins and generic maps and slices must be
+// registered with gob.Register.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type GobCodec struct{}
+
+func init() {
+	gob.Register(map[string]interface{}{})
+	gob.Register(map[string]string{})
+	gob.Register([]interface{}{})
+}
+
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	if isNumber, err := decodeNumber(data, v); isNumber {
+		return err
+	}
+
+	var out interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&out); err != nil {
+		return err
+	}
+
+	return assignValue(v, out)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// decodeNumber decodes a raw decimal number, as stored by the counters, into the value pointed
+// to by v: integers as int64, others as float64. It reports whether data is such a number.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func decodeNumber(data []byte, v interface{}) (bool, error) {
+	if !isDecimal(data) {
+		return false, nil
+	}
+
+	if n, err := strconv.ParseInt(string(data), 10, 64); err == nil {
+		return true, assignValue(v, n)
+	}
+
+	f, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return false, nil
+	}
+
+	return true, assignValue(v, f)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// isDecimal reports whether data is a number like -12, 0.5 or 1e+21.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func isDecimal(data []byte) bool {
+	i := 0
+	if i < len(data) && data[i] == '-' {
+		i++
+	}
+
+	digits := 0
+	for ; i < len(data) && data[i] >= '0' && data[i] <= '9'; i++ {
+		digits++
+	}
+
+	if i < len(data) && data[i] == '.' {
+		for i++; i < len(data) && data[i] >= '0' && data[i] <= '9'; i++ {
+			digits++
+		}
+	}
+
+	if digits == 0 {
+		return false
+	}
+
+	if i < len(data) && (data[i] == 'e' || data[i] == 'E') {
+		i++
+		if i < len(data) && (data[i] == '+' || data[i] == '-') {
+			i++
+		}
+
+		exp := i
+		for ; i < len(data) && data[i] >= '0' && data[i] <= '9'; i++ {
+		}
+
+		if i == exp {
+			return false
+		}
+	}
+
+	return i == len(data)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// assignValue stores src in the value pointed to by dst, converting between
+// compatible types where needed.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func assignValue(dst interface{}, src interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("store: decode target must be a non-nil pointer, got %T", dst)
+	}
+
+	elem := rv.Elem()
+	if src == nil {
+		elem.Set(reflect.Zero(elem.Type()))
+		return nil
+	}
+
+	sv := reflect.ValueOf(src)
+	switch {
+	case sv.Type().AssignableTo(elem.Type()):
+		elem.Set(sv)
+	case isNumberKind(sv.Kind()) && isNumberKind(elem.Kind()):
+		elem.Set(sv.Convert(elem.Type()))
+	default:
+		return fmt.Errorf("store: cannot decode %T into %s", src, elem.Type())
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// isNumberKind reports whether values of kind k can be converted between each other.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// codec returns the configured Codec or DefaultCodec.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) codec() Codec {
+	if s.Codec == nil {
+		return DefaultCodec
+	}
+
+	return s.Codec
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// encode marshals a value with the store codec.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) encode(value interface{}) ([]byte, error) {
+	return s.codec().Marshal(value)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// decode unmarshals a bulk reply with the store codec into the value pointed to by v. Errors
+// are returned as *DecodeError for key and the optional hash field.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) decode(key, field string, data interface{}, v interface{}) error {
+	b, ok := data.([]byte)
+	if !ok {
+		return &DecodeError{Key: key, Field: field, Err: fmt.Errorf("unexpected reply type %T", data)}
+	}
+
+	if err := s.codec().Unmarshal(b, v); err != nil {
+		return &DecodeError{Key: key, Field: field, Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/denkhaus/tcgl/asserts"
+	"github.com/vmihailenco/msgpack"
+	"testing"
+)
+
+type codecTestItem struct {
+	Name  string
+	Count int
+	Tags  []string
+}
+
+func init() {
+	gob.Register(codecTestItem{})
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// testCodecRoundTrip is the round-trip suite every Codec has to pass.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func testCodecRoundTrip(t *testing.T, codec Codec) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	// strings are what the store tests rely on when decoding into interface{}
+	b, err := codec.Marshal("This is a test")
+	assert.Nil(err, "Error should be nil.")
+
+	var raw interface{}
+	err = codec.Unmarshal(b, &raw)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(raw, "This is a test", "string roundtrip into interface{}")
+
+	var str string
+	err = codec.Unmarshal(b, &str)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(str, "This is a test", "string roundtrip into string")
+
+	b, err = codec.Marshal(int64(-42))
+	assert.Nil(err, "Error should be nil.")
+
+	var num int64
+	err = codec.Unmarshal(b, &num)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(num, int64(-42), "int64 roundtrip")
+
+	b, err = codec.Marshal(3.5)
+	assert.Nil(err, "Error should be nil.")
+
+	var f float64
+	err = codec.Unmarshal(b, &f)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(f, 3.5, "float64 roundtrip")
+
+	b, err = codec.Marshal(true)
+	assert.Nil(err, "Error should be nil.")
+
+	var ok bool
+	err = codec.Unmarshal(b, &ok)
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "bool roundtrip")
+
+	item := codecTestItem{Name: "item", Count: 7, Tags: []string{"a", "b"}}
+	b, err = codec.Marshal(item)
+	assert.Nil(err, "Error should be nil.")
+
+	var out codecTestItem
+	err = codec.Unmarshal(b, &out)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(out, item, "struct roundtrip")
+
+	m := map[string]string{"key1": "value1", "key2": "value2"}
+	b, err = codec.Marshal(m)
+	assert.Nil(err, "Error should be nil.")
+
+	var mOut map[string]string
+	err = codec.Unmarshal(b, &mOut)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(mOut, m, "map roundtrip")
+
+	err = codec.Unmarshal([]byte{0xc1, 0xff, 0x00}, &out)
+	assert.NotNil(err, "Error should not be nil for garbage input.")
+
+	// small integers must not be confused with the raw numbers of the counters
+	for i := 0; i < 128; i++ {
+		b, err = codec.Marshal(i)
+		assert.Nil(err, "Error should be nil.")
+
+		var n int
+		assert.Nil(codec.Unmarshal(b, &n), "Error should be nil.")
+		assert.Equal(n, i, "small int roundtrip")
+	}
+
+	// raw numbers written by the counters
+	var counter int
+	err = codec.Unmarshal([]byte("7"), &counter)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(counter, 7, "raw integer into int")
+
+	err = codec.Unmarshal([]byte("-2.5"), &f)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(f, -2.5, "raw float into float64")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestCodecs
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestCodecs(t *testing.T) {
+	for _, name := range []string{"msgpack", "json", "gob"} {
+		codec, ok := LookupCodec(name)
+		if !ok {
+			t.Fatalf("codec %s is not registered", name)
+		}
+
+		t.Run(name, func(t *testing.T) {
+			testCodecRoundTrip(t, codec)
+		})
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestDecodeNumber
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestDecodeNumber(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	for _, s := range []string{"0", "42", "-7", "0.5", "-10.25", "1e+21", "3E-5"} {
+		assert.True(isDecimal([]byte(s)), "should be a number: "+s)
+	}
+
+	for _, s := range []string{"", "-", ".", "1e", "+1", "1x", "inf", "\x01"} {
+		assert.False(isDecimal([]byte(s)), "should not be a number: "+s)
+	}
+
+	var v interface{}
+	isNumber, err := decodeNumber([]byte("12"), &v)
+	assert.True(isNumber, "12 should be a number")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(v, int64(12), "integers should decode as int64")
+
+	isNumber, err = decodeNumber([]byte("1.5"), &v)
+	assert.True(isNumber, "1.5 should be a number")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(v, 1.5, "floats should decode as float64")
+
+	var s string
+	isNumber, err = decodeNumber([]byte("12"), &s)
+	assert.True(isNumber, "12 should be a number")
+	assert.NotNil(err, "a number should not decode into a string")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestMsgpackDigits
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestMsgpackDigits(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	// MsgpackCodec.Unmarshal reads decimal data as a raw counter, which relies on msgpack
+	// encoding integers with a type prefix, never as a digit.
+	for i := -64; i < 256; i++ {
+		for _, v := range []interface{}{i, int8(i), int16(i), int32(i), int64(i),
+			uint(i), uint8(i), uint16(i), uint32(i), uint64(i), float32(i), float64(i)} {
+			data, err := MsgpackCodec{}.Marshal(v)
+			assert.Nil(err, "Error should be nil.")
+			assert.False(isDecimal(data), "msgpack should not encode a number as a decimal")
+		}
+	}
+
+	var compact bytes.Buffer
+	assert.Nil(msgpack.NewEncoder(&compact).UseCompactEncoding(true).Encode(48), "Error should be nil.")
+	assert.True(isDecimal(compact.Bytes()), "the compact encoding of 48 should be the digit 0")
+
+	var n int
+	assert.Nil(st.Set("testMsgpackDigits", 48), "Error should be nil.")
+	assert.Nil(st.GetInto("testMsgpackDigits", &n), "Error should be nil.")
+	assert.Equal(n, 48, "48 should not be read as the counter 0")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestRegisterCodec
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestRegisterCodec(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	t.Cleanup(func() {
+		codecsMu.Lock()
+		delete(codecs, "test-json")
+		codecsMu.Unlock()
+	})
+
+	RegisterCodec("test-json", JSONCodec{})
+	codec, ok := LookupCodec("test-json")
+	assert.True(ok, "registered codec not found")
+	testCodecRoundTrip(t, codec)
+
+	defer func() {
+		assert.NotNil(recover(), "duplicate RegisterCodec should panic")
+	}()
+
+	RegisterCodec("test-json", JSONCodec{})
+}
@@ -0,0 +1,252 @@
+package store
+
+import (
+	"context"
+	"github.com/garyburd/redigo/redis"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithContext returns a shallow copy of the store whose operations are bound to ctx.
+// Connections are acquired with the pool's GetContext, every command is bounded by the
+// deadline of ctx and operations return ctx.Err() as soon as ctx is done. Both stores share
+// the same pool.
+//
+// The connection of an operation cancelled by ctx is closed. The server may have executed the
+// command nonetheless, so a write that returned ctx.Err() has to be treated as possibly
+// applied.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) WithContext(ctx context.Context) *Store {
+	if ctx == nil {
+		panic("store: nil context")
+	}
+
+	s2 := *s
+	s2.ctx = ctx
+	return &s2
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Context returns the context of the store. It defaults to context.Background().
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+
+	return s.ctx
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// exec runs fn on a connection of the master pool.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) exec(fn func(conn redis.Conn) error) error {
+	return s.execPool(s.Pool, fn)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// execPool runs fn on a connection of pool. On a sentinel store a READONLY error means the
+// master has been demoted, so the master is resolved again and fn is retried once. Errors are
+// translated by mapError.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) execPool(pool *redis.Pool, fn func(conn redis.Conn) error) error {
+	err := s.run(pool, fn)
+	if s.sentinel != nil && s.sentinel.failover(err) {
+		err = s.run(pool, fn)
+	}
+
+	return mapError(err)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// run runs fn on a connection of pool. If the store context can be cancelled, fn runs in its
+// own goroutine, so run is able to return ctx.Err() right away. The connection is closed as
+// soon as ctx is done, which ends fn and keeps the connection out of the pool. Connections of
+// pools not created by this package, see NewStoreWithPool, are released once fn has finished.
+// Once ctx is done, errors of fn are reported as ctx.Err(), also the timeouts of reads bounded
+// by the deadline of ctx.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) run(pool *redis.Pool, fn func(conn redis.Conn) error) error {
+	err := s.runConn(pool, fn)
+	if err != nil {
+		if ctxErr := contextErr(s.Context()); ctxErr != nil {
+			return ctxErr
+		}
+	}
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// contextErr returns ctx.Err() or DeadlineExceeded if the deadline of ctx has passed, which
+// may happen shortly before ctx is done.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func contextErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if deadline, hasDeadline := ctx.Deadline(); hasDeadline && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+
+	return nil
+}
+
+func (s *Store) runConn(pool *redis.Pool, fn func(conn redis.Conn) error) error {
+	ctx := s.Context()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Done() == nil {
+		defer conn.Close()
+		return fn(conn)
+	}
+
+	raw, _ := conn.Do("", rawConnRequest{})
+
+	deadline, _ := ctx.Deadline()
+	done := make(chan error, 1)
+
+	go func() {
+		err := fn(contextConn{Conn: conn, deadline: deadline})
+		conn.Close()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if raw, isConn := raw.(redis.Conn); isConn {
+			raw.Close()
+		}
+
+		return ctx.Err()
+	}
+}
+
+// rawConnRequest is answered by poolConn with the wrapped connection. It is sent with the empty
+// command, which other connections of redigo answer with nil without writing to the server.
+type rawConnRequest struct{}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// poolConn wraps the connections of the pools created by newPool. Pooled connections of redigo
+// hide the dialed connection, poolConn returns it for a rawConnRequest, so that run is able to
+// close it while a command is in flight.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type poolConn struct {
+	redis.Conn
+}
+
+func (c *poolConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if len(args) == 1 && cmd == "" {
+		if _, isRequest := args[0].(rawConnRequest); isRequest {
+			return c.Conn, nil
+		}
+	}
+
+	return c.Conn.Do(cmd, args...)
+}
+
+func (c *poolConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+func (c *poolConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// do executes a single command on a pooled connection. Read only commands go to a replica
+// if the store has been opened with WithReplicaReads, on a cluster the command is routed by
+// its key. Within a Tx the command runs on the connection of the transaction.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) do(cmd string, args ...interface{}) (interface{}, error) {
+	if s.conn != nil {
+		reply, err := s.conn.Do(cmd, args...)
+		if err != nil {
+			if ctxErr := contextErr(s.Context()); ctxErr != nil {
+				return nil, ctxErr
+			}
+
+			return nil, mapError(err)
+		}
+
+		return reply, nil
+	}
+
+	if s.cluster != nil {
+		return s.doCluster(cmd, args...)
+	}
+
+	pool := s.Pool
+	if s.replicas != nil && readOnlyCommands[cmd] {
+		pool = s.replicas
+	}
+
+	var reply interface{}
+	err := s.execPool(pool, func(conn redis.Conn) (err error) {
+		reply, err = conn.Do(cmd, args...)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// contextConn bounds every read of the wrapped connection by a context deadline.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type contextConn struct {
+	redis.Conn
+	deadline time.Time
+}
+
+func (c contextConn) timeout() (time.Duration, error) {
+	if c.deadline.IsZero() {
+		return 0, nil
+	}
+
+	timeout := time.Until(c.deadline)
+	if timeout <= 0 {
+		return 0, context.DeadlineExceeded
+	}
+
+	return timeout, nil
+}
+
+func (c contextConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	timeout, err := c.timeout()
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout == 0 {
+		return c.Conn.Do(cmd, args...)
+	}
+
+	return redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+func (c contextConn) Receive() (interface{}, error) {
+	timeout, err := c.timeout()
+	if err != nil {
+		return nil, err
+	}
+
+	if timeout == 0 {
+		return c.Conn.Receive()
+	}
+
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"github.com/denkhaus/tcgl/asserts"
+	"github.com/garyburd/redigo/redis"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// Create a Store whose server accepts commands but never answers.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func createStallingStore(t *testing.T) *Store {
+	return &Store{Pool: &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			client, server := net.Pipe()
+			go io.Copy(ioutil.Discard, server)
+			return redis.NewConn(client, 0, 0), nil
+		},
+	}}
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestContextCancel
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestContextCancel(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStallingStore(t)
+	defer st.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := st.WithContext(ctx).Get("testKey1")
+	assert.Equal(err, context.Canceled, "Get should return ctx.Err()")
+	assert.True(time.Since(start) < time.Second, "Get should return promptly")
+
+	_, err = st.WithContext(ctx).HashGetValues("testHash")
+	assert.Equal(err, context.Canceled, "a done context should fail right away")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestContextDeadline
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestContextDeadline(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStallingStore(t)
+	defer st.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := st.WithContext(ctx).Set("testKey1", "value")
+	assert.NotNil(err, "Set should fail on deadline")
+	assert.True(time.Since(start) < time.Second, "Set should return promptly")
+	assert.Equal(st.Context(), context.Background(), "WithContext must not modify the original store")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestContextCancelClosesConn
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestContextCancelClosesConn(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	srv := newFakeServer(t, func(args []string) interface{} {
+		if args[0] == "GET" {
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		return "PONG"
+	})
+
+	st, err := Open(srv.addr())
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = st.WithContext(ctx).Get("testKey1")
+	assert.Equal(err, context.Canceled, "Get should return ctx.Err()")
+	assert.True(time.Since(start) < 100*time.Millisecond, "Get should return promptly")
+
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(st.Pool.ActiveCount(), 0, "the connection of the cancelled call should be closed")
+	assert.Equal(st.Pool.IdleCount(), 0, "the connection of the cancelled call should not be reused")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestContextForeignPool
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestContextForeignPool(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	srv := newFakeServer(t, func(args []string) interface{} {
+		if args[0] == "LLEN" {
+			return 0
+		}
+
+		return "PONG"
+	})
+
+	pool := &redis.Pool{MaxIdle: 1, Dial: func() (redis.Conn, error) {
+		return redis.Dial("tcp", srv.addr())
+	}}
+
+	st, err := NewStoreWithPool(pool)
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err = st.WithContext(ctx).ListLen("testKey1")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(pool.IdleCount(), 1, "the connection of a foreign pool should be released")
+	assert.Equal(srv.received(), [][]string{{"PING"}, {"LLEN", "testKey1"}},
+		"only the commands of the store should be sent")
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"github.com/garyburd/redigo/redis"
+	"strconv"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Incr increments the counter at key by one and returns the new value. Counters are stored as
+// raw decimal numbers, not encoded by the codec, and start at 0 if key does not exist. Get,
+// GetInto and the typed handles decode them as numbers.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) Incr(key string) (int64, error) {
+	return redis.Int64(s.do("INCR", key))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Decr decrements the counter at key by one and returns the new value, see Incr.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) Decr(key string) (int64, error) {
+	return redis.Int64(s.do("DECR", key))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// IncrBy adds n to the counter at key and returns the new value, see Incr. Incrementing a
+// value that is not a raw integer fails.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) IncrBy(key string, n int64) (int64, error) {
+	return redis.Int64(s.do("INCRBY", key, n))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// IncrByFloat adds f to the floating point counter at key and returns the new value, see
+// Incr.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) IncrByFloat(key string, f float64) (float64, error) {
+	return redis.Float64(s.do("INCRBYFLOAT", key, strconv.FormatFloat(f, 'g', -1, 64)))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// HashIncrBy adds n to the counter in a hash field and returns the new value. Like Incr the
+// field holds a raw decimal number that HashGet decodes.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) HashIncrBy(hash, field string, n int64) (int64, error) {
+	return redis.Int64(s.do("HINCRBY", hash, field, n))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// HashIncrByFloat adds f to the floating point counter in a hash field and returns the new
+// value, see HashIncrBy.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) HashIncrByFloat(hash, field string, f float64) (float64, error) {
+	return redis.Float64(s.do("HINCRBYFLOAT", hash, field, strconv.FormatFloat(f, 'g', -1, 64)))
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"github.com/denkhaus/tcgl/asserts"
+	"testing"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestCounters
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestCounters(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	assert.Nil(st.Delete("testCounter", "testCounterFloat", "testCounterHash"), "Error should be nil.")
+
+	n, err := st.Incr("testCounter")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, int64(1), "Incr should start at 0")
+
+	n, err = st.IncrBy("testCounter", 9)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, int64(10), "IncrBy should add n")
+
+	n, err = st.Decr("testCounter")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, int64(9), "Decr should subtract one")
+
+	val, err := st.Get("testCounter")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, int64(9), "Get should decode a counter as int64")
+
+	var i int
+	assert.Nil(st.GetInto("testCounter", &i), "Error should be nil.")
+	assert.Equal(i, 9, "GetInto should decode a counter into an int")
+
+	typed, err := NewValue[uint8](st, "testCounter").Get()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(typed, uint8(9), "a typed value should decode a counter")
+
+	f, err := st.IncrByFloat("testCounterFloat", 10.5)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(f, 10.5, "IncrByFloat should add f")
+
+	f, err = st.IncrByFloat("testCounterFloat", -0.25)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(f, 10.25, "IncrByFloat should add negative values")
+
+	val, err = st.Get("testCounterFloat")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, 10.25, "Get should decode a float counter as float64")
+
+	n, err = st.HashIncrBy("testCounterHash", "hits", 3)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, int64(3), "HashIncrBy should start at 0")
+
+	f, err = st.HashIncrByFloat("testCounterHash", "score", 1.5)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(f, 1.5, "HashIncrByFloat should start at 0")
+
+	val, err = st.HashGet("testCounterHash", "hits")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, int64(3), "HashGet should decode a counter")
+
+	score, err := NewHash[float64](st, "testCounterHash").Get("score")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(score, 1.5, "a typed hash should decode a counter")
+
+	assert.Nil(st.Set("testCounterEncoded", 5), "Error should be nil.")
+	_, err = st.Incr("testCounterEncoded")
+	assert.NotNil(err, "incrementing an encoded value should fail")
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"io"
+	"net"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned if a key or hash field does not exist.
+	ErrNotFound = errors.New("store: not found")
+
+	// ErrWrongType wraps the WRONGTYPE error of a command against a key holding another type.
+	ErrWrongType = errors.New("store: wrong type")
+
+	// ErrDecode is matched by all DecodeErrors.
+	ErrDecode = errors.New("store: decode failed")
+
+	// ErrConnection wraps errors that occurred while dialing or talking to the server.
+	ErrConnection = errors.New("store: connection failed")
+
+	// ErrTxConflict is returned by Tx if the watched keys kept changing during all attempts.
+	ErrTxConflict = errors.New("store: transaction conflict, watched keys have been modified")
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// DecodeError is returned if a stored value could not be decoded by the codec. It matches
+// ErrDecode with errors.Is and unwraps to the codec error.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type DecodeError struct {
+	// Key of the value. Empty for values decoded with DecodeValues.
+	Key string
+
+	// Field is the hash field of the value, if any.
+	Field string
+
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	key := e.Key
+	if e.Field != "" {
+		key += "/" + e.Field
+	}
+
+	if key == "" {
+		return fmt.Sprintf("store: decode value: %v", e.Err)
+	}
+
+	return fmt.Sprintf("store: decode value of %s: %v", key, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrDecode
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// dialError marks the error of dialing a pooled connection, which is a connection error
+// whatever its cause, e.g. a refused connection, a failed TLS handshake or a rejected AUTH.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type dialError struct {
+	err error
+}
+
+func (e *dialError) Error() string {
+	return e.err.Error()
+}
+
+func (e *dialError) Unwrap() error {
+	return e.err
+}
+
+// redigoConnectionErrors are the unexported errors of redigo for closed pools and connections
+// and for malformed replies.
+var redigoConnectionErrors = []string{
+	"redigo: get on closed pool",
+	"redigo: connection pool closed",
+	"redigo: connection closed",
+	"redigo: closed",
+	"(possible server error or unsupported concurrent read by application)",
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// isConnectionError reports whether err occurred while dialing or talking to the server.
+// Context errors are not connection errors, although context.DeadlineExceeded is a net.Error.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func isConnectionError(err error) bool {
+	var nerr net.Error
+	var derr *dialError
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.As(err, &derr), errors.As(err, &nerr), errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, redis.ErrPoolExhausted):
+		return true
+	}
+
+	msg := err.Error()
+	for _, text := range redigoConnectionErrors {
+		if strings.Contains(msg, text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// mapError translates the errors of a command into the errors of this package. Connection
+// errors are wrapped by ErrConnection, WRONGTYPE replies by ErrWrongType. All other errors,
+// e.g. other error replies, reply conversion errors and context errors, are returned unchanged.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func mapError(err error) error {
+	var rerr redis.Error
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return err
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrWrongType), errors.Is(err, ErrDecode),
+		errors.Is(err, ErrConnection), errors.Is(err, ErrTxConflict):
+		return err
+	case errors.Is(err, redis.ErrNil):
+		return ErrNotFound
+	case isConnectionError(err):
+		return fmt.Errorf("%w: %w", ErrConnection, err)
+	case errors.As(err, &rerr) && strings.HasPrefix(string(rerr), "WRONGTYPE"):
+		return fmt.Errorf("%w: %w", ErrWrongType, err)
+	}
+
+	return err
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denkhaus/tcgl/asserts"
+	"github.com/garyburd/redigo/redis"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestErrors
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestErrors(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	srv := newFakeServer(t, func(args []string) interface{} {
+		switch {
+		case args[0] == "PING":
+			return "PONG"
+		case len(args) > 1 && args[1] == "missing":
+			return nil
+		case len(args) > 1 && args[1] == "list":
+			return redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value")
+		case args[0] == "HVALS":
+			return []interface{}{[]byte("\xc1")}
+		}
+
+		return []byte("\xc1")
+	})
+
+	st, err := Open(srv.addr())
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	_, err = st.Get("missing")
+	assert.True(errors.Is(err, ErrNotFound), "a missing key should return ErrNotFound")
+
+	_, err = st.HashGet("missing", "field")
+	assert.True(errors.Is(err, ErrNotFound), "a missing hash field should return ErrNotFound")
+
+	_, err = st.Get("list")
+	assert.True(errors.Is(err, ErrWrongType), "WRONGTYPE should return ErrWrongType")
+	assert.ErrorMatch(err, "WRONGTYPE", "the server error should be kept")
+
+	_, err = st.Get("invalid")
+	assert.True(errors.Is(err, ErrDecode), "an undecodable value should return ErrDecode")
+
+	var derr *DecodeError
+	assert.True(errors.As(err, &derr), "an undecodable value should return a DecodeError")
+	assert.Equal(derr.Key, "invalid", "DecodeError should report the key")
+
+	_, err = st.HashGet("hash", "field")
+	assert.True(errors.As(err, &derr), "an undecodable value should return a DecodeError")
+	assert.Equal(derr.Field, "field", "DecodeError should report the field")
+
+	_, err = st.HashGetValues("hash")
+	assert.True(errors.Is(err, ErrDecode), "an undecodable value should return ErrDecode")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestErrConnection
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestErrConnection(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	srv := newFakeServer(t, okHandler)
+
+	st, err := Open(srv.addr(), WithDialTimeout(time.Second))
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	srv.ln.Close()
+	st.Pool.Close()
+
+	_, err = st.Get("testKey1")
+	assert.True(errors.Is(err, ErrConnection), "a closed pool should return ErrConnection")
+
+	_, err = Open(closedAddr(t))
+	assert.True(errors.Is(err, ErrConnection), "an unreachable server should return ErrConnection")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestErrDeadline
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestErrDeadline(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	srv := newFakeServer(t, func(args []string) interface{} {
+		if args[0] == "GET" {
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		return []byte("\xc0")
+	})
+
+	st, err := Open(srv.addr())
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	// The read timeout and ctx.Done fire at the same time, both must return the deadline.
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err = st.WithContext(ctx).Get("slow")
+		cancel()
+		assert.Equal(err, context.DeadlineExceeded, "a passed deadline should return ctx.Err()")
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestMapError
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestMapError(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	derr := &DecodeError{Key: "key", Err: errors.New("invalid")}
+	assert.Equal(mapError(derr), error(derr), "a DecodeError should be returned unchanged")
+	assert.Equal(mapError(ErrNotFound), ErrNotFound, "ErrNotFound should be returned unchanged")
+
+	wrongType := mapError(redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value"))
+	assert.True(errors.Is(wrongType, ErrWrongType), "WRONGTYPE should return ErrWrongType")
+	assert.Equal(mapError(wrongType), wrongType, "ErrWrongType should not be wrapped again")
+
+	conn := mapError(&net.OpError{Op: "read", Net: "tcp", Err: errors.New("broken pipe")})
+	assert.True(errors.Is(conn, ErrConnection), "a network error should return ErrConnection")
+	assert.Equal(mapError(conn), conn, "ErrConnection should not be wrapped again")
+	assert.True(errors.Is(mapError(io.EOF), ErrConnection), "io.EOF should return ErrConnection")
+	assert.True(errors.Is(mapError(&dialError{err: redis.Error("WRONGPASS invalid password")}), ErrConnection),
+		"a dial error should return ErrConnection")
+
+	_, convErr := redis.Int64([]interface{}{}, nil)
+	assert.NotNil(convErr, "Int64 of an array should fail")
+	assert.False(errors.Is(mapError(convErr), ErrConnection), "a conversion error should not return ErrConnection")
+	assert.Equal(mapError(convErr), convErr, "a conversion error should be returned unchanged")
+
+	own := fmt.Errorf("store: invalid SCAN cursor %q", "x")
+	assert.Equal(mapError(own), own, "an error of this package should be returned unchanged")
+}
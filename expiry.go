@@ -0,0 +1,97 @@
+package store
+
+import (
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"time"
+)
+
+// NoExpiry is returned by TTL for keys without a time to live.
+const NoExpiry time.Duration = -1
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Expire lets key expire after ttl with millisecond precision. It works for keys of any type
+// and reports whether key exists. A ttl that is not positive deletes key.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) Expire(key string, ttl time.Duration) (bool, error) {
+	return redis.Bool(s.do("PEXPIRE", key, millis(ttl)))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// ExpireAt lets key expire at the given time and reports whether key exists. A time in the
+// past deletes key.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ExpireAt(key string, at time.Time) (bool, error) {
+	return redis.Bool(s.do("PEXPIREAT", key, at.UnixMilli()))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// TTL returns the remaining time to live of key, NoExpiry if key does not expire. A missing
+// key will return ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) TTL(key string) (time.Duration, error) {
+	ms, err := redis.Int64(s.do("PTTL", key))
+	switch {
+	case err != nil:
+		return 0, err
+	case ms == -2:
+		return 0, ErrNotFound
+	case ms < 0:
+		return NoExpiry, nil
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Persist removes the time to live of key and reports whether there was one.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) Persist(key string) (bool, error) {
+	return redis.Bool(s.do("PERSIST", key))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// expireNXScript lets KEYS[1] expire after ARGV[1] milliseconds unless it has a time to live
+// already and returns 1 if the expiry has been set, like PEXPIRE with NX, which needs redis 7.
+////////////////////////////////////////////////////////////////////////////////////////////////
+var expireNXScript = newScript(1, `
+if redis.call('PTTL', KEYS[1]) ~= -1 then
+	return 0
+end
+return redis.call('PEXPIRE', KEYS[1], ARGV[1])
+`, func(call func(args ...interface{}) interface{}, keys, argv [][]byte) interface{} {
+	if call("PTTL", keys[0]) != int64(-1) {
+		return int64(0)
+	}
+
+	return call("PEXPIRE", keys[0], argv[0])
+})
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// doWithExpiry executes a command writing key. With a positive ttl the command runs in a
+// transaction that lets key expire after ttl, unless key has a time to live already, so that
+// a collection gets its expiry on the first write. The script is sent with EVAL, since EVALSHA
+// can't fall back to EVAL within the transaction.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) doWithExpiry(key string, ttl []time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	switch {
+	case len(ttl) > 1:
+		return nil, fmt.Errorf("store: at most one ttl is allowed, got %d", len(ttl))
+	case len(ttl) == 0 || ttl[0] == 0:
+		return s.do(cmd, args...)
+	case ttl[0] < 0:
+		return nil, fmt.Errorf("store: invalid ttl %v, must not be negative", ttl[0])
+	}
+
+	var res *BatchResult
+	err := s.multi(key, func(tx *Tx) {
+		res = tx.batch.queue(key, "", cmd, args...)
+		tx.batch.queue(key, "", "EVAL", expireNXScript.src, expireNXScript.keys, key, millis(ttl[0]))
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Reply()
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"errors"
+	"github.com/denkhaus/tcgl/asserts"
+	"github.com/garyburd/redigo/redis"
+	"strings"
+	"testing"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestExpire
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestExpire(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	_, err := st.TTL("testExpireMissing")
+	assert.True(errors.Is(err, ErrNotFound), "TTL of a missing key should return ErrNotFound")
+
+	ok, err := st.Expire("testExpireMissing", time.Minute)
+	assert.Nil(err, "Error should be nil.")
+	assert.False(ok, "Expire should report a missing key")
+
+	assert.Nil(st.HashSet("testExpireHash", "field", "value"), "Error should be nil.")
+	ttl, err := st.TTL("testExpireHash")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(ttl, NoExpiry, "a key without ttl should return NoExpiry")
+
+	ok, err = st.Expire("testExpireHash", time.Minute)
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "Expire should set the ttl of a hash")
+
+	ttl, err = st.TTL("testExpireHash")
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ttl > 59*time.Second && ttl <= time.Minute, "TTL should return the remaining ttl")
+
+	ok, err = st.ExpireAt("testExpireHash", time.Now().Add(time.Hour))
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "ExpireAt should set the ttl")
+
+	ttl, err = st.TTL("testExpireHash")
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ttl > 59*time.Minute && ttl <= time.Hour, "ExpireAt should set an absolute expiry")
+
+	ok, err = st.Persist("testExpireHash")
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "Persist should remove the ttl")
+
+	ttl, err = st.TTL("testExpireHash")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(ttl, NoExpiry, "a persisted key should not expire")
+
+	ok, err = st.ExpireAt("testExpireHash", time.Now().Add(-time.Second))
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "ExpireAt in the past should succeed")
+
+	_, err = st.TTL("testExpireHash")
+	assert.True(errors.Is(err, ErrNotFound), "ExpireAt in the past should delete the key")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestCollectionTTL
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestCollectionTTL(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	assert.Nil(st.HashSet("testTTLHash", "a", 1, time.Minute), "Error should be nil.")
+	assert.Nil(st.HashSet("testTTLHash", "b", 2, time.Hour), "Error should be nil.")
+
+	ttl, err := st.TTL("testTTLHash")
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ttl > 0 && ttl <= time.Minute, "the ttl should be set by the first write only")
+
+	n, err := st.HashSize("testTTLHash")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "both fields should be written")
+
+	n, err = st.SortedSetSet("testTTLZSet", 1, "member", time.Minute)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "SortedSetSet should return the added members")
+
+	ttl, err = st.TTL("testTTLZSet")
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ttl > 0 && ttl <= time.Minute, "SortedSetSet should set the ttl")
+
+	assert.NotNil(st.HashSet("testTTLHash", "c", 3, -time.Second), "a negative ttl should fail")
+	assert.NotNil(st.HashSet("testTTLHash", "c", 3, time.Second, time.Minute), "more than one ttl should fail")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestCollectionTTLRedis6
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestCollectionTTLRedis6(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	srv := newFakeServer(t, func(args []string) interface{} {
+		switch args[0] {
+		case "PING":
+			return "PONG"
+		case "MULTI":
+			return "OK"
+		case "EXEC":
+			return []interface{}{1, 1}
+		case "PEXPIRE", "EXPIRE":
+			if len(args) > 3 {
+				return redis.Error("ERR wrong number of arguments for '" + strings.ToLower(args[0]) + "' command")
+			}
+		}
+
+		return "QUEUED"
+	})
+
+	st, err := Open(srv.addr())
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	assert.Nil(st.HashSet("testTTLHash", "a", 1, time.Minute), "the ttl should not need redis 7")
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// fakeServer is a scripted RESP server. Every command is passed to handler whose return value
+// is written back: string as status, []byte as bulk string, int as integer, nil as nil bulk,
+// redis.Error as error and []interface{} as array.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+type fakeServer struct {
+	ln      net.Listener
+	handler func(args []string) interface{}
+
+	mu       sync.Mutex
+	commands [][]string
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// Start a fake server on a random local port.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func newFakeServer(t *testing.T, handler func(args []string) interface{}) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return serveFake(t, ln, handler)
+}
+
+func serveFake(t *testing.T, ln net.Listener, handler func(args []string) interface{}) *fakeServer {
+	srv := &fakeServer{ln: ln, handler: handler}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go srv.serve(conn)
+		}
+	}()
+
+	return srv
+}
+
+func (srv *fakeServer) addr() string {
+	return srv.ln.Addr().String()
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns all received commands with the command name in upper case.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func (srv *fakeServer) received() [][]string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	return append([][]string(nil), srv.commands...)
+}
+
+func (srv *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		args, err := readFakeCommand(r)
+		if err != nil {
+			return
+		}
+
+		args[0] = strings.ToUpper(args[0])
+		srv.mu.Lock()
+		srv.commands = append(srv.commands, args)
+		srv.mu.Unlock()
+
+		writeFakeReply(w, srv.handler(args))
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func readFakeCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected line %q", line)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid array length %q", line)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+
+		args[i] = string(buf[:size])
+	}
+
+	return args, nil
+}
+
+func writeFakeReply(w *bufio.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case string:
+		w.WriteString("+" + v + "\r\n")
+	case []byte:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case int:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case redis.Error:
+		w.WriteString("-" + string(v) + "\r\n")
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, elem := range v {
+			writeFakeReply(w, elem)
+		}
+	default:
+		panic(fmt.Sprintf("unsupported fake reply %T", reply))
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// okHandler answers PING with PONG and everything else with OK.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func okHandler(args []string) interface{} {
+	if args[0] == "PING" {
+		return "PONG"
+	}
+
+	return "OK"
+}
@@ -1,27 +1,23 @@
 package store
 
 import (
+	"fmt"
 	"github.com/garyburd/redigo/redis"
-	"github.com/vmihailenco/msgpack"
+	"reflect"
+	"time"
 )
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
-// Sets key and value in a hash
+// Sets key and value in a hash. An optional ttl lets a new hash expire after ttl; it is set in
+// the same transaction as the field, unless the hash has a time to live already.
 ////////////////////////////////////////////////////////////////////////////////////////////////
-func (s *Store) HashSet(hash, key string, value interface{}) error {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	if err := conn.Err(); err != nil {
-		return err
-	}
-
-	b, err := msgpack.Marshal(value)
+func (s *Store) HashSet(hash, key string, value interface{}, ttl ...time.Duration) error {
+	b, err := s.encode(value)
 	if err != nil {
 		return err
 	}
 
-	_, err = conn.Do("HSET", hash, key, b)
+	_, err = s.doWithExpiry(hash, ttl, "HSET", hash, key, b)
 	if err != nil {
 		return err
 	}
@@ -30,51 +26,78 @@ func (s *Store) HashSet(hash, key string, value interface{}) error {
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
-// Get a value from a hash. A missing value will return nil, nil.
+// Get a value from a hash. A missing value will return ErrNotFound.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) HashGet(hash, key string) (interface{}, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	if err := conn.Err(); err != nil {
+	var out interface{}
+	err := s.HashGetInto(hash, key, &out)
+	if err != nil {
 		return nil, err
 	}
 
-	data, err := conn.Do("HGET", hash, key)
+	return out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Decode a value from a hash into the value pointed to by dst.
+// A missing value will return ErrNotFound and leaves dst untouched.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) HashGetInto(hash, key string, dst interface{}) error {
+	data, err := s.do("HGET", hash, key)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if data == nil {
-		return nil, nil
+		return ErrNotFound
 	}
 
-	b, err := redis.Bytes(data, err)
-	if err != nil {
-		return nil, err
+	return s.decode(hash, key, data, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Decode all fields and values from a hash into the map pointed to by dst,
+// e.g. a *map[string]User. A nil map is allocated.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) HashGetAllInto(hash string, dst interface{}) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Map ||
+		rv.Elem().Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("store: decode target must be a non-nil pointer to a map with string keys, got %T", dst)
 	}
 
-	var out interface{}
-	err = msgpack.Unmarshal(b, &out)
+	vals, err := redis.Values(s.do("HGETALL", hash))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return out, nil
+	m := rv.Elem()
+	if m.IsNil() {
+		m.Set(reflect.MakeMapWithSize(m.Type(), len(vals)/2))
+	}
+
+	for n := 0; n+1 < len(vals); n += 2 {
+		field, err := redis.String(vals[n], nil)
+		if err != nil {
+			return err
+		}
+
+		value := reflect.New(m.Type().Elem())
+		if err := s.decode(hash, field, vals[n+1], value.Interface()); err != nil {
+			return err
+		}
+
+		m.SetMapIndex(reflect.ValueOf(field).Convert(m.Type().Key()), value.Elem())
+	}
+
+	return nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
 // Get all fields from a hash.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) HashGetFields(hash string) ([]string, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	if err := conn.Err(); err != nil {
-		return nil, err
-	}
-
-	data, err := conn.Do("HKEYS", hash)
+	data, err := s.do("HKEYS", hash)
 	if err != nil {
 		return nil, err
 	}
@@ -95,28 +118,40 @@ func (s *Store) HashGetFields(hash string) ([]string, error) {
 // Get all values from a hash
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) HashGetValues(hash string) ([]interface{}, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	if err := conn.Err(); err != nil {
+	vals, err := s.hashValues(hash)
+	if err != nil {
 		return nil, err
 	}
 
-	data, err := conn.Do("HVALS", hash)
+	return s.decodeValues(hash, vals)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Decode all values from a hash into the slice pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) HashGetValuesInto(hash string, dst interface{}) error {
+	vals, err := s.hashValues(hash)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if data == nil {
-		return nil, nil
-	}
+	return s.decodeValuesInto(hash, vals, dst)
+}
 
-	vals, err := redis.Values(data, err)
+////////////////////////////////////////////////////////////////////////////////////////////////
+// hashValues returns the raw values of a hash.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) hashValues(hash string) ([]interface{}, error) {
+	data, err := s.do("HVALS", hash)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.DecodeValues(vals)
+	if data == nil {
+		return nil, nil
+	}
+
+	return redis.Values(data, err)
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
@@ -124,14 +159,7 @@ func (s *Store) HashGetValues(hash string) ([]interface{}, error) {
 // Returns number of fields in the hash, or 0 when key does not exist.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) HashSize(hash string) (int, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	if err := conn.Err(); err != nil {
-		return 0, err
-	}
-
-	data, err := conn.Do("HLEN", hash)
+	data, err := s.do("HLEN", hash)
 	return redis.Int(data, err)
 }
 
@@ -141,14 +169,7 @@ func (s *Store) HashSize(hash string) (int, error) {
 // Returns the number of fields that were removed from the hash, not including specified but non existing fields.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) HashDeleteField(hash, field string) (int, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	if err := conn.Err(); err != nil {
-		return 0, err
-	}
-
-	data, err := conn.Do("HDEL", hash, field)
+	data, err := s.do("HDEL", hash, field)
 	if err != nil {
 		return 0, err
 	}
@@ -86,4 +86,48 @@ func TestHashSetGetSizeGetFieldsGetValues(t *testing.T) {
 			t.Fail()
 		}
 	}
-}
\ No newline at end of file
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestHashGetInto
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestHashGetInto(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	hash := "testHashInto"
+
+	err := st.Delete(hash)
+	assert.Nil(err, "Error should be nil.")
+
+	items := map[string]codecTestItem{}
+	for i := 0; i < 3; i++ {
+		field := fmt.Sprintf("hashfield%d", i)
+		items[field] = codecTestItem{Name: field, Count: i}
+
+		err := st.HashSet(hash, field, items[field])
+		assert.Nil(err, "Error should be nil.")
+	}
+
+	var item codecTestItem
+	err = st.HashGetInto(hash, "hashfield1", &item)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(item, items["hashfield1"], "HashGetInto wrong value")
+
+	err = st.HashGetInto(hash, "missing", &item)
+	assert.NotNil(err, "HashGetInto on a missing field should fail.")
+
+	var all map[string]codecTestItem
+	err = st.HashGetAllInto(hash, &all)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(all, items, "HashGetAllInto wrong value")
+
+	var values []codecTestItem
+	err = st.HashGetValuesInto(hash, &values)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(values, 3, "HashGetValuesInto wrong length")
+
+	err = st.HashGetAllInto(hash, all)
+	assert.NotNil(err, "HashGetAllInto needs a pointer.")
+}
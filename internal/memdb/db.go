@@ -0,0 +1,345 @@
+// Package memdb is an in-memory engine that executes redis commands. It backs the in-memory
+// Store and the embedded RESP server and follows the semantics of redis as close as the
+// store needs it.
+package memdb
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is a simple string reply like OK.
+type Status string
+
+// Error is an error reply.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Replies of commands are nil, Status, Error, int64, []byte or []interface{} of replies.
+var (
+	ok = Status("OK")
+
+	errWrongType  = Error("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errSyntax     = Error("ERR syntax error")
+	errNotInteger = Error("ERR value is not an integer or out of range")
+	errNotFloat   = Error("ERR value is not a valid float")
+	errNoSuchKey  = Error("ERR no such key")
+	errIndex      = Error("ERR index out of range")
+	errDBIndex    = Error("ERR DB index is out of range")
+	errOverflow   = Error("ERR increment or decrement would overflow")
+	errTimeout    = Error("ERR timeout is not a float or out of range")
+	errClosed     = Error("ERR connection closed")
+)
+
+// Databases is the number of databases selectable with SELECT.
+const Databases = 16
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// DB holds the databases. It is safe for concurrent use, every command is executed atomically.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type DB struct {
+	mu       sync.Mutex
+	spaces   [Databases]*keyspace
+	offset   time.Duration
+	watchers map[watchedKey]map[*Conn]struct{}
+
+	// loaded holds the SHA1 digests of the scripts known to EVALSHA
+	loaded map[string]struct{}
+
+	// changed is closed and replaced by notify while blocked connections wait for it
+	changed chan struct{}
+	blocked int
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// New returns an empty DB.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func New() *DB {
+	db := &DB{
+		watchers: make(map[watchedKey]map[*Conn]struct{}),
+		loaded:   make(map[string]struct{}),
+		changed:  make(chan struct{}),
+	}
+
+	for i := range db.spaces {
+		db.spaces[i] = newKeyspace()
+	}
+
+	return db
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Now returns the current time of the DB, which is the wall clock moved by FastForward.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (db *DB) Now() time.Time {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.now()
+}
+
+func (db *DB) now() time.Time {
+	return time.Now().Add(db.offset)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// FastForward moves the clock of the DB by d, expiring keys whose time to live has passed.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (db *DB) FastForward(d time.Duration) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.offset += d
+	db.notify()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// notify wakes up the connections blocked by a command, so that they retry it.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (db *DB) notify() {
+	if db.blocked > 0 {
+		close(db.changed)
+		db.changed = make(chan struct{})
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// FlushAll removes all keys of all databases.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (db *DB) FlushAll() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for i := range db.spaces {
+		db.spaces[i] = newKeyspace()
+	}
+
+	db.touchAll(-1)
+	db.notify()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Conn is a client session. It keeps the selected database, the client name and the state of
+// a transaction. A Conn must not be used concurrently, except for Close which ends a blocking
+// command, and should be closed to release the watched keys.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Conn struct {
+	db    *DB
+	index int
+	name  string
+
+	// set by MULTI, queued holds the commands to run on EXEC
+	multi   bool
+	aborted bool
+	queued  [][][]byte
+
+	// set by WATCH, dirty once a watched key has been modified
+	watched []watchedKey
+	dirty   bool
+
+	// done is closed by Close
+	done   chan struct{}
+	closed bool
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Conn returns a new session using database 0.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (db *DB) Conn() *Conn {
+	return &Conn{db: db, done: make(chan struct{})}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Do executes a command, args[0] is the command name. Within MULTI the command is queued
+// until EXEC.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *Conn) Do(args [][]byte) interface{} {
+	if len(args) == 0 {
+		return Error("ERR empty command")
+	}
+
+	name := strings.ToUpper(string(args[0]))
+	cmd, found := commands[name]
+	if !found {
+		c.aborted = c.multi
+		return Error("ERR unknown command '" + string(args[0]) + "'")
+	}
+
+	if (cmd.arity > 0 && len(args) != cmd.arity) || (cmd.arity < 0 && len(args) < -cmd.arity) {
+		c.aborted = c.multi
+		return Error("ERR wrong number of arguments for '" + strings.ToLower(name) + "' command")
+	}
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if c.closed {
+		return errClosed
+	}
+
+	if c.multi && !txCommands[name] {
+		c.queued = append(c.queued, args)
+		return Status("QUEUED")
+	}
+
+	if cmd.blocking {
+		return c.block(cmd, args)
+	}
+
+	return c.exec(cmd, args)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Blocking reports whether args is a command that may block, like BLPOP.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func Blocking(args [][]byte) bool {
+	return len(args) > 0 && commands[strings.ToUpper(string(args[0]))].blocking
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// block runs a blocking command, whose last argument is the timeout in seconds, until it
+// replies, the timeout expires or the connection is closed. The DB is unlocked while the
+// command waits for a modification. Within MULTI the command does not block.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *Conn) block(cmd command, args [][]byte) interface{} {
+	timeout, err := strconv.ParseFloat(string(args[len(args)-1]), 64)
+	if err != nil || math.IsNaN(timeout) || math.IsInf(timeout, 0) {
+		return errTimeout
+	}
+
+	if timeout < 0 {
+		return Error("ERR timeout is negative")
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = c.db.now().Add(time.Duration(timeout * float64(time.Second)))
+	}
+
+	for {
+		if reply := c.exec(cmd, args); reply != nil {
+			return reply
+		}
+
+		var (
+			timer   *time.Timer
+			expired <-chan time.Time
+		)
+
+		if !deadline.IsZero() {
+			left := deadline.Sub(c.db.now())
+			if left <= 0 {
+				return nil
+			}
+
+			timer = time.NewTimer(left)
+			expired = timer.C
+		}
+
+		changed := c.db.changed
+		c.db.blocked++
+		c.db.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-expired:
+		case <-c.done:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		c.db.mu.Lock()
+		c.db.blocked--
+
+		if c.closed {
+			return errClosed
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// exec runs a command with the DB locked and signals the keys it modifies to the watching
+// connections.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *Conn) exec(cmd command, args [][]byte) interface{} {
+	s := &state{conn: c, ks: c.db.spaces[c.index], now: c.db.now()}
+	reply := cmd.fn(s, args[1:])
+
+	// A blocking command without a reply has not modified anything.
+	if _, failed := reply.(Error); cmd.keys != nil && !failed && !(cmd.blocking && reply == nil) {
+		for _, key := range cmd.keys(args[1:]) {
+			c.db.touch(c.index, string(key))
+		}
+
+		c.db.notify()
+	}
+
+	return reply
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Close discards a pending transaction, releases the watched keys and ends a blocking command
+// waiting on another goroutine. Commands of a closed Conn fail.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *Conn) Close() {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	c.discard()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// state is passed to the command functions. The DB is locked while a command runs.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type state struct {
+	conn *Conn
+	ks   *keyspace
+	now  time.Time
+}
+
+type command struct {
+	// arity is the exact number of arguments including the command name, or the negated
+	// minimum number of arguments.
+	arity int
+	fn    func(s *state, args [][]byte) interface{}
+
+	// keys returns the keys modified by a write command, nil for read commands.
+	keys func(args [][]byte) [][]byte
+
+	// blocking commands reply nil until they are able to run, see Conn.block.
+	blocking bool
+}
+
+var commands = map[string]command{}
+
+func register(name string, arity int, fn func(s *state, args [][]byte) interface{}) {
+	commands[name] = command{arity: arity, fn: fn}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// registerWrite registers a command modifying the keys returned by keys. Transactions watching
+// one of them are aborted.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func registerWrite(name string, arity int, keys func(args [][]byte) [][]byte, fn func(s *state, args [][]byte) interface{}) {
+	commands[name] = command{arity: arity, fn: fn, keys: keys}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// registerBlocking registers a blocking write command whose last argument is a timeout. fn
+// replies nil as long as the command has to wait.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func registerBlocking(name string, arity int, keys func(args [][]byte) [][]byte, fn func(s *state, args [][]byte) interface{}) {
+	commands[name] = command{arity: arity, fn: fn, keys: keys, blocking: true}
+}
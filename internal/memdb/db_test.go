@@ -0,0 +1,307 @@
+package memdb
+
+import (
+	"github.com/denkhaus/tcgl/asserts"
+	"testing"
+	"time"
+)
+
+func do(c *Conn, args ...string) interface{} {
+	cmd := make([][]byte, len(args))
+	for i, arg := range args {
+		cmd[i] = []byte(arg)
+	}
+
+	return c.Do(cmd)
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestMatch
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestMatch(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	assert.True(match("*", "anything"), "* should match anything")
+	assert.True(match("user:*", "user:1"), "prefix should match")
+	assert.False(match("user:*", "item:1"), "prefix should not match")
+	assert.True(match("h?llo", "hallo"), "? should match a single character")
+	assert.True(match("h[ae]llo", "hello"), "class should match")
+	assert.False(match("h[^e]llo", "hello"), "negated class should not match")
+	assert.True(match("h[a-c]llo", "hbllo"), "range should match")
+	assert.True(match(`a\*b`, "a*b"), "escaped * should match literally")
+	assert.False(match(`a\*b`, "axb"), "escaped * should not match other characters")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestExpiry
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestExpiry(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	db := New()
+	c := db.Conn()
+
+	assert.Equal(do(c, "SET", "key", "value", "EX", "10"), ok, "SET should return OK")
+	assert.Equal(do(c, "TTL", "key"), int64(10), "TTL should return the remaining seconds")
+	assert.Equal(do(c, "INCR", "counter"), int64(1), "INCR should start at 0")
+	assert.Equal(do(c, "TTL", "counter"), int64(-1), "TTL of a key without expiry should be -1")
+
+	db.FastForward(10 * time.Second)
+	assert.Nil(do(c, "GET", "key"), "an expired key should be gone")
+	assert.Equal(do(c, "TTL", "key"), int64(-2), "TTL of a missing key should be -2")
+	assert.Equal(do(c, "DBSIZE"), int64(1), "only the counter should be left")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestScan
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestScan(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	c := New().Conn()
+
+	for i := 0; i < 100; i++ {
+		do(c, "SET", "key:"+string(rune('A'+i%26))+string(rune('a'+i/26)), "v")
+	}
+
+	do(c, "LPUSH", "list", "v")
+
+	seen := map[string]int{}
+	cursor := "0"
+	for {
+		reply := do(c, "SCAN", cursor, "MATCH", "key:*", "COUNT", "7").([]interface{})
+		for _, key := range reply[1].([]interface{}) {
+			seen[string(key.([]byte))]++
+		}
+
+		cursor = string(reply[0].([]byte))
+		if cursor == "0" {
+			break
+		}
+	}
+
+	assert.Length(seen, 100, "SCAN should return every matching key")
+	for key, n := range seen {
+		assert.Equal(n, 1, "SCAN should return "+key+" once")
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestWrongType
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestWrongType(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	c := New().Conn()
+
+	do(c, "LPUSH", "list", "v")
+	for _, cmd := range [][]string{
+		{"GET", "list"},
+		{"HSET", "list", "f", "v"},
+		{"SADD", "list", "m"},
+		{"ZADD", "list", "1", "m"},
+	} {
+		assert.Equal(do(c, cmd...), errWrongType, cmd[0]+" should return WRONGTYPE")
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestSortedSet
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestSortedSet(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	c := New().Conn()
+
+	assert.Equal(do(c, "ZADD", "z", "2", "b", "1", "a", "3", "c", "2", "bb"), int64(4), "ZADD should add 4 members")
+	assert.Equal(do(c, "ZADD", "z", "CH", "5", "a"), int64(1), "ZADD CH should count changed members")
+
+	members := func(reply interface{}) []string {
+		return strs(bytesOf(reply.([]interface{})))
+	}
+
+	assert.Equal(members(do(c, "ZRANGE", "z", "0", "-1")), []string{"b", "bb", "c", "a"}, "ZRANGE should order by score and member")
+	assert.Equal(members(do(c, "ZREVRANGEBYSCORE", "z", "+inf", "(2")), []string{"a", "c"}, "ZREVRANGEBYSCORE should respect exclusive bounds")
+	assert.Equal(members(do(c, "ZRANGE", "z", "2", "5", "BYSCORE", "LIMIT", "1", "2")), []string{"bb", "c"}, "ZRANGE BYSCORE should apply LIMIT")
+	assert.Equal(do(c, "ZCOUNT", "z", "-inf", "+inf"), int64(4), "ZCOUNT should count all members")
+	assert.Equal(do(c, "ZREMRANGEBYSCORE", "z", "2", "2"), int64(2), "ZREMRANGEBYSCORE should remove 2 members")
+	assert.Equal(do(c, "ZCARD", "z"), int64(2), "ZCARD should return the size")
+}
+
+func bytesOf(replies []interface{}) [][]byte {
+	res := make([][]byte, len(replies))
+	for i, r := range replies {
+		res[i] = r.([]byte)
+	}
+
+	return res
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestTransaction
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestTransaction(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	db := New()
+	c, other := db.Conn(), db.Conn()
+
+	assert.Equal(do(c, "MULTI"), ok, "MULTI should start a transaction")
+	assert.Equal(do(c, "SET", "key", "value"), Status("QUEUED"), "commands should be queued")
+	assert.Equal(do(c, "INCR", "key"), Status("QUEUED"), "commands should be queued")
+	assert.Nil(do(other, "GET", "key"), "queued commands should not run before EXEC")
+
+	replies := do(c, "EXEC").([]interface{})
+	assert.Equal(replies[0], ok, "EXEC should return the replies")
+	assert.Equal(replies[1], errNotInteger, "a failing command should not abort EXEC")
+	assert.Equal(do(c, "EXEC"), Error("ERR EXEC without MULTI"), "EXEC should end the transaction")
+
+	do(c, "MULTI")
+	do(c, "SET", "key", "other")
+	assert.Equal(do(c, "SET"), Error("ERR wrong number of arguments for 'set' command"), "invalid commands should be rejected")
+	assert.Equal(do(c, "EXEC"), Error("EXECABORT Transaction discarded because of previous errors."), "an invalid command should abort EXEC")
+	assert.Equal(do(c, "GET", "key"), []byte("value"), "an aborted transaction should not run")
+
+	do(c, "MULTI")
+	do(c, "DEL", "key")
+	assert.Equal(do(c, "DISCARD"), ok, "DISCARD should end the transaction")
+	assert.Equal(do(c, "GET", "key"), []byte("value"), "a discarded transaction should not run")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestWatch
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestWatch(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	db := New()
+	c, other := db.Conn(), db.Conn()
+
+	exec := func() interface{} {
+		do(c, "MULTI")
+		do(c, "SET", "result", "done")
+		return do(c, "EXEC")
+	}
+
+	do(c, "WATCH", "hash", "missing")
+	do(other, "HSET", "hash", "field", "value")
+	assert.Nil(exec(), "a modified key should abort EXEC")
+	assert.Nil(do(c, "GET", "result"), "an aborted transaction should not run")
+
+	do(c, "WATCH", "hash")
+	do(other, "HGET", "hash", "field")
+	assert.NotNil(exec(), "reading a key should not abort EXEC")
+
+	do(c, "WATCH", "hash")
+	do(other, "SELECT", "1")
+	do(other, "DEL", "hash")
+	assert.NotNil(exec(), "a key of another database should not abort EXEC")
+
+	do(c, "WATCH", "hash")
+	do(other, "FLUSHALL")
+	assert.Nil(exec(), "FLUSHALL should abort EXEC")
+
+	do(c, "SET", "volatile", "value", "PX", "100")
+	do(c, "WATCH", "volatile")
+	db.FastForward(time.Second)
+	assert.Nil(exec(), "an expired key should abort EXEC")
+
+	do(c, "WATCH", "hash")
+	do(c, "UNWATCH")
+	do(other, "SELECT", "0")
+	do(other, "DEL", "result")
+	do(other, "HSET", "hash", "field", "value")
+	assert.NotNil(exec(), "UNWATCH should release the keys")
+
+	do(c, "WATCH", "hash")
+	c.Close()
+	assert.Length(db.watchers, 0, "Close should release the keys")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestBlocking
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestBlocking(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	db := New()
+	c, other := db.Conn(), db.Conn()
+
+	blocked := func(args ...string) <-chan interface{} {
+		reply := make(chan interface{}, 1)
+		go func() {
+			reply <- do(c, args...)
+		}()
+
+		// Wait until the command blocks.
+		for {
+			db.mu.Lock()
+			n := db.blocked
+			db.mu.Unlock()
+
+			if n > 0 {
+				return reply
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	do(other, "RPUSH", "b", "1")
+	assert.Equal(do(c, "BLPOP", "a", "b", "0"), []interface{}{[]byte("b"), []byte("1")}, "BLPOP should pop right away")
+
+	reply := blocked("BLPOP", "a", "b", "0")
+	do(other, "SET", "c", "value")
+	do(other, "RPUSH", "b", "2", "3")
+	assert.Equal(<-reply, []interface{}{[]byte("b"), []byte("2")}, "BLPOP should pop a pushed element")
+
+	reply = blocked("BLMOVE", "a", "b", "LEFT", "RIGHT", "0")
+	do(other, "LPUSH", "a", "4")
+	assert.Equal(<-reply, []byte("4"), "BLMOVE should move a pushed element")
+	assert.Equal(do(other, "LRANGE", "b", "0", "-1"), []interface{}{[]byte("3"), []byte("4")}, "BLMOVE should push to the destination")
+
+	reply = blocked("BRPOP", "a", "1.5")
+	db.FastForward(time.Second)
+	db.FastForward(time.Second)
+	assert.Nil(<-reply, "BRPOP should time out")
+
+	assert.Equal(do(c, "BLPOP", "a", "-1"), Error("ERR timeout is negative"), "a negative timeout should be rejected")
+	assert.Equal(do(c, "BLPOP", "a", "x"), errTimeout, "an invalid timeout should be rejected")
+	assert.Equal(do(c, "BLPOP", "c", "0"), errWrongType, "BLPOP should not block on a key of another type")
+
+	do(c, "MULTI")
+	do(c, "BLPOP", "a", "0")
+	assert.Equal(do(c, "EXEC"), []interface{}{nil}, "BLPOP should not block within MULTI")
+
+	reply = blocked("BLPOP", "a", "0")
+	c.Close()
+	assert.Equal(<-reply, errClosed, "Close should end a blocking command")
+	assert.Equal(do(c, "GET", "c"), errClosed, "a closed connection should fail")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestScripts
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestScripts(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	db := New()
+	c, other := db.Conn(), db.Conn()
+
+	src := "return redis.call('INCRBY', KEYS[1], ARGV[1])"
+	RegisterScript(src, func(call func(args ...interface{}) interface{}, keys, argv [][]byte) interface{} {
+		if len(argv) == 0 {
+			return call("BLPOP", keys[0], 0)
+		}
+
+		return call("INCRBY", keys[0], argv[0])
+	})
+
+	sha := scriptSHA(src)
+	assert.Equal(do(c, "EVALSHA", sha, "1", "n", "2"), Error("NOSCRIPT No matching script. Please use EVAL."), "EVALSHA should need a loaded script")
+	assert.Equal(do(c, "SCRIPT", "EXISTS", sha), []interface{}{int64(0)}, "the script should not be loaded")
+
+	do(other, "WATCH", "n")
+	assert.Equal(do(c, "EVAL", src, "1", "n", "2"), int64(2), "EVAL should run the script")
+	assert.True(other.dirty, "a script should signal its writes")
+	assert.Equal(do(c, "EVALSHA", sha, "1", "n", "3"), int64(5), "EVAL should load the script")
+	assert.Equal(do(c, "EVALSHA", sha, "1", "n"), Error("ERR This Redis command is not allowed from script"), "scripts should not block")
+
+	assert.Equal(do(c, "SCRIPT", "FLUSH"), ok, "SCRIPT FLUSH should succeed")
+	assert.Equal(do(c, "SCRIPT", "EXISTS", sha), []interface{}{int64(0)}, "SCRIPT FLUSH should drop the script")
+	assert.Equal(do(c, "SCRIPT", "LOAD", src), []byte(sha), "SCRIPT LOAD should return the digest")
+	assert.Equal(do(c, "EVALSHA", sha, "2", "n"), Error("ERR Number of keys can't be greater than number of args"), "numkeys should be checked")
+	assert.Equal(do(c, "EVAL", "return 1", "0"), Error("ERR unknown script, only registered scripts can be run"), "unknown scripts should fail")
+}
@@ -0,0 +1,251 @@
+package memdb
+
+import (
+	"math"
+	"strconv"
+)
+
+func init() {
+	registerWrite("HSET", -4, firstKey, cmdHSet)
+	registerWrite("HMSET", -4, firstKey, cmdHMSet)
+	registerWrite("HSETNX", 4, firstKey, cmdHSetNX)
+	register("HGET", 3, cmdHGet)
+	register("HMGET", -3, cmdHMGet)
+	registerWrite("HDEL", -3, firstKey, cmdHDel)
+	register("HLEN", 2, cmdHLen)
+	register("HEXISTS", 3, cmdHExists)
+	register("HKEYS", 2, cmdHKeys)
+	register("HVALS", 2, cmdHVals)
+	register("HGETALL", 2, cmdHGetAll)
+	registerWrite("HINCRBY", 4, firstKey, cmdHIncrBy)
+	registerWrite("HINCRBYFLOAT", 4, firstKey, cmdHIncrByFloat)
+}
+
+func cmdHSet(s *state, args [][]byte) interface{} {
+	if len(args)%2 != 1 {
+		return Error("ERR wrong number of arguments for 'hset' command")
+	}
+
+	h, err := s.hash(string(args[0]), true)
+	if err != nil {
+		return err
+	}
+
+	n := int64(0)
+	for i := 1; i < len(args); i += 2 {
+		if h.put(string(args[i]), args[i+1]) {
+			n++
+		}
+	}
+
+	return n
+}
+
+func cmdHMSet(s *state, args [][]byte) interface{} {
+	if len(args)%2 != 1 {
+		return Error("ERR wrong number of arguments for 'hmset' command")
+	}
+
+	if reply := cmdHSet(s, args); reply == errWrongType {
+		return reply
+	}
+
+	return ok
+}
+
+func cmdHSetNX(s *state, args [][]byte) interface{} {
+	h, err := s.hash(string(args[0]), true)
+	if err != nil {
+		return err
+	}
+
+	if _, found := h.get(string(args[1])); found {
+		return int64(0)
+	}
+
+	h.put(string(args[1]), args[2])
+	return int64(1)
+}
+
+func cmdHGet(s *state, args [][]byte) interface{} {
+	h, err := s.hash(string(args[0]), false)
+	if err != nil {
+		return err
+	}
+
+	if h == nil {
+		return nil
+	}
+
+	if v, found := h.get(string(args[1])); found {
+		return v
+	}
+
+	return nil
+}
+
+func cmdHMGet(s *state, args [][]byte) interface{} {
+	h, err := s.hash(string(args[0]), false)
+	if err != nil {
+		return err
+	}
+
+	res := make([]interface{}, len(args)-1)
+	if h != nil {
+		for i, field := range args[1:] {
+			if v, found := h.get(string(field)); found {
+				res[i] = v
+			}
+		}
+	}
+
+	return res
+}
+
+func cmdHDel(s *state, args [][]byte) interface{} {
+	key := string(args[0])
+	h, err := s.hash(key, false)
+	if err != nil || h == nil {
+		return orZero(err)
+	}
+
+	n := int64(0)
+	for _, field := range args[1:] {
+		if h.del(string(field)) {
+			n++
+		}
+	}
+
+	s.removeIfEmpty(key)
+	return n
+}
+
+func cmdHLen(s *state, args [][]byte) interface{} {
+	h, err := s.hash(string(args[0]), false)
+	if err != nil || h == nil {
+		return orZero(err)
+	}
+
+	return int64(h.len())
+}
+
+func cmdHExists(s *state, args [][]byte) interface{} {
+	h, err := s.hash(string(args[0]), false)
+	if err != nil || h == nil {
+		return orZero(err)
+	}
+
+	if _, found := h.get(string(args[1])); found {
+		return int64(1)
+	}
+
+	return int64(0)
+}
+
+func cmdHKeys(s *state, args [][]byte) interface{} {
+	return hashReply(s, args[0], true, false)
+}
+
+func cmdHVals(s *state, args [][]byte) interface{} {
+	return hashReply(s, args[0], false, true)
+}
+
+func cmdHGetAll(s *state, args [][]byte) interface{} {
+	return hashReply(s, args[0], true, true)
+}
+
+func hashReply(s *state, key []byte, fields, values bool) interface{} {
+	h, err := s.hash(string(key), false)
+	if err != nil {
+		return err
+	}
+
+	res := []interface{}{}
+	if h == nil {
+		return res
+	}
+
+	for _, field := range h.keys {
+		if fields {
+			res = append(res, []byte(field))
+		}
+
+		if values {
+			res = append(res, h.values[field])
+		}
+	}
+
+	return res
+}
+
+func cmdHIncrBy(s *state, args [][]byte) interface{} {
+	delta, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+
+	h, err := s.hash(string(args[0]), true)
+	if err != nil {
+		return err
+	}
+
+	field := string(args[1])
+	n := int64(0)
+	if v, found := h.get(field); found {
+		if n, err = strconv.ParseInt(string(v), 10, 64); err != nil {
+			s.removeIfEmpty(string(args[0]))
+			return Error("ERR hash value is not an integer")
+		}
+	}
+
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		s.removeIfEmpty(string(args[0]))
+		return errOverflow
+	}
+
+	n += delta
+	h.put(field, []byte(strconv.FormatInt(n, 10)))
+	return n
+}
+
+func cmdHIncrByFloat(s *state, args [][]byte) interface{} {
+	delta, err := parseFloat(args[2])
+	if err != nil {
+		return err
+	}
+
+	h, err := s.hash(string(args[0]), true)
+	if err != nil {
+		return err
+	}
+
+	field := string(args[1])
+	f := 0.0
+	if v, found := h.get(field); found {
+		if f, err = parseFloat(v); err != nil {
+			s.removeIfEmpty(string(args[0]))
+			return Error("ERR hash value is not a float")
+		}
+	}
+
+	f += delta
+	if math.IsInf(f, 0) {
+		s.removeIfEmpty(string(args[0]))
+		return Error("ERR increment would produce NaN or Infinity")
+	}
+
+	res := formatHumanFloat(f)
+	h.put(field, res)
+	return res
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// orZero returns err as reply, or 0 for a missing key.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func orZero(err error) interface{} {
+	if err != nil {
+		return err
+	}
+
+	return int64(0)
+}
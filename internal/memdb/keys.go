@@ -0,0 +1,320 @@
+package memdb
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"time"
+)
+
+func init() {
+	registerWrite("DEL", -2, allKeys, cmdDel)
+	registerWrite("UNLINK", -2, allKeys, cmdDel)
+	register("EXISTS", -2, cmdExists)
+	register("TYPE", 2, cmdType)
+	registerWrite("EXPIRE", -3, firstKey, cmdExpire(time.Second, false))
+	registerWrite("PEXPIRE", -3, firstKey, cmdExpire(time.Millisecond, false))
+	registerWrite("EXPIREAT", -3, firstKey, cmdExpire(time.Second, true))
+	registerWrite("PEXPIREAT", -3, firstKey, cmdExpire(time.Millisecond, true))
+	register("TTL", 2, cmdTTL(time.Second))
+	register("PTTL", 2, cmdTTL(time.Millisecond))
+	registerWrite("PERSIST", 2, firstKey, cmdPersist)
+	register("SCAN", -2, cmdScan)
+	register("KEYS", 2, cmdKeys)
+	register("DBSIZE", 1, cmdDBSize)
+	register("FLUSHDB", -1, cmdFlushDB)
+	register("FLUSHALL", -1, cmdFlushAll)
+	register("PING", -1, cmdPing)
+	register("ECHO", 2, cmdEcho)
+	register("SELECT", 2, cmdSelect)
+	register("CLIENT", -2, cmdClient)
+}
+
+func cmdDel(s *state, args [][]byte) interface{} {
+	n := int64(0)
+	for _, key := range args {
+		if s.del(string(key)) {
+			n++
+		}
+	}
+
+	return n
+}
+
+func cmdExists(s *state, args [][]byte) interface{} {
+	n := int64(0)
+	for _, key := range args {
+		if s.lookup(string(key)) != nil {
+			n++
+		}
+	}
+
+	return n
+}
+
+func cmdType(s *state, args [][]byte) interface{} {
+	e := s.lookup(string(args[0]))
+	if e == nil {
+		return Status("none")
+	}
+
+	return Status(e.typeName())
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// cmdExpire implements EXPIRE and its variants with the NX, XX, GT and LT options. A time in
+// the past deletes the key.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func cmdExpire(unit time.Duration, absolute bool) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		n, err := parseInt(args[1])
+		if err != nil {
+			return err
+		}
+
+		var nx, xx, gt, lt bool
+		for _, arg := range args[2:] {
+			switch {
+			case isArg(arg, "NX"):
+				nx = true
+			case isArg(arg, "XX"):
+				xx = true
+			case isArg(arg, "GT"):
+				gt = true
+			case isArg(arg, "LT"):
+				lt = true
+			default:
+				return Error("ERR Unsupported option " + string(arg))
+			}
+		}
+
+		if (nx && (xx || gt || lt)) || (gt && lt) {
+			return Error("ERR NX and XX, GT or LT options at the same time are not compatible")
+		}
+
+		key := string(args[0])
+		e := s.lookup(key)
+		if e == nil {
+			return int64(0)
+		}
+
+		at := s.now.Add(time.Duration(n) * unit)
+		if absolute {
+			at = time.Unix(0, 0).Add(time.Duration(n) * unit)
+		}
+
+		persistent := e.expireAt.IsZero()
+		switch {
+		case nx && !persistent, xx && persistent:
+			return int64(0)
+		case gt && (persistent || !at.After(e.expireAt)):
+			return int64(0)
+		case lt && !persistent && !at.Before(e.expireAt):
+			return int64(0)
+		}
+
+		if !at.After(s.now) {
+			s.del(key)
+			return int64(1)
+		}
+
+		e.expireAt = at
+		return int64(1)
+	}
+}
+
+func cmdTTL(unit time.Duration) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		e := s.lookup(string(args[0]))
+		switch {
+		case e == nil:
+			return int64(-2)
+		case e.expireAt.IsZero():
+			return int64(-1)
+		}
+
+		left := e.expireAt.Sub(s.now)
+		return int64((left + unit/2) / unit)
+	}
+}
+
+func cmdPersist(s *state, args [][]byte) interface{} {
+	e := s.lookup(string(args[0]))
+	if e == nil || e.expireAt.IsZero() {
+		return int64(0)
+	}
+
+	e.expireAt = time.Time{}
+	return int64(1)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// cmdScan implements SCAN with the MATCH, COUNT and TYPE options. Keys are visited in the
+// order of their hash and the cursor is the next hash to visit, so every key that exists
+// during the whole iteration is returned, independent of other keys being added or removed.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func cmdScan(s *state, args [][]byte) interface{} {
+	cursor, err := strconv.ParseUint(string(args[0]), 10, 64)
+	if err != nil {
+		return Error("ERR invalid cursor")
+	}
+
+	pattern, count, typ := "*", 10, ""
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return errSyntax
+		}
+
+		switch {
+		case isArg(args[i], "MATCH"):
+			pattern = string(args[i+1])
+		case isArg(args[i], "COUNT"):
+			n, err := parseInt(args[i+1])
+			if err != nil {
+				return err
+			}
+
+			if n < 1 {
+				return errSyntax
+			}
+
+			count = int(n)
+		case isArg(args[i], "TYPE"):
+			typ = string(args[i+1])
+		default:
+			return errSyntax
+		}
+	}
+
+	next, keys := scan(s.keys(), cursor, count)
+	res := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		if !match(pattern, key) {
+			continue
+		}
+
+		if typ != "" && !isArg([]byte(s.lookup(key).typeName()), typ) {
+			continue
+		}
+
+		res = append(res, []byte(key))
+	}
+
+	return []interface{}{[]byte(strconv.FormatUint(next, 10)), res}
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return uint64(h.Sum32())
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// scan returns at least count keys, unless exhausted, whose hash is not below cursor together
+// with the next cursor, 0 at the end of the iteration.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func scan(keys []string, cursor uint64, count int) (uint64, []string) {
+	sort.Slice(keys, func(i, j int) bool {
+		hi, hj := hashKey(keys[i]), hashKey(keys[j])
+		return hi < hj || (hi == hj && keys[i] < keys[j])
+	})
+
+	start := sort.Search(len(keys), func(i int) bool {
+		return hashKey(keys[i]) >= cursor
+	})
+
+	end := start + count
+	if end >= len(keys) {
+		return 0, keys[start:]
+	}
+
+	// never split keys with the same hash between two calls
+	last := hashKey(keys[end-1])
+	for end < len(keys) && hashKey(keys[end]) == last {
+		end++
+	}
+
+	if end == len(keys) {
+		return 0, keys[start:]
+	}
+
+	return last + 1, keys[start:end]
+}
+
+func cmdKeys(s *state, args [][]byte) interface{} {
+	keys := s.keys()
+	sort.Strings(keys)
+
+	res := []interface{}{}
+	for _, key := range keys {
+		if match(string(args[0]), key) {
+			res = append(res, []byte(key))
+		}
+	}
+
+	return res
+}
+
+func cmdDBSize(s *state, args [][]byte) interface{} {
+	return int64(len(s.keys()))
+}
+
+func cmdFlushDB(s *state, args [][]byte) interface{} {
+	*s.ks = *newKeyspace()
+	s.conn.db.touchAll(s.conn.index)
+	return ok
+}
+
+func cmdFlushAll(s *state, args [][]byte) interface{} {
+	for _, ks := range s.conn.db.spaces {
+		*ks = *newKeyspace()
+	}
+
+	s.conn.db.touchAll(-1)
+	return ok
+}
+
+func cmdPing(s *state, args [][]byte) interface{} {
+	switch len(args) {
+	case 0:
+		return Status("PONG")
+	case 1:
+		return args[0]
+	}
+
+	return Error("ERR wrong number of arguments for 'ping' command")
+}
+
+func cmdEcho(s *state, args [][]byte) interface{} {
+	return args[0]
+}
+
+func cmdSelect(s *state, args [][]byte) interface{} {
+	n, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+
+	if n < 0 || n >= Databases {
+		return errDBIndex
+	}
+
+	s.conn.index = int(n)
+	return ok
+}
+
+func cmdClient(s *state, args [][]byte) interface{} {
+	switch {
+	case isArg(args[0], "SETNAME") && len(args) == 2:
+		s.conn.name = string(args[1])
+		return ok
+	case isArg(args[0], "GETNAME") && len(args) == 1:
+		if s.conn.name == "" {
+			return nil
+		}
+
+		return []byte(s.conn.name)
+	}
+
+	return Error("ERR unknown subcommand '" + string(args[0]) + "'")
+}
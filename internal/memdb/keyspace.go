@@ -0,0 +1,288 @@
+package memdb
+
+import (
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// keyspace maps keys to entries of a single database.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type keyspace struct {
+	entries map[string]*entry
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// entry is a value with an optional expiry. value is []byte for strings, *omap for hashes,
+// *list for lists, *omap with nil values for sets and *zset for sorted sets.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type entry struct {
+	value    interface{}
+	expireAt time.Time
+}
+
+func newKeyspace() *keyspace {
+	return &keyspace{entries: make(map[string]*entry)}
+}
+
+func (e *entry) typeName() string {
+	switch v := e.value.(type) {
+	case []byte:
+		return "string"
+	case *omap:
+		if v.set {
+			return "set"
+		}
+
+		return "hash"
+	case *list:
+		return "list"
+	case *zset:
+		return "zset"
+	}
+
+	return "none"
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// lookup returns the entry of key or nil. Expired keys are removed.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *state) lookup(key string) *entry {
+	e := s.ks.entries[key]
+	if e == nil {
+		return nil
+	}
+
+	if e.expired(s.now) {
+		delete(s.ks.entries, key)
+		s.conn.db.touch(s.conn.index, key)
+		return nil
+	}
+
+	return e
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// keys returns all keys that have not expired.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *state) keys() []string {
+	keys := make([]string, 0, len(s.ks.entries))
+	for key := range s.ks.entries {
+		if s.lookup(key) != nil {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func (s *state) del(key string) bool {
+	if s.lookup(key) == nil {
+		return false
+	}
+
+	delete(s.ks.entries, key)
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// put stores value at key and keeps the expiry of an existing key if keepTTL is set.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *state) put(key string, value interface{}, keepTTL bool) *entry {
+	e := &entry{value: value}
+	if old := s.lookup(key); old != nil && keepTTL {
+		e.expireAt = old.expireAt
+	}
+
+	s.ks.entries[key] = e
+	return e
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// removeIfEmpty deletes a collection without elements, like redis does.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *state) removeIfEmpty(key string) {
+	e := s.ks.entries[key]
+	if e == nil {
+		return
+	}
+
+	empty := false
+	switch v := e.value.(type) {
+	case *omap:
+		empty = v.len() == 0
+	case *list:
+		empty = len(v.items) == 0
+	case *zset:
+		empty = len(v.sorted) == 0
+	}
+
+	if empty {
+		delete(s.ks.entries, key)
+	}
+}
+
+func (s *state) str(key string) ([]byte, error) {
+	e := s.lookup(key)
+	if e == nil {
+		return nil, nil
+	}
+
+	b, isStr := e.value.([]byte)
+	if !isStr {
+		return nil, errWrongType
+	}
+
+	return b, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// hash returns the hash at key. A missing hash is created if create is set, nil otherwise.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *state) hash(key string, create bool) (*omap, error) {
+	return s.omap(key, false, create)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// set returns the set at key. A missing set is created if create is set, nil otherwise.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *state) set(key string, create bool) (*omap, error) {
+	return s.omap(key, true, create)
+}
+
+func (s *state) omap(key string, set, create bool) (*omap, error) {
+	e := s.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+
+		m := newOmap(set)
+		s.put(key, m, false)
+		return m, nil
+	}
+
+	m, isMap := e.value.(*omap)
+	if !isMap || m.set != set {
+		return nil, errWrongType
+	}
+
+	return m, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// list returns the list at key. A missing list is created if create is set, nil otherwise.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *state) list(key string, create bool) (*list, error) {
+	e := s.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+
+		l := &list{}
+		s.put(key, l, false)
+		return l, nil
+	}
+
+	l, isList := e.value.(*list)
+	if !isList {
+		return nil, errWrongType
+	}
+
+	return l, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// zset returns the sorted set at key. A missing set is created if create is set, nil otherwise.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *state) zset(key string, create bool) (*zset, error) {
+	e := s.lookup(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+
+		z := newZset()
+		s.put(key, z, false)
+		return z, nil
+	}
+
+	z, isZset := e.value.(*zset)
+	if !isZset {
+		return nil, errWrongType
+	}
+
+	return z, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// omap is a map keeping the insertion order like the small encodings of redis do. Sets use an
+// omap without values.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type omap struct {
+	set    bool
+	keys   []string
+	values map[string][]byte
+}
+
+func newOmap(set bool) *omap {
+	return &omap{set: set, values: make(map[string][]byte)}
+}
+
+func (m *omap) len() int {
+	return len(m.keys)
+}
+
+func (m *omap) get(key string) ([]byte, bool) {
+	v, found := m.values[key]
+	return v, found
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// put sets key to value and reports whether key is new.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (m *omap) put(key string, value []byte) bool {
+	_, found := m.values[key]
+	if !found {
+		m.keys = append(m.keys, key)
+	}
+
+	m.values[key] = value
+	return !found
+}
+
+func (m *omap) del(key string) bool {
+	if _, found := m.values[key]; !found {
+		return false
+	}
+
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+
+	return true
+}
+
+func (m *omap) copy() *omap {
+	c := newOmap(m.set)
+	for _, key := range m.keys {
+		c.put(key, m.values[key])
+	}
+
+	return c
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// list is a list of elements.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type list struct {
+	items [][]byte
+}
@@ -0,0 +1,402 @@
+package memdb
+
+func init() {
+	registerWrite("LPUSH", -3, firstKey, cmdPush(true, false))
+	registerWrite("RPUSH", -3, firstKey, cmdPush(false, false))
+	registerWrite("LPUSHX", -3, firstKey, cmdPush(true, true))
+	registerWrite("RPUSHX", -3, firstKey, cmdPush(false, true))
+	registerWrite("LPOP", -2, firstKey, cmdPop(true))
+	registerWrite("RPOP", -2, firstKey, cmdPop(false))
+	register("LLEN", 2, cmdLLen)
+	register("LRANGE", 4, cmdLRange)
+	register("LINDEX", 3, cmdLIndex)
+	registerWrite("LSET", 4, firstKey, cmdLSet)
+	registerWrite("LREM", 4, firstKey, cmdLRem)
+	registerWrite("LTRIM", 4, firstKey, cmdLTrim)
+	registerWrite("LINSERT", 5, firstKey, cmdLInsert)
+	registerWrite("LMOVE", 5, twoKeys, cmdLMove)
+	registerWrite("RPOPLPUSH", 3, twoKeys, cmdRPopLPush)
+	registerBlocking("BLPOP", -3, blockKeys, cmdBPop(true))
+	registerBlocking("BRPOP", -3, blockKeys, cmdBPop(false))
+	registerBlocking("BLMOVE", 6, twoKeys, cmdLMove)
+	registerBlocking("BRPOPLPUSH", 4, twoKeys, cmdRPopLPush)
+}
+
+// blockKeys returns the keys of BLPOP and BRPOP, all arguments but the timeout.
+func blockKeys(args [][]byte) [][]byte {
+	return args[:len(args)-1]
+}
+
+func cmdPush(left, existing bool) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		l, err := s.list(string(args[0]), !existing)
+		if err != nil || l == nil {
+			return orZero(err)
+		}
+
+		for _, v := range args[1:] {
+			if left {
+				l.items = append([][]byte{v}, l.items...)
+			} else {
+				l.items = append(l.items, v)
+			}
+		}
+
+		return int64(len(l.items))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// pop removes n elements from the head or tail of the list at key.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *state) pop(key string, left bool, n int) ([][]byte, error) {
+	l, err := s.list(key, false)
+	if err != nil || l == nil {
+		return nil, err
+	}
+
+	if n > len(l.items) {
+		n = len(l.items)
+	}
+
+	res := make([][]byte, n)
+	for i := range res {
+		if left {
+			res[i] = l.items[i]
+		} else {
+			res[i] = l.items[len(l.items)-1-i]
+		}
+	}
+
+	if left {
+		l.items = l.items[n:]
+	} else {
+		l.items = l.items[:len(l.items)-n]
+	}
+
+	s.removeIfEmpty(key)
+	return res, nil
+}
+
+func cmdPop(left bool) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		if len(args) > 2 {
+			return errSyntax
+		}
+
+		n := int64(1)
+		if len(args) == 2 {
+			var err error
+			if n, err = parseInt(args[1]); err != nil || n < 0 {
+				return Error("ERR value is out of range, must be positive")
+			}
+		}
+
+		res, err := s.pop(string(args[0]), left, int(n))
+		switch {
+		case err != nil:
+			return err
+		case len(args) == 2 && res == nil:
+			return nil
+		case len(args) == 2:
+			return bulks(res)
+		case len(res) == 0:
+			return nil
+		}
+
+		return res[0]
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// cmdBPop pops an element of the first non-empty list and replies the key and the element,
+// nil if all lists are empty.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func cmdBPop(left bool) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		for _, key := range blockKeys(args) {
+			res, err := s.pop(string(key), left, 1)
+			if err != nil {
+				return err
+			}
+
+			if len(res) > 0 {
+				return []interface{}{key, res[0]}
+			}
+		}
+
+		return nil
+	}
+}
+
+func cmdLLen(s *state, args [][]byte) interface{} {
+	l, err := s.list(string(args[0]), false)
+	if err != nil || l == nil {
+		return orZero(err)
+	}
+
+	return int64(len(l.items))
+}
+
+func cmdLRange(s *state, args [][]byte) interface{} {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+
+	stop, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+
+	l, err := s.list(string(args[0]), false)
+	if err != nil {
+		return err
+	}
+
+	if l == nil {
+		return []interface{}{}
+	}
+
+	from, to, ok := span(start, stop, len(l.items))
+	if !ok {
+		return []interface{}{}
+	}
+
+	return bulks(l.items[from:to])
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// index resolves a possibly negative index of a list, -1 if it is out of range.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *list) index(i int64) int {
+	if i < 0 {
+		i += int64(len(l.items))
+	}
+
+	if i < 0 || i >= int64(len(l.items)) {
+		return -1
+	}
+
+	return int(i)
+}
+
+func cmdLIndex(s *state, args [][]byte) interface{} {
+	i, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+
+	l, err := s.list(string(args[0]), false)
+	if err != nil || l == nil {
+		return err
+	}
+
+	if idx := l.index(i); idx >= 0 {
+		return l.items[idx]
+	}
+
+	return nil
+}
+
+func cmdLSet(s *state, args [][]byte) interface{} {
+	i, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+
+	l, err := s.list(string(args[0]), false)
+	if err != nil {
+		return err
+	}
+
+	if l == nil {
+		return errNoSuchKey
+	}
+
+	idx := l.index(i)
+	if idx < 0 {
+		return errIndex
+	}
+
+	l.items[idx] = args[2]
+	return ok
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// cmdLRem removes count occurrences of an element, from the tail if count is negative and
+// all of them if count is 0.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func cmdLRem(s *state, args [][]byte) interface{} {
+	count, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+
+	key := string(args[0])
+	l, err := s.list(key, false)
+	if err != nil || l == nil {
+		return orZero(err)
+	}
+
+	value := string(args[2])
+	removed := int64(0)
+	limit := count
+	if limit < 0 {
+		limit = -limit
+	}
+
+	keep := make([]bool, len(l.items))
+	for n := range l.items {
+		i := n
+		if count < 0 {
+			i = len(l.items) - 1 - n
+		}
+
+		keep[i] = true
+		if string(l.items[i]) == value && (limit == 0 || removed < limit) {
+			keep[i] = false
+			removed++
+		}
+	}
+
+	items := l.items[:0]
+	for i, item := range l.items {
+		if keep[i] {
+			items = append(items, item)
+		}
+	}
+
+	l.items = items
+	s.removeIfEmpty(key)
+	return removed
+}
+
+func cmdLTrim(s *state, args [][]byte) interface{} {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+
+	stop, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+
+	key := string(args[0])
+	l, err := s.list(key, false)
+	if err != nil || l == nil {
+		if err != nil {
+			return err
+		}
+
+		return ok
+	}
+
+	from, to, inRange := span(start, stop, len(l.items))
+	if !inRange {
+		from, to = 0, 0
+	}
+
+	l.items = append([][]byte(nil), l.items[from:to]...)
+	s.removeIfEmpty(key)
+	return ok
+}
+
+func cmdLInsert(s *state, args [][]byte) interface{} {
+	var before bool
+	switch {
+	case isArg(args[1], "BEFORE"):
+		before = true
+	case isArg(args[1], "AFTER"):
+	default:
+		return errSyntax
+	}
+
+	l, err := s.list(string(args[0]), false)
+	if err != nil || l == nil {
+		return orZero(err)
+	}
+
+	for i, item := range l.items {
+		if string(item) != string(args[2]) {
+			continue
+		}
+
+		if !before {
+			i++
+		}
+
+		l.items = append(l.items[:i], append([][]byte{args[3]}, l.items[i:]...)...)
+		return int64(len(l.items))
+	}
+
+	return int64(-1)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// move pops an element from src and pushes it to dst. The element is nil if src is empty.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *state) move(src, dst string, fromLeft, toLeft bool) ([]byte, error) {
+	l, err := s.list(src, false)
+	if err != nil || l == nil {
+		return nil, err
+	}
+
+	if _, err := s.list(dst, false); err != nil {
+		return nil, err
+	}
+
+	res, err := s.pop(src, fromLeft, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	d, _ := s.list(dst, true)
+	if toLeft {
+		d.items = append([][]byte{res[0]}, d.items...)
+	} else {
+		d.items = append(d.items, res[0])
+	}
+
+	return res[0], nil
+}
+
+func parseSide(b []byte) (left bool, err error) {
+	switch {
+	case isArg(b, "LEFT"):
+		return true, nil
+	case isArg(b, "RIGHT"):
+		return false, nil
+	}
+
+	return false, errSyntax
+}
+
+func cmdLMove(s *state, args [][]byte) interface{} {
+	fromLeft, err := parseSide(args[2])
+	if err != nil {
+		return err
+	}
+
+	toLeft, err := parseSide(args[3])
+	if err != nil {
+		return err
+	}
+
+	v, err := s.move(string(args[0]), string(args[1]), fromLeft, toLeft)
+	if err != nil || v == nil {
+		return err
+	}
+
+	return v
+}
+
+func cmdRPopLPush(s *state, args [][]byte) interface{} {
+	v, err := s.move(string(args[0]), string(args[1]), false, true)
+	if err != nil || v == nil {
+		return err
+	}
+
+	return v
+}
@@ -0,0 +1,175 @@
+package memdb
+
+import (
+	"strings"
+)
+
+// txCommands are executed right away within MULTI instead of being queued.
+var txCommands = map[string]bool{
+	"MULTI":   true,
+	"EXEC":    true,
+	"DISCARD": true,
+	"WATCH":   true,
+}
+
+func init() {
+	register("MULTI", 1, cmdMulti)
+	register("EXEC", 1, cmdExec)
+	register("DISCARD", 1, cmdDiscard)
+	register("WATCH", -2, cmdWatch)
+	register("UNWATCH", 1, cmdUnwatch)
+}
+
+func firstKey(args [][]byte) [][]byte {
+	return args[:1]
+}
+
+func twoKeys(args [][]byte) [][]byte {
+	return args[:2]
+}
+
+func allKeys(args [][]byte) [][]byte {
+	return args
+}
+
+func pairKeys(args [][]byte) [][]byte {
+	keys := make([][]byte, 0, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		keys = append(keys, args[i])
+	}
+
+	return keys
+}
+
+// watchedKey is a key of a database.
+type watchedKey struct {
+	index int
+	key   string
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// touch marks the connections watching key of database index as dirty.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (db *DB) touch(index int, key string) {
+	for c := range db.watchers[watchedKey{index, key}] {
+		c.dirty = true
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// touchAll marks the connections watching a key of database index as dirty, of all databases
+// if index is negative.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (db *DB) touchAll(index int) {
+	for wk, conns := range db.watchers {
+		if index >= 0 && wk.index != index {
+			continue
+		}
+
+		for c := range conns {
+			c.dirty = true
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// discard ends a transaction and releases the watched keys.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *Conn) discard() {
+	c.multi, c.aborted, c.queued = false, false, nil
+	c.unwatch()
+}
+
+// unwatch releases the watched keys.
+func (c *Conn) unwatch() {
+	for _, wk := range c.watched {
+		conns := c.db.watchers[wk]
+		delete(conns, c)
+		if len(conns) == 0 {
+			delete(c.db.watchers, wk)
+		}
+	}
+
+	c.watched, c.dirty = nil, false
+}
+
+func cmdMulti(s *state, args [][]byte) interface{} {
+	if s.conn.multi {
+		return Error("ERR MULTI calls can not be nested")
+	}
+
+	s.conn.multi = true
+	return ok
+}
+
+func cmdExec(s *state, args [][]byte) interface{} {
+	c := s.conn
+	if !c.multi {
+		return Error("ERR EXEC without MULTI")
+	}
+
+	// A watched key expiring counts as a modification, even if it has not been removed yet.
+	for _, wk := range c.watched {
+		if e := c.db.spaces[wk.index].entries[wk.key]; e != nil && e.expired(s.now) {
+			c.dirty = true
+		}
+	}
+
+	queued, aborted, dirty := c.queued, c.aborted, c.dirty
+	c.discard()
+
+	if aborted {
+		return Error("EXECABORT Transaction discarded because of previous errors.")
+	}
+
+	if dirty {
+		return nil
+	}
+
+	replies := make([]interface{}, len(queued))
+	for i, args := range queued {
+		replies[i] = c.exec(commands[strings.ToUpper(string(args[0]))], args)
+	}
+
+	return replies
+}
+
+func cmdDiscard(s *state, args [][]byte) interface{} {
+	if !s.conn.multi {
+		return Error("ERR DISCARD without MULTI")
+	}
+
+	s.conn.discard()
+	return ok
+}
+
+func cmdWatch(s *state, args [][]byte) interface{} {
+	c := s.conn
+	if c.multi {
+		return Error("ERR WATCH inside MULTI is not allowed")
+	}
+
+	for _, key := range args {
+		// Drop an expired key, so that only keys expiring from now on abort the transaction.
+		s.lookup(string(key))
+
+		wk := watchedKey{c.index, string(key)}
+		conns := c.db.watchers[wk]
+		if conns == nil {
+			conns = make(map[*Conn]struct{})
+			c.db.watchers[wk] = conns
+		}
+
+		if _, watching := conns[c]; !watching {
+			conns[c] = struct{}{}
+			c.watched = append(c.watched, wk)
+		}
+	}
+
+	return ok
+}
+
+func cmdUnwatch(s *state, args [][]byte) interface{} {
+	s.conn.unwatch()
+	return ok
+}
@@ -0,0 +1,153 @@
+package memdb
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// ScriptFunc implements a Lua script in Go, since the DB does not interpret Lua. call runs a
+// command like redis.call and returns its reply, error replies included. keys and argv are
+// KEYS and ARGV of the script. The DB is locked while the script runs.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type ScriptFunc func(call func(args ...interface{}) interface{}, keys, argv [][]byte) interface{}
+
+// scripts maps the SHA1 digests of the registered scripts to their implementations.
+var scripts = map[string]ScriptFunc{}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// RegisterScript registers fn as implementation of the Lua script src, which makes src
+// available to EVAL, EVALSHA and SCRIPT LOAD. Scripts have to be registered during
+// initialization; other scripts fail.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func RegisterScript(src string, fn ScriptFunc) {
+	scripts[scriptSHA(src)] = fn
+}
+
+func scriptSHA(src string) string {
+	sum := sha1.Sum([]byte(src))
+	return hex.EncodeToString(sum[:])
+}
+
+// noScriptCommands can not be called by scripts.
+var noScriptCommands = map[string]bool{
+	"EVAL":    true,
+	"EVALSHA": true,
+	"SCRIPT":  true,
+	"UNWATCH": true,
+}
+
+func init() {
+	register("EVAL", -3, cmdEval)
+	register("EVALSHA", -3, cmdEvalSHA)
+	register("SCRIPT", -2, cmdScript)
+}
+
+func cmdEval(s *state, args [][]byte) interface{} {
+	sha := scriptSHA(string(args[0]))
+	fn, found := scripts[sha]
+	if !found {
+		return Error("ERR unknown script, only registered scripts can be run")
+	}
+
+	s.conn.db.loaded[sha] = struct{}{}
+	return s.runScript(fn, args[1:])
+}
+
+func cmdEvalSHA(s *state, args [][]byte) interface{} {
+	sha := strings.ToLower(string(args[0]))
+	fn, found := scripts[sha]
+	if _, loaded := s.conn.db.loaded[sha]; !found || !loaded {
+		return Error("NOSCRIPT No matching script. Please use EVAL.")
+	}
+
+	return s.runScript(fn, args[1:])
+}
+
+func cmdScript(s *state, args [][]byte) interface{} {
+	db := s.conn.db
+	switch {
+	case isArg(args[0], "LOAD") && len(args) == 2:
+		sha := scriptSHA(string(args[1]))
+		if _, found := scripts[sha]; !found {
+			return Error("ERR unknown script, only registered scripts can be loaded")
+		}
+
+		db.loaded[sha] = struct{}{}
+		return []byte(sha)
+	case isArg(args[0], "EXISTS") && len(args) > 1:
+		res := make([]interface{}, len(args)-1)
+		for i, sha := range args[1:] {
+			res[i] = int64(0)
+			if _, loaded := db.loaded[strings.ToLower(string(sha))]; loaded {
+				res[i] = int64(1)
+			}
+		}
+
+		return res
+	case isArg(args[0], "FLUSH") && len(args) <= 2:
+		db.loaded = make(map[string]struct{})
+		return ok
+	}
+
+	return errSyntax
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// runScript runs fn with args starting at numkeys. The commands called by fn signal their
+// modifications like any other command.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *state) runScript(fn ScriptFunc, args [][]byte) interface{} {
+	n, err := parseInt(args[0])
+	switch {
+	case err != nil:
+		return err
+	case n < 0:
+		return Error("ERR Number of keys can't be negative")
+	case n > int64(len(args)-1):
+		return Error("ERR Number of keys can't be greater than number of args")
+	}
+
+	c := s.conn
+	call := func(callArgs ...interface{}) interface{} {
+		if len(callArgs) == 0 {
+			return Error("ERR Please specify at least one argument for this redis lib call")
+		}
+
+		cmdArgs := make([][]byte, len(callArgs))
+		for i, a := range callArgs {
+			cmdArgs[i] = scriptArg(a)
+		}
+
+		name := strings.ToUpper(string(cmdArgs[0]))
+		cmd, found := commands[name]
+		switch {
+		case !found:
+			return Error("ERR Unknown Redis command called from script")
+		case cmd.blocking || txCommands[name] || noScriptCommands[name]:
+			return Error("ERR This Redis command is not allowed from script")
+		case (cmd.arity > 0 && len(cmdArgs) != cmd.arity) || (cmd.arity < 0 && len(cmdArgs) < -cmd.arity):
+			return Error("ERR wrong number of arguments for '" + strings.ToLower(name) + "' command")
+		}
+
+		return c.exec(cmd, cmdArgs)
+	}
+
+	return fn(call, args[1:1+n], args[1+n:])
+}
+
+// scriptArg converts an argument of a command called by a script, like Lua numbers and strings.
+func scriptArg(a interface{}) []byte {
+	switch a := a.(type) {
+	case []byte:
+		return a
+	case string:
+		return []byte(a)
+	case float64:
+		return formatFloat(a)
+	}
+
+	return []byte(fmt.Sprint(a))
+}
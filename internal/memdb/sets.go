@@ -0,0 +1,329 @@
+package memdb
+
+import (
+	"math/rand"
+)
+
+func init() {
+	registerWrite("SADD", -3, firstKey, cmdSAdd)
+	registerWrite("SREM", -3, firstKey, cmdSRem)
+	register("SMEMBERS", 2, cmdSMembers)
+	register("SISMEMBER", 3, cmdSIsMember)
+	register("SMISMEMBER", -3, cmdSMIsMember)
+	register("SCARD", 2, cmdSCard)
+	registerWrite("SPOP", -2, firstKey, cmdSPop)
+	register("SRANDMEMBER", -2, cmdSRandMember)
+	registerWrite("SMOVE", 4, twoKeys, cmdSMove)
+	register("SINTER", -2, cmdSetOp(setInter, false))
+	register("SUNION", -2, cmdSetOp(setUnion, false))
+	register("SDIFF", -2, cmdSetOp(setDiff, false))
+	registerWrite("SINTERSTORE", -3, firstKey, cmdSetOp(setInter, true))
+	registerWrite("SUNIONSTORE", -3, firstKey, cmdSetOp(setUnion, true))
+	registerWrite("SDIFFSTORE", -3, firstKey, cmdSetOp(setDiff, true))
+}
+
+func cmdSAdd(s *state, args [][]byte) interface{} {
+	set, err := s.set(string(args[0]), true)
+	if err != nil {
+		return err
+	}
+
+	n := int64(0)
+	for _, member := range args[1:] {
+		if set.put(string(member), nil) {
+			n++
+		}
+	}
+
+	return n
+}
+
+func cmdSRem(s *state, args [][]byte) interface{} {
+	key := string(args[0])
+	set, err := s.set(key, false)
+	if err != nil || set == nil {
+		return orZero(err)
+	}
+
+	n := int64(0)
+	for _, member := range args[1:] {
+		if set.del(string(member)) {
+			n++
+		}
+	}
+
+	s.removeIfEmpty(key)
+	return n
+}
+
+func cmdSMembers(s *state, args [][]byte) interface{} {
+	set, err := s.set(string(args[0]), false)
+	if err != nil {
+		return err
+	}
+
+	return members(set)
+}
+
+func members(set *omap) []interface{} {
+	res := []interface{}{}
+	if set != nil {
+		for _, member := range set.keys {
+			res = append(res, []byte(member))
+		}
+	}
+
+	return res
+}
+
+func cmdSIsMember(s *state, args [][]byte) interface{} {
+	set, err := s.set(string(args[0]), false)
+	if err != nil || set == nil {
+		return orZero(err)
+	}
+
+	if _, found := set.get(string(args[1])); found {
+		return int64(1)
+	}
+
+	return int64(0)
+}
+
+func cmdSMIsMember(s *state, args [][]byte) interface{} {
+	set, err := s.set(string(args[0]), false)
+	if err != nil {
+		return err
+	}
+
+	res := make([]interface{}, len(args)-1)
+	for i, member := range args[1:] {
+		res[i] = int64(0)
+		if set != nil {
+			if _, found := set.get(string(member)); found {
+				res[i] = int64(1)
+			}
+		}
+	}
+
+	return res
+}
+
+func cmdSCard(s *state, args [][]byte) interface{} {
+	set, err := s.set(string(args[0]), false)
+	if err != nil || set == nil {
+		return orZero(err)
+	}
+
+	return int64(set.len())
+}
+
+func cmdSPop(s *state, args [][]byte) interface{} {
+	if len(args) > 2 {
+		return errSyntax
+	}
+
+	key := string(args[0])
+	n := int64(1)
+	if len(args) == 2 {
+		var err error
+		if n, err = parseInt(args[1]); err != nil || n < 0 {
+			return Error("ERR value is out of range, must be positive")
+		}
+	}
+
+	set, err := s.set(key, false)
+	if err != nil {
+		return err
+	}
+
+	if set == nil {
+		if len(args) == 2 {
+			return []interface{}{}
+		}
+
+		return nil
+	}
+
+	res := []interface{}{}
+	for ; n > 0 && set.len() > 0; n-- {
+		member := set.keys[rand.Intn(set.len())]
+		set.del(member)
+		res = append(res, []byte(member))
+	}
+
+	s.removeIfEmpty(key)
+	if len(args) == 2 {
+		return res
+	}
+
+	return res[0]
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// cmdSRandMember returns distinct random members for a positive count and possibly repeated
+// members for a negative count.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func cmdSRandMember(s *state, args [][]byte) interface{} {
+	if len(args) > 2 {
+		return errSyntax
+	}
+
+	set, err := s.set(string(args[0]), false)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		if set == nil {
+			return nil
+		}
+
+		return []byte(set.keys[rand.Intn(set.len())])
+	}
+
+	count, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+
+	res := []interface{}{}
+	if set == nil {
+		return res
+	}
+
+	if count < 0 {
+		for ; count < 0; count++ {
+			res = append(res, []byte(set.keys[rand.Intn(set.len())]))
+		}
+
+		return res
+	}
+
+	for _, i := range rand.Perm(set.len()) {
+		if int64(len(res)) == count {
+			break
+		}
+
+		res = append(res, []byte(set.keys[i]))
+	}
+
+	return res
+}
+
+func cmdSMove(s *state, args [][]byte) interface{} {
+	src, dst, member := string(args[0]), string(args[1]), string(args[2])
+	from, err := s.set(src, false)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.set(dst, false); err != nil {
+		return err
+	}
+
+	if from == nil {
+		return int64(0)
+	}
+
+	if _, found := from.get(member); !found {
+		return int64(0)
+	}
+
+	from.del(member)
+	s.removeIfEmpty(src)
+
+	to, _ := s.set(dst, true)
+	to.put(member, nil)
+	return int64(1)
+}
+
+func setInter(sets []*omap) *omap {
+	res := newOmap(true)
+	if len(sets) == 0 || sets[0] == nil {
+		return res
+	}
+
+members:
+	for _, member := range sets[0].keys {
+		for _, other := range sets[1:] {
+			if other == nil {
+				return newOmap(true)
+			}
+
+			if _, found := other.get(member); !found {
+				continue members
+			}
+		}
+
+		res.put(member, nil)
+	}
+
+	return res
+}
+
+func setUnion(sets []*omap) *omap {
+	res := newOmap(true)
+	for _, set := range sets {
+		if set != nil {
+			for _, member := range set.keys {
+				res.put(member, nil)
+			}
+		}
+	}
+
+	return res
+}
+
+func setDiff(sets []*omap) *omap {
+	res := newOmap(true)
+	if len(sets) == 0 || sets[0] == nil {
+		return res
+	}
+
+	res = sets[0].copy()
+	for _, other := range sets[1:] {
+		if other != nil {
+			for _, member := range other.keys {
+				res.del(member)
+			}
+		}
+	}
+
+	return res
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// cmdSetOp implements SINTER, SUNION, SDIFF and their STORE variants, which take the
+// destination as first argument.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func cmdSetOp(op func(sets []*omap) *omap, store bool) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		keys := args
+		if store {
+			keys = args[1:]
+		}
+
+		sets := make([]*omap, len(keys))
+		for i, key := range keys {
+			set, err := s.set(string(key), false)
+			if err != nil {
+				return err
+			}
+
+			sets[i] = set
+		}
+
+		res := op(sets)
+		if !store {
+			return members(res)
+		}
+
+		dst := string(args[0])
+		s.del(dst)
+		if res.len() > 0 {
+			s.put(dst, res, false)
+		}
+
+		return int64(res.len())
+	}
+}
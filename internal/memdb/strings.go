@@ -0,0 +1,320 @@
+package memdb
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+func init() {
+	register("GET", 2, cmdGet)
+	registerWrite("SET", -3, firstKey, cmdSet)
+	registerWrite("SETEX", 4, firstKey, cmdSetEx(time.Second))
+	registerWrite("PSETEX", 4, firstKey, cmdSetEx(time.Millisecond))
+	registerWrite("SETNX", 3, firstKey, cmdSetNX)
+	registerWrite("GETSET", 3, firstKey, cmdGetSet)
+	registerWrite("GETDEL", 2, firstKey, cmdGetDel)
+	register("MGET", -2, cmdMGet)
+	registerWrite("MSET", -3, pairKeys, cmdMSet(false))
+	registerWrite("MSETNX", -3, pairKeys, cmdMSet(true))
+	registerWrite("INCR", 2, firstKey, cmdIncr(1))
+	registerWrite("DECR", 2, firstKey, cmdIncr(-1))
+	registerWrite("INCRBY", 3, firstKey, cmdIncrBy(1))
+	registerWrite("DECRBY", 3, firstKey, cmdIncrBy(-1))
+	registerWrite("INCRBYFLOAT", 3, firstKey, cmdIncrByFloat)
+	registerWrite("APPEND", 3, firstKey, cmdAppend)
+	register("STRLEN", 2, cmdStrlen)
+}
+
+func cmdGet(s *state, args [][]byte) interface{} {
+	b, err := s.str(string(args[0]))
+	if err != nil {
+		return err
+	}
+
+	if b == nil {
+		return nil
+	}
+
+	return b
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// cmdSet implements SET with the EX, PX, EXAT, PXAT, KEEPTTL, NX, XX and GET options.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func cmdSet(s *state, args [][]byte) interface{} {
+	key := string(args[0])
+
+	var (
+		expireAt          time.Time
+		nx, xx, keep, get bool
+		expiries          int
+	)
+
+	for i := 2; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case isArg(arg, "NX"):
+			nx = true
+		case isArg(arg, "XX"):
+			xx = true
+		case isArg(arg, "GET"):
+			get = true
+		case isArg(arg, "KEEPTTL"):
+			keep = true
+			expiries++
+		case isArg(arg, "EX"), isArg(arg, "PX"), isArg(arg, "EXAT"), isArg(arg, "PXAT"):
+			if i+1 >= len(args) {
+				return errSyntax
+			}
+
+			n, err := parseInt(args[i+1])
+			if err != nil {
+				return err
+			}
+
+			if n <= 0 {
+				return Error("ERR invalid expire time in 'set' command")
+			}
+
+			switch {
+			case isArg(arg, "EX"):
+				expireAt = s.now.Add(time.Duration(n) * time.Second)
+			case isArg(arg, "PX"):
+				expireAt = s.now.Add(time.Duration(n) * time.Millisecond)
+			case isArg(arg, "EXAT"):
+				expireAt = time.Unix(n, 0)
+			default:
+				expireAt = time.Unix(0, 0).Add(time.Duration(n) * time.Millisecond)
+			}
+
+			expiries++
+			i++
+		default:
+			return errSyntax
+		}
+	}
+
+	if (nx && xx) || expiries > 1 {
+		return errSyntax
+	}
+
+	var old interface{}
+	if get {
+		b, err := s.str(key)
+		if err != nil {
+			return err
+		}
+
+		if b != nil {
+			old = b
+		}
+	}
+
+	exists := s.lookup(key) != nil
+	if (nx && exists) || (xx && !exists) {
+		if get {
+			return old
+		}
+
+		return nil
+	}
+
+	e := s.put(key, args[1], keep)
+	if !expireAt.IsZero() {
+		e.expireAt = expireAt
+	}
+
+	if get {
+		return old
+	}
+
+	return ok
+}
+
+func cmdSetEx(unit time.Duration) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		n, err := parseInt(args[1])
+		if err != nil {
+			return err
+		}
+
+		if n <= 0 {
+			return Error("ERR invalid expire time in 'setex' command")
+		}
+
+		e := s.put(string(args[0]), args[2], false)
+		e.expireAt = s.now.Add(time.Duration(n) * unit)
+		return ok
+	}
+}
+
+func cmdSetNX(s *state, args [][]byte) interface{} {
+	if s.lookup(string(args[0])) != nil {
+		return int64(0)
+	}
+
+	s.put(string(args[0]), args[1], false)
+	return int64(1)
+}
+
+func cmdGetSet(s *state, args [][]byte) interface{} {
+	reply := cmdGet(s, args[:1])
+	if _, isErr := reply.(error); isErr {
+		return reply
+	}
+
+	s.put(string(args[0]), args[1], false)
+	return reply
+}
+
+func cmdGetDel(s *state, args [][]byte) interface{} {
+	reply := cmdGet(s, args)
+	if reply != nil {
+		if _, isErr := reply.(error); !isErr {
+			s.del(string(args[0]))
+		}
+	}
+
+	return reply
+}
+
+func cmdMGet(s *state, args [][]byte) interface{} {
+	res := make([]interface{}, len(args))
+	for i, key := range args {
+		if b, err := s.str(string(key)); err == nil && b != nil {
+			res[i] = b
+		}
+	}
+
+	return res
+}
+
+func cmdMSet(nx bool) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		if len(args)%2 != 0 {
+			if nx {
+				return Error("ERR wrong number of arguments for 'msetnx' command")
+			}
+
+			return Error("ERR wrong number of arguments for 'mset' command")
+		}
+
+		if nx {
+			for i := 0; i < len(args); i += 2 {
+				if s.lookup(string(args[i])) != nil {
+					return int64(0)
+				}
+			}
+		}
+
+		for i := 0; i < len(args); i += 2 {
+			s.put(string(args[i]), args[i+1], false)
+		}
+
+		if nx {
+			return int64(1)
+		}
+
+		return ok
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// incr adds delta to the integer stored at key. The time to live of the key is kept.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *state) incr(key string, delta int64) interface{} {
+	b, err := s.str(key)
+	if err != nil {
+		return err
+	}
+
+	n := int64(0)
+	if b != nil {
+		if n, err = parseInt(b); err != nil {
+			return err
+		}
+	}
+
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		return errOverflow
+	}
+
+	n += delta
+	s.put(key, []byte(strconv.FormatInt(n, 10)), true)
+	return n
+}
+
+func cmdIncr(delta int64) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		return s.incr(string(args[0]), delta)
+	}
+}
+
+func cmdIncrBy(sign int64) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		delta, err := parseInt(args[1])
+		if err != nil {
+			return err
+		}
+
+		if sign < 0 {
+			if delta == math.MinInt64 {
+				return errOverflow
+			}
+
+			delta = -delta
+		}
+
+		return s.incr(string(args[0]), delta)
+	}
+}
+
+func cmdIncrByFloat(s *state, args [][]byte) interface{} {
+	key := string(args[0])
+	delta, err := parseFloat(args[1])
+	if err != nil {
+		return err
+	}
+
+	b, err := s.str(key)
+	if err != nil {
+		return err
+	}
+
+	f := 0.0
+	if b != nil {
+		if f, err = parseFloat(b); err != nil {
+			return err
+		}
+	}
+
+	f += delta
+	if math.IsInf(f, 0) {
+		return Error("ERR increment would produce NaN or Infinity")
+	}
+
+	res := formatHumanFloat(f)
+	s.put(key, res, true)
+	return res
+}
+
+func cmdAppend(s *state, args [][]byte) interface{} {
+	b, err := s.str(string(args[0]))
+	if err != nil {
+		return err
+	}
+
+	b = append(append([]byte(nil), b...), args[1]...)
+	s.put(string(args[0]), b, true)
+	return int64(len(b))
+}
+
+func cmdStrlen(s *state, args [][]byte) interface{} {
+	b, err := s.str(string(args[0]))
+	if err != nil {
+		return err
+	}
+
+	return int64(len(b))
+}
@@ -0,0 +1,192 @@
+package memdb
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+func parseInt(b []byte) (int64, error) {
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return 0, errNotInteger
+	}
+
+	return n, nil
+}
+
+func parseFloat(b []byte) (float64, error) {
+	f, err := strconv.ParseFloat(string(b), 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, errNotFloat
+	}
+
+	return f, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// formatFloat formats f like redis replies with doubles.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func formatFloat(f float64) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return []byte("inf")
+	case math.IsInf(f, -1):
+		return []byte("-inf")
+	}
+
+	return []byte(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+// formatHumanFloat formats a finite f without exponent, like INCRBYFLOAT stores it.
+func formatHumanFloat(f float64) []byte {
+	return []byte(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+func isArg(b []byte, name string) bool {
+	return strings.EqualFold(string(b), name)
+}
+
+func bulks(values [][]byte) []interface{} {
+	res := make([]interface{}, len(values))
+	for i, v := range values {
+		res[i] = v
+	}
+
+	return res
+}
+
+func strs(args [][]byte) []string {
+	res := make([]string, len(args))
+	for i, a := range args {
+		res[i] = string(a)
+	}
+
+	return res
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// span converts the inclusive range start..stop, where negative indexes count from the end,
+// into a half open range of a sequence of length n. ok is false for an empty range.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func span(start, stop int64, n int) (from, to int, ok bool) {
+	if start < 0 {
+		start += int64(n)
+	}
+
+	if stop < 0 {
+		stop += int64(n)
+	}
+
+	if start < 0 {
+		start = 0
+	}
+
+	if stop >= int64(n) {
+		stop = int64(n) - 1
+	}
+
+	if start > stop || start >= int64(n) {
+		return 0, 0, false
+	}
+
+	return int(start), int(stop) + 1, true
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// match reports whether s matches the glob-style pattern like the MATCH option of SCAN.
+// Supported are *, ?, character classes like [a-z] or [^a] and \ to escape.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+
+			if len(pattern) == 1 {
+				return true
+			}
+
+			for i := 0; i <= len(s); i++ {
+				if match(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+
+			end, matched := matchClass(pattern, s[0])
+			if !matched {
+				return false
+			}
+
+			s = s[1:]
+			pattern = pattern[end:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+
+			s = s[1:]
+			pattern = pattern[1:]
+		}
+	}
+
+	return len(s) == 0
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// matchClass matches c against the class at the start of pattern and returns the length of
+// the class.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func matchClass(pattern string, c byte) (int, bool) {
+	i := 1
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+
+	matched := false
+	for ; i < len(pattern) && pattern[i] != ']'; i++ {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			i++
+			matched = matched || pattern[i] == c
+		case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+			lo, hi := pattern[i], pattern[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+
+			matched = matched || (c >= lo && c <= hi)
+			i += 2
+		default:
+			matched = matched || pattern[i] == c
+		}
+	}
+
+	if i < len(pattern) {
+		i++
+	}
+
+	return i, matched != negate
+}
@@ -0,0 +1,628 @@
+package memdb
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerWrite("ZADD", -4, firstKey, cmdZAdd)
+	registerWrite("ZINCRBY", 4, firstKey, cmdZIncrBy)
+	registerWrite("ZREM", -3, firstKey, cmdZRem)
+	register("ZCARD", 2, cmdZCard)
+	register("ZSCORE", 3, cmdZScore)
+	register("ZMSCORE", -3, cmdZMScore)
+	register("ZCOUNT", 4, cmdZCount)
+	register("ZRANK", 3, cmdZRank(false))
+	register("ZREVRANK", 3, cmdZRank(true))
+	register("ZRANGE", -4, cmdZRange)
+	register("ZREVRANGE", -4, cmdZRevRange)
+	register("ZRANGEBYSCORE", -4, cmdZRangeByScore(false))
+	register("ZREVRANGEBYSCORE", -4, cmdZRangeByScore(true))
+	registerWrite("ZREMRANGEBYSCORE", 4, firstKey, cmdZRemRangeByScore)
+	registerWrite("ZREMRANGEBYRANK", 4, firstKey, cmdZRemRangeByRank)
+	registerWrite("ZPOPMIN", -2, firstKey, cmdZPop(false))
+	registerWrite("ZPOPMAX", -2, firstKey, cmdZPop(true))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// zset keeps its members ordered by score and member.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type zset struct {
+	scores map[string]float64
+	sorted []zitem
+}
+
+type zitem struct {
+	member string
+	score  float64
+}
+
+func newZset() *zset {
+	return &zset{scores: make(map[string]float64)}
+}
+
+func (a zitem) less(b zitem) bool {
+	return a.score < b.score || (a.score == b.score && a.member < b.member)
+}
+
+func (z *zset) search(it zitem) int {
+	return sort.Search(len(z.sorted), func(i int) bool {
+		return !z.sorted[i].less(it)
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// put sets the score of member and reports whether member is new.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (z *zset) put(member string, score float64) bool {
+	old, found := z.scores[member]
+	if found {
+		if old == score {
+			return false
+		}
+
+		z.remove(zitem{member, old})
+	}
+
+	it := zitem{member, score}
+	i := z.search(it)
+	z.sorted = append(z.sorted, zitem{})
+	copy(z.sorted[i+1:], z.sorted[i:])
+	z.sorted[i] = it
+	z.scores[member] = score
+	return !found
+}
+
+func (z *zset) del(member string) bool {
+	score, found := z.scores[member]
+	if !found {
+		return false
+	}
+
+	z.remove(zitem{member, score})
+	delete(z.scores, member)
+	return true
+}
+
+func (z *zset) remove(it zitem) {
+	i := z.search(it)
+	z.sorted = append(z.sorted[:i], z.sorted[i+1:]...)
+}
+
+func (z *zset) rank(member string) int {
+	score, found := z.scores[member]
+	if !found {
+		return -1
+	}
+
+	return z.search(zitem{member, score})
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// bound is a score boundary like "1.5", "(1.5", "-inf" or "+inf".
+////////////////////////////////////////////////////////////////////////////////////////////////
+type bound struct {
+	value     float64
+	exclusive bool
+}
+
+func parseBound(b []byte) (bound, error) {
+	s := string(b)
+	res := bound{}
+	if strings.HasPrefix(s, "(") {
+		res.exclusive = true
+		s = s[1:]
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return res, Error("ERR min or max is not a float")
+	}
+
+	res.value = f
+	return res, nil
+}
+
+func (b bound) below(score float64) bool {
+	return b.value < score || (!b.exclusive && b.value == score)
+}
+
+func (b bound) above(score float64) bool {
+	return b.value > score || (!b.exclusive && b.value == score)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// byScore returns the half open index range of the members with a score within min and max.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (z *zset) byScore(min, max bound) (int, int) {
+	from := sort.Search(len(z.sorted), func(i int) bool {
+		return min.below(z.sorted[i].score)
+	})
+
+	to := sort.Search(len(z.sorted), func(i int) bool {
+		return !max.above(z.sorted[i].score)
+	})
+
+	if to < from {
+		to = from
+	}
+
+	return from, to
+}
+
+func cmdZAdd(s *state, args [][]byte) interface{} {
+	var nx, xx, gt, lt, ch, incr bool
+	i := 1
+options:
+	for ; i < len(args); i++ {
+		switch {
+		case isArg(args[i], "NX"):
+			nx = true
+		case isArg(args[i], "XX"):
+			xx = true
+		case isArg(args[i], "GT"):
+			gt = true
+		case isArg(args[i], "LT"):
+			lt = true
+		case isArg(args[i], "CH"):
+			ch = true
+		case isArg(args[i], "INCR"):
+			incr = true
+		default:
+			break options
+		}
+	}
+
+	pairs := args[i:]
+	switch {
+	case len(pairs) == 0 || len(pairs)%2 != 0:
+		return errSyntax
+	case nx && xx:
+		return Error("ERR XX and NX options at the same time are not compatible")
+	case (gt && lt) || (nx && (gt || lt)):
+		return Error("ERR GT, LT, and/or NX options at the same time are not compatible")
+	case incr && len(pairs) != 2:
+		return Error("ERR INCR option supports a single increment-element pair")
+	}
+
+	scores := make([]float64, len(pairs)/2)
+	for n := range scores {
+		f, err := parseFloat(pairs[2*n])
+		if err != nil {
+			return err
+		}
+
+		scores[n] = f
+	}
+
+	key := string(args[0])
+	z, err := s.zset(key, true)
+	if err != nil {
+		return err
+	}
+
+	defer s.removeIfEmpty(key)
+
+	added, changed := int64(0), int64(0)
+	for n, score := range scores {
+		member := string(pairs[2*n+1])
+		old, found := z.scores[member]
+
+		if incr && found {
+			score += old
+		}
+
+		switch {
+		case nx && found, xx && !found:
+			if incr {
+				return nil
+			}
+
+			continue
+		case found && ((gt && score <= old) || (lt && score >= old)):
+			if incr {
+				return nil
+			}
+
+			continue
+		}
+
+		if z.put(member, score) {
+			added++
+		} else if found && old != score {
+			changed++
+		}
+
+		if incr {
+			return formatFloat(score)
+		}
+	}
+
+	if ch {
+		return added + changed
+	}
+
+	return added
+}
+
+func cmdZIncrBy(s *state, args [][]byte) interface{} {
+	delta, err := parseFloat(args[1])
+	if err != nil {
+		return err
+	}
+
+	z, err := s.zset(string(args[0]), true)
+	if err != nil {
+		return err
+	}
+
+	score := z.scores[string(args[2])] + delta
+	if math.IsNaN(score) {
+		s.removeIfEmpty(string(args[0]))
+		return Error("ERR resulting score is not a number (NaN)")
+	}
+
+	z.put(string(args[2]), score)
+	return formatFloat(score)
+}
+
+func cmdZRem(s *state, args [][]byte) interface{} {
+	key := string(args[0])
+	z, err := s.zset(key, false)
+	if err != nil || z == nil {
+		return orZero(err)
+	}
+
+	n := int64(0)
+	for _, member := range args[1:] {
+		if z.del(string(member)) {
+			n++
+		}
+	}
+
+	s.removeIfEmpty(key)
+	return n
+}
+
+func cmdZCard(s *state, args [][]byte) interface{} {
+	z, err := s.zset(string(args[0]), false)
+	if err != nil || z == nil {
+		return orZero(err)
+	}
+
+	return int64(len(z.sorted))
+}
+
+func cmdZScore(s *state, args [][]byte) interface{} {
+	z, err := s.zset(string(args[0]), false)
+	if err != nil || z == nil {
+		return err
+	}
+
+	if score, found := z.scores[string(args[1])]; found {
+		return formatFloat(score)
+	}
+
+	return nil
+}
+
+func cmdZMScore(s *state, args [][]byte) interface{} {
+	z, err := s.zset(string(args[0]), false)
+	if err != nil {
+		return err
+	}
+
+	res := make([]interface{}, len(args)-1)
+	for i, member := range args[1:] {
+		if z != nil {
+			if score, found := z.scores[string(member)]; found {
+				res[i] = formatFloat(score)
+			}
+		}
+	}
+
+	return res
+}
+
+func cmdZCount(s *state, args [][]byte) interface{} {
+	min, err := parseBound(args[1])
+	if err != nil {
+		return err
+	}
+
+	max, err := parseBound(args[2])
+	if err != nil {
+		return err
+	}
+
+	z, err := s.zset(string(args[0]), false)
+	if err != nil || z == nil {
+		return orZero(err)
+	}
+
+	from, to := z.byScore(min, max)
+	return int64(to - from)
+}
+
+func cmdZRank(rev bool) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		z, err := s.zset(string(args[0]), false)
+		if err != nil || z == nil {
+			return err
+		}
+
+		rank := z.rank(string(args[1]))
+		switch {
+		case rank < 0:
+			return nil
+		case rev:
+			return int64(len(z.sorted) - 1 - rank)
+		}
+
+		return int64(rank)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// zrangeQuery describes a range of a sorted set by index or by score.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type zrangeQuery struct {
+	start, stop   []byte
+	byScore       bool
+	rev           bool
+	withScores    bool
+	limit         bool
+	offset, count int64
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// parseOptions parses WITHSCORES, LIMIT and, if all is set, BYSCORE and REV.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *zrangeQuery) parseOptions(args [][]byte, all bool) error {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case isArg(args[i], "WITHSCORES"):
+			q.withScores = true
+		case all && isArg(args[i], "BYSCORE"):
+			q.byScore = true
+		case all && isArg(args[i], "REV"):
+			q.rev = true
+		case isArg(args[i], "LIMIT") && i+2 < len(args):
+			offset, err := parseInt(args[i+1])
+			if err != nil {
+				return err
+			}
+
+			count, err := parseInt(args[i+2])
+			if err != nil {
+				return err
+			}
+
+			q.limit, q.offset, q.count = true, offset, count
+			i += 2
+		default:
+			return errSyntax
+		}
+	}
+
+	if q.limit && !q.byScore {
+		return Error("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// items returns the members selected by q in reply order.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *zrangeQuery) items(z *zset) ([]zitem, error) {
+	var items []zitem
+
+	if q.byScore {
+		min, max := q.start, q.stop
+		if q.rev {
+			min, max = max, min
+		}
+
+		lo, err := parseBound(min)
+		if err != nil {
+			return nil, err
+		}
+
+		hi, err := parseBound(max)
+		if err != nil {
+			return nil, err
+		}
+
+		if z != nil {
+			from, to := z.byScore(lo, hi)
+			items = append(items, z.sorted[from:to]...)
+		}
+	} else {
+		start, err := parseInt(q.start)
+		if err != nil {
+			return nil, err
+		}
+
+		stop, err := parseInt(q.stop)
+		if err != nil {
+			return nil, err
+		}
+
+		if z != nil {
+			n := len(z.sorted)
+			if from, to, ok := span(start, stop, n); ok {
+				if q.rev {
+					from, to = n-to, n-from
+				}
+
+				items = append(items, z.sorted[from:to]...)
+			}
+		}
+	}
+
+	if q.rev {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if q.limit {
+		if q.offset < 0 || q.offset >= int64(len(items)) {
+			return nil, nil
+		}
+
+		items = items[q.offset:]
+		if q.count >= 0 && q.count < int64(len(items)) {
+			items = items[:q.count]
+		}
+	}
+
+	return items, nil
+}
+
+func (q *zrangeQuery) reply(s *state, key []byte) interface{} {
+	z, err := s.zset(string(key), false)
+	if err != nil {
+		return err
+	}
+
+	items, err := q.items(z)
+	if err != nil {
+		return err
+	}
+
+	res := []interface{}{}
+	for _, it := range items {
+		res = append(res, []byte(it.member))
+		if q.withScores {
+			res = append(res, formatFloat(it.score))
+		}
+	}
+
+	return res
+}
+
+func cmdZRange(s *state, args [][]byte) interface{} {
+	q := &zrangeQuery{start: args[1], stop: args[2]}
+	if err := q.parseOptions(args[3:], true); err != nil {
+		return err
+	}
+
+	return q.reply(s, args[0])
+}
+
+func cmdZRevRange(s *state, args [][]byte) interface{} {
+	q := &zrangeQuery{start: args[1], stop: args[2], rev: true}
+	if err := q.parseOptions(args[3:], false); err != nil {
+		return err
+	}
+
+	return q.reply(s, args[0])
+}
+
+func cmdZRangeByScore(rev bool) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		q := &zrangeQuery{start: args[1], stop: args[2], byScore: true, rev: rev}
+		if err := q.parseOptions(args[3:], false); err != nil {
+			return err
+		}
+
+		return q.reply(s, args[0])
+	}
+}
+
+func cmdZRemRangeByScore(s *state, args [][]byte) interface{} {
+	min, err := parseBound(args[1])
+	if err != nil {
+		return err
+	}
+
+	max, err := parseBound(args[2])
+	if err != nil {
+		return err
+	}
+
+	key := string(args[0])
+	z, err := s.zset(key, false)
+	if err != nil || z == nil {
+		return orZero(err)
+	}
+
+	from, to := z.byScore(min, max)
+	return removeRange(s, key, z, from, to)
+}
+
+func cmdZRemRangeByRank(s *state, args [][]byte) interface{} {
+	start, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+
+	stop, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+
+	key := string(args[0])
+	z, err := s.zset(key, false)
+	if err != nil || z == nil {
+		return orZero(err)
+	}
+
+	from, to, ok := span(start, stop, len(z.sorted))
+	if !ok {
+		return int64(0)
+	}
+
+	return removeRange(s, key, z, from, to)
+}
+
+func removeRange(s *state, key string, z *zset, from, to int) interface{} {
+	for _, it := range z.sorted[from:to] {
+		delete(z.scores, it.member)
+	}
+
+	z.sorted = append(z.sorted[:from], z.sorted[to:]...)
+	s.removeIfEmpty(key)
+	return int64(to - from)
+}
+
+func cmdZPop(max bool) func(s *state, args [][]byte) interface{} {
+	return func(s *state, args [][]byte) interface{} {
+		if len(args) > 2 {
+			return errSyntax
+		}
+
+		n := int64(1)
+		if len(args) == 2 {
+			var err error
+			if n, err = parseInt(args[1]); err != nil || n < 0 {
+				return Error("ERR value is out of range, must be positive")
+			}
+		}
+
+		key := string(args[0])
+		z, err := s.zset(key, false)
+		if err != nil {
+			return err
+		}
+
+		res := []interface{}{}
+		for ; z != nil && n > 0 && len(z.sorted) > 0; n-- {
+			it := z.sorted[0]
+			if max {
+				it = z.sorted[len(z.sorted)-1]
+			}
+
+			z.del(it.member)
+			res = append(res, []byte(it.member), formatFloat(it.score))
+		}
+
+		s.removeIfEmpty(key)
+		return res
+	}
+}
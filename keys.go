@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"iter"
+)
+
+// defaultScanCount is the COUNT hint of Keys if ScanOptions.Count is zero.
+const defaultScanCount = 100
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// ScanOptions configure the iteration of Keys.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type ScanOptions struct {
+	// Count is the number of keys the server visits per SCAN step, 100 if zero. It is a hint,
+	// a step may return more or fewer keys.
+	Count int
+
+	// Type limits the keys to a type like "string", "hash" or "zset", which needs redis 6 or
+	// later.
+	Type string
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Keys returns an iterator over the keys matching the glob-style pattern match, all keys if
+// match is empty. The keys are fetched with SCAN bounded by ctx while the loop runs and every
+// key is yielded once, even if SCAN returns it repeatedly. Breaking out of the loop ends the
+// iteration. On a cluster every master is scanned.
+//
+//	for key, err := range st.Keys(ctx, "user:*", store.ScanOptions{Type: "hash"}) {
+//		if err != nil {
+//			return err
+//		}
+//
+//		fmt.Println(key)
+//	}
+//
+// An error ends the iteration and is yielded with an empty key. Keys added or removed during
+// the iteration may or may not be returned. To deduplicate, the keys returned so far are kept
+// in memory until the iteration ends.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) Keys(ctx context.Context, match string, opts ScanOptions) iter.Seq2[string, error] {
+	st := s.WithContext(ctx)
+
+	return func(yield func(string, error) bool) {
+		if opts.Count < 0 {
+			yield("", fmt.Errorf("store: invalid SCAN count %d", opts.Count))
+			return
+		}
+
+		if match == "" {
+			match = "*"
+		}
+
+		count := opts.Count
+		if count == 0 {
+			count = defaultScanCount
+		}
+
+		args := []interface{}{"MATCH", match, "COUNT", count}
+		if opts.Type != "" {
+			args = append(args, "TYPE", opts.Type)
+		}
+
+		scans := []func(args ...interface{}) (interface{}, error){
+			func(args ...interface{}) (interface{}, error) {
+				return st.do("SCAN", args...)
+			},
+		}
+
+		if st.cluster != nil {
+			scans = scans[:0]
+			for _, addr := range st.cluster.masters() {
+				addr := addr
+				scans = append(scans, func(args ...interface{}) (interface{}, error) {
+					return st.doNode(addr, "SCAN", args...)
+				})
+			}
+		}
+
+		seen := make(map[string]struct{})
+		for _, scan := range scans {
+			cursor := "0"
+			for {
+				next, keys, err := parseScanPage(scan(append([]interface{}{cursor}, args...)...))
+				if err != nil {
+					yield("", err)
+					return
+				}
+
+				for _, key := range keys {
+					if _, dup := seen[key]; dup {
+						continue
+					}
+
+					seen[key] = struct{}{}
+					if !yield(key, nil) {
+						return
+					}
+				}
+
+				if next == "0" {
+					break
+				}
+
+				cursor = next
+			}
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// parseScanPage parses a SCAN reply. The cursor is kept as string, since it is an unsigned 64
+// bit integer.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func parseScanPage(data interface{}, err error) (string, []string, error) {
+	vals, err := redis.Values(data, err)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(vals) != 2 {
+		return "", nil, fmt.Errorf("store: invalid SCAN reply of length %d", len(vals))
+	}
+
+	cursor, err := redis.String(vals[0], nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	keys, err := redis.Strings(vals[1], nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return cursor, keys, nil
+}
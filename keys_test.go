@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"github.com/denkhaus/tcgl/asserts"
+	"github.com/garyburd/redigo/redis"
+	"sort"
+	"testing"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestKeys
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestKeys(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	want := []string{"testKeys1", "testKeys2", "testKeys3"}
+	for _, key := range want {
+		assert.Nil(st.Set(key, "value"), "Error should be nil.")
+	}
+	assert.Nil(st.HashSet("testKeysHash", "field", "value"), "Error should be nil.")
+
+	var got []string
+	for key, err := range st.Keys(context.Background(), "testKeys?", ScanOptions{Count: 1}) {
+		assert.Nil(err, "Error should be nil.")
+		got = append(got, key)
+	}
+
+	sort.Strings(got)
+	assert.Equal(got, want, "Keys should return every matching key")
+
+	got = nil
+	for key, err := range st.Keys(context.Background(), "testKeys*", ScanOptions{Type: "hash"}) {
+		assert.Nil(err, "Error should be nil.")
+		got = append(got, key)
+	}
+	assert.Equal(got, []string{"testKeysHash"}, "Keys should filter by type")
+
+	calls := 0
+	for range st.Keys(context.Background(), "testKeys*", ScanOptions{}) {
+		calls++
+		break
+	}
+	assert.Equal(calls, 1, "breaking out of the loop should stop the iteration")
+
+	for _, err := range st.Keys(context.Background(), "", ScanOptions{Count: -1}) {
+		assert.NotNil(err, "a negative count should fail")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range st.Keys(ctx, "", ScanOptions{}) {
+		assert.Equal(err, context.Canceled, "a cancelled context should end the iteration")
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestKeysScanReplies
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestKeysScanReplies(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	// SCAN returns a key twice and a cursor beyond the range of int64.
+	srv := newFakeServer(t, func(args []string) interface{} {
+		switch {
+		case args[0] == "PING":
+			return "PONG"
+		case args[0] == "SCAN" && args[1] == "0":
+			return []interface{}{[]byte("18446744073709551615"), []interface{}{[]byte("a"), []byte("b")}}
+		case args[0] == "SCAN":
+			return []interface{}{[]byte("0"), []interface{}{[]byte("b"), []byte("c")}}
+		}
+
+		return redis.Error("ERR unknown command")
+	})
+
+	st, err := Open(srv.addr())
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	var got []string
+	for key, err := range st.Keys(context.Background(), "", ScanOptions{Count: 50, Type: "string"}) {
+		assert.Nil(err, "Error should be nil.")
+		got = append(got, key)
+	}
+	assert.Equal(got, []string{"a", "b", "c"}, "Keys should skip repeated keys")
+
+	var scans [][]string
+	for _, cmd := range srv.received() {
+		if cmd[0] == "SCAN" {
+			scans = append(scans, cmd)
+		}
+	}
+
+	assert.Length(scans, 2, "Keys should scan until the cursor is 0")
+	assert.Equal(scans[1], []string{"SCAN", "18446744073709551615", "MATCH", "*", "COUNT", "50", "TYPE", "string"}, "Keys should pass the cursor and options")
+}
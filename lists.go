@@ -1,31 +1,263 @@
 package store
 
 import (
-	//	"github.com/garyburd/redigo/redis"
-	"github.com/vmihailenco/msgpack"
-	//	"time"
+	"github.com/garyburd/redigo/redis"
+	"strings"
+)
+
+// ListEnd selects the head or the tail of a list.
+type ListEnd string
+
+const (
+	// ListLeft is the head of a list.
+	ListLeft ListEnd = "LEFT"
+
+	// ListRight is the tail of a list.
+	ListRight ListEnd = "RIGHT"
 )
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
-// Pushes a value to a list
+// Pushes a value to the head of a list. The key is not stored, it was pushed as an additional
+// element by earlier versions.
+//
+// Deprecated: use ListPushLeft.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) ListPush(list, key string, value interface{}) error {
-	conn := s.Pool.Get()
-	defer conn.Close()
+	_, err := s.ListPushLeft(list, value)
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Pushes values to the head of a list and returns its new length. The values are pushed one
+// after the other, so the last one becomes the head.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListPushLeft(list string, values ...interface{}) (int, error) {
+	return s.listPush("LPUSH", list, values)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Appends values to the tail of a list and returns its new length.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListPushRight(list string, values ...interface{}) (int, error) {
+	return s.listPush("RPUSH", list, values)
+}
+
+func (s *Store) listPush(cmd, list string, values []interface{}) (int, error) {
+	if len(values) == 0 {
+		return s.ListLen(list)
+	}
+
+	args := make([]interface{}, 1, len(values)+1)
+	args[0] = list
+	for _, value := range values {
+		b, err := s.encode(value)
+		if err != nil {
+			return 0, err
+		}
+
+		args = append(args, b)
+	}
+
+	data, err := s.do(cmd, args...)
+	return redis.Int(data, err)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes and returns the head of a list. An empty list will return ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListPopLeft(list string) (interface{}, error) {
+	var out interface{}
+	if err := s.ListPopLeftInto(list, &out); err != nil {
+		return nil, err
+	}
 
-	if err := conn.Err(); err != nil {
+	return out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes the head of a list and decodes it into the value pointed to by dst.
+// An empty list will return ErrNotFound and leaves dst untouched.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListPopLeftInto(list string, dst interface{}) error {
+	return s.listGet(list, dst, "LPOP", list)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes and returns the tail of a list. An empty list will return ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListPopRight(list string) (interface{}, error) {
+	var out interface{}
+	if err := s.ListPopRightInto(list, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes the tail of a list and decodes it into the value pointed to by dst.
+// An empty list will return ErrNotFound and leaves dst untouched.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListPopRightInto(list string, dst interface{}) error {
+	return s.listGet(list, dst, "RPOP", list)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Atomically removes the element at the end from of source, pushes it to the end to of
+// destination and returns it. Both may be the same list to rotate it. An empty source will
+// return ErrNotFound. It needs redis 6.2 or later.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListMove(source, destination string, from, to ListEnd) (interface{}, error) {
+	var out interface{}
+	if err := s.ListMoveInto(source, destination, from, to, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like ListMove but decodes the moved element into the value pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListMoveInto(source, destination string, from, to ListEnd, dst interface{}) error {
+	return s.listGet(source, dst, "LMOVE", source, destination, string(from), string(to))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the element at index of a list, counting from the tail if index is negative. An
+// index out of range will return ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListIndex(list string, index int) (interface{}, error) {
+	var out interface{}
+	if err := s.ListIndexInto(list, index, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Decodes the element at index of a list into the value pointed to by dst.
+// An index out of range will return ErrNotFound and leaves dst untouched.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListIndexInto(list string, index int, dst interface{}) error {
+	return s.listGet(list, dst, "LINDEX", list, index)
+}
+
+// listGet decodes the single element returned by cmd into dst.
+func (s *Store) listGet(list string, dst interface{}, cmd string, args ...interface{}) error {
+	data, err := s.do(cmd, args...)
+	if err != nil {
 		return err
 	}
 
-	b, err := msgpack.Marshal(value)
+	if data == nil {
+		return ErrNotFound
+	}
+
+	return s.decode(list, "", data, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the elements of a list from start to stop (inclusive). Negative indexes count from
+// the tail, ListRange(list, 0, -1) returns all elements.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListRange(list string, start, stop int) ([]interface{}, error) {
+	res, err := redis.Values(s.do("LRANGE", list, start, stop))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.decodeValues(list, res)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like ListRange but decodes the elements into the slice pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListRangeInto(list string, start, stop int, dst interface{}) error {
+	res, err := redis.Values(s.do("LRANGE", list, start, stop))
 	if err != nil {
 		return err
 	}
 
-	_, err = conn.Do("LPUSH", list, key, b)
+	return s.decodeValuesInto(list, res, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the length of a list, 0 if it does not exist.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListLen(list string) (int, error) {
+	data, err := s.do("LLEN", list)
+	return redis.Int(data, err)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Replaces the element at index of a list, counting from the tail if index is negative. A
+// missing list will return ErrNotFound, an index out of range the error of the server.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListSet(list string, index int, value interface{}) error {
+	b, err := s.encode(value)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	_, err = s.do("LSET", list, index, b)
+	if rerr, isReply := err.(redis.Error); isReply && strings.HasPrefix(string(rerr), "ERR no such key") {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes elements equal to value from a list and returns the number of removed elements.
+// If count is positive at most count elements are removed from the head on, if it is negative
+// from the tail on and all of them if it is 0. Elements are compared by their encoding.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListRemove(list string, count int, value interface{}) (int, error) {
+	b, err := s.encode(value)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := s.do("LREM", list, count, b)
+	return redis.Int(data, err)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Trims a list to the elements from start to stop (inclusive), negative indexes count from the
+// tail. A list trimmed to no elements is removed.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListTrim(list string, start, stop int) error {
+	_, err := s.do("LTRIM", list, start, stop)
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Inserts value before or after the first element equal to pivot and returns the new length
+// of the list. Elements are compared by their encoding. A missing list or pivot will return
+// ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) ListInsert(list string, before bool, pivot, value interface{}) (int, error) {
+	p, err := s.encode(pivot)
+	if err != nil {
+		return 0, err
+	}
+
+	b, err := s.encode(value)
+	if err != nil {
+		return 0, err
+	}
+
+	where := "AFTER"
+	if before {
+		where = "BEFORE"
+	}
+
+	n, err := redis.Int(s.do("LINSERT", list, where, p, b))
+	if err == nil && n <= 0 {
+		return 0, ErrNotFound
+	}
+
+	return n, err
 }
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"errors"
+	"github.com/denkhaus/tcgl/asserts"
+	"testing"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestListPushPop
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestListPushPop(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	list := "testListPushPop"
+	assert.Nil(st.Delete(list), "Error should be nil.")
+
+	n, err := st.ListPushRight(list, "b", "c")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "ListPushRight should return the new length")
+
+	n, err = st.ListPushLeft(list, "a", codecTestItem{Name: "item", Count: 1})
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 4, "ListPushLeft should return the new length")
+
+	var item codecTestItem
+	assert.Nil(st.ListPopLeftInto(list, &item), "Error should be nil.")
+	assert.Equal(item.Name, "item", "ListPopLeftInto should decode the head")
+
+	val, err := st.ListPopRight(list)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "c", "ListPopRight should return the tail")
+
+	vals, err := st.ListRange(list, 0, -1)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(vals, []interface{}{"a", "b"}, "ListRange should return the remaining elements")
+
+	assert.Nil(st.ListPush(list, "key", "z"), "Error should be nil.")
+	n, err = st.ListLen(list)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 3, "ListPush should not push the key")
+
+	assert.Nil(st.Delete(list), "Error should be nil.")
+	_, err = st.ListPopLeft(list)
+	assert.True(errors.Is(err, ErrNotFound), "popping from an empty list should return ErrNotFound")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestListModify
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestListModify(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	list := "testListModify"
+	assert.Nil(st.Delete(list), "Error should be nil.")
+
+	_, err := st.ListPushRight(list, 1, 2, 3, 2, 1)
+	assert.Nil(err, "Error should be nil.")
+
+	var i int
+	assert.Nil(st.ListIndexInto(list, -2, &i), "Error should be nil.")
+	assert.Equal(i, 2, "ListIndexInto should count negative indexes from the tail")
+
+	_, err = st.ListIndex(list, 5)
+	assert.True(errors.Is(err, ErrNotFound), "an index out of range should return ErrNotFound")
+
+	assert.Nil(st.ListSet(list, 0, 10), "Error should be nil.")
+	assert.NotNil(st.ListSet(list, 5, 10), "setting an index out of range should fail")
+	assert.True(errors.Is(st.ListSet("testListMissing", 0, 10), ErrNotFound), "setting in a missing list should return ErrNotFound")
+
+	n, err := st.ListRemove(list, -1, 2)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "ListRemove should remove count elements")
+
+	n, err = st.ListInsert(list, true, 3, 4)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 5, "ListInsert should return the new length")
+
+	_, err = st.ListInsert(list, false, 99, 4)
+	assert.True(errors.Is(err, ErrNotFound), "a missing pivot should return ErrNotFound")
+
+	var got []int
+	assert.Nil(st.ListRangeInto(list, 0, -1, &got), "Error should be nil.")
+	assert.Equal(got, []int{10, 2, 4, 3, 1}, "the list should be modified")
+
+	assert.Nil(st.ListTrim(list, 1, 2), "Error should be nil.")
+	assert.Nil(st.ListRangeInto(list, 0, -1, &got), "Error should be nil.")
+	assert.Equal(got, []int{2, 4}, "ListTrim should keep the range")
+}
@@ -0,0 +1,244 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/denkhaus/go-store/internal/memdb"
+	"github.com/garyburd/redigo/redis"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// MemoryStore is a Store that keeps all data in process. It executes the same commands as a
+// redis server would, so strings, hashes, lists, sets, sorted sets, expiry and enumeration
+// behave exactly like they do with Open. It is safe for concurrent use.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type MemoryStore struct {
+	*Store
+	db *memdb.DB
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// NewMemoryStore returns an empty in-memory store. Of the options only the pool limits,
+// WithDB, WithClientName and WithCodec take effect, network, TLS and authentication options
+// are ignored.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func NewMemoryStore(opts ...Option) *MemoryStore {
+	o := defaultOptions("")
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	o.username, o.password = "", ""
+
+	m := &MemoryStore{db: memdb.New()}
+	m.Store = &Store{
+		Codec: o.codec,
+		Pool: o.newPool(func() (redis.Conn, error) {
+			c := newMemConn(m.db)
+			if err := o.prepare(c); err != nil {
+				c.Close()
+				return nil, err
+			}
+
+			return c, nil
+		}, nil),
+	}
+
+	return m
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// FastForward moves the clock of the store by d. Keys whose time to live passes expire, which
+// allows to test expiry without waiting.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (m *MemoryStore) FastForward(d time.Duration) {
+	m.db.FastForward(d)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// FlushAll removes all keys of all databases.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (m *MemoryStore) FlushAll() {
+	m.db.FlushAll()
+}
+
+var errMemConnClosed = errors.New("store: connection closed")
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// memConn is a redis.Conn executing commands on a memdb session. Sent commands are executed
+// on Flush, Do flushes pending commands and returns their first error like redigo does. Like
+// a network connection it may be closed while a blocking command waits.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type memConn struct {
+	conn    *memdb.Conn
+	sent    [][][]byte
+	replies []interface{}
+	closed  atomic.Bool
+}
+
+func newMemConn(db *memdb.DB) *memConn {
+	return &memConn{conn: db.Conn()}
+}
+
+func (c *memConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		c.conn.Close()
+	}
+
+	return nil
+}
+
+func (c *memConn) Err() error {
+	if c.closed.Load() {
+		return errMemConnClosed
+	}
+
+	return nil
+}
+
+func (c *memConn) Send(cmd string, args ...interface{}) error {
+	if c.closed.Load() {
+		return errMemConnClosed
+	}
+
+	c.sent = append(c.sent, memArgs(cmd, args))
+	return nil
+}
+
+func (c *memConn) Flush() error {
+	if c.closed.Load() {
+		return errMemConnClosed
+	}
+
+	for _, args := range c.sent {
+		c.replies = append(c.replies, memReply(c.conn.Do(args)))
+	}
+
+	c.sent = nil
+	return nil
+}
+
+func (c *memConn) Receive() (interface{}, error) {
+	if len(c.replies) == 0 {
+		if err := c.Flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.replies) == 0 {
+		return nil, errors.New("store: no pending reply")
+	}
+
+	reply := c.replies[0]
+	c.replies = c.replies[1:]
+	if err, isErr := reply.(redis.Error); isErr {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+func (c *memConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cmd != "" {
+		if err := c.Send(cmd, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := c.replies
+	c.replies = nil
+
+	if cmd == "" {
+		return replies, nil
+	}
+
+	var err error
+	for _, reply := range replies {
+		if e, isErr := reply.(redis.Error); isErr && err == nil {
+			err = e
+		}
+	}
+
+	return replies[len(replies)-1], err
+}
+
+func (c *memConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	return c.Do(cmd, args...)
+}
+
+func (c *memConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return c.Receive()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// memArgs formats the command arguments the way redigo writes them to the wire.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func memArgs(cmd string, args []interface{}) [][]byte {
+	res := make([][]byte, 0, len(args)+1)
+	res = append(res, []byte(cmd))
+	for _, arg := range args {
+		res = append(res, memArg(arg))
+	}
+
+	return res
+}
+
+func memArg(arg interface{}) []byte {
+	switch arg := arg.(type) {
+	case string:
+		return []byte(arg)
+	case []byte:
+		return append([]byte(nil), arg...)
+	case int:
+		return strconv.AppendInt(nil, int64(arg), 10)
+	case int64:
+		return strconv.AppendInt(nil, arg, 10)
+	case float64:
+		return strconv.AppendFloat(nil, arg, 'g', -1, 64)
+	case bool:
+		if arg {
+			return []byte("1")
+		}
+
+		return []byte("0")
+	case nil:
+		return []byte{}
+	case redis.Argument:
+		return memArg(arg.RedisArg())
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprint(&buf, arg)
+	return buf.Bytes()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// memReply converts a memdb reply to the types returned by redigo.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func memReply(reply interface{}) interface{} {
+	switch reply := reply.(type) {
+	case memdb.Status:
+		return string(reply)
+	case memdb.Error:
+		return redis.Error(reply)
+	case []byte:
+		return append([]byte(nil), reply...)
+	case []interface{}:
+		res := make([]interface{}, len(reply))
+		for i, r := range reply {
+			res[i] = memReply(r)
+		}
+
+		return res
+	}
+
+	return reply
+}
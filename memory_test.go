@@ -0,0 +1,99 @@
+package store
+
+import (
+	"errors"
+	"github.com/denkhaus/tcgl/asserts"
+	"testing"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestMemoryStoreTTL
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestMemoryStoreTTL(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := NewMemoryStore()
+	defer st.Close()
+
+	assert.Nil(st.SetWithTTL("session", "data", 60), "Error should be nil.")
+
+	st.FastForward(59 * time.Second)
+	val, err := st.Get("session")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "data", "the value should be alive before its ttl passed")
+
+	st.FastForward(time.Second)
+	_, err = st.Get("session")
+	assert.True(errors.Is(err, ErrNotFound), "an expired value should return ErrNotFound")
+
+	assert.Nil(st.SetWithExpiry("precise", "data", 1500*time.Millisecond), "Error should be nil.")
+	assert.Nil(st.SetKeepTTL("precise", "updated"), "Error should be nil.")
+	ok, err := st.SetNX("lock", "owner", 200*time.Microsecond)
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "SetNX should store a missing key")
+
+	st.FastForward(time.Second)
+	val, err = st.Get("precise")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "updated", "SetKeepTTL should update the value")
+
+	_, err = st.Get("lock")
+	assert.True(errors.Is(err, ErrNotFound), "a ttl below a millisecond should be rounded up to one")
+
+	st.FastForward(500 * time.Millisecond)
+	_, err = st.Get("precise")
+	assert.True(errors.Is(err, ErrNotFound), "SetKeepTTL should keep the ttl with millisecond precision")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestMemoryStoreSemantics
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestMemoryStoreSemantics(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := NewMemoryStore()
+	defer st.Close()
+
+	assert.Nil(st.HashSet("hash", "field", 1), "Error should be nil.")
+	_, err := st.Get("hash")
+	assert.True(errors.Is(err, ErrWrongType), "reading a hash as value should return ErrWrongType")
+
+	n, err := st.HashDeleteField("hash", "field")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "one field should be deleted")
+
+	_, err = st.HashGet("hash", "field")
+	assert.True(errors.Is(err, ErrNotFound), "an empty hash should be removed")
+
+	n, err = st.SetSet("set", "a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "a new member should be added")
+
+	n, err = st.SetSet("set", "a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "an existing member should not be added")
+
+	assert.Nil(st.ListPush("list", "key", "value"), "Error should be nil.")
+	_, err = st.SetSet("list", "a")
+	assert.True(errors.Is(err, ErrWrongType), "adding to a list as set should return ErrWrongType")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestMemoryStoreDB
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestMemoryStoreDB(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := NewMemoryStore(WithDB(3))
+	defer st.Close()
+
+	assert.Nil(st.Set("key", "value"), "Error should be nil.")
+
+	_, err := NewMemoryStore(WithDB(3)).Get("key")
+	assert.True(errors.Is(err, ErrNotFound), "stores should not share data")
+
+	st.FlushAll()
+	_, err = st.Get("key")
+	assert.True(errors.Is(err, ErrNotFound), "FlushAll should remove all keys")
+
+	_, err = NewMemoryStore(WithDB(16)).Get("key")
+	assert.ErrorMatch(err, "DB index is out of range", "an invalid database should fail")
+}
@@ -0,0 +1,417 @@
+package store
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"strings"
+	"time"
+)
+
+// Option configures a Store created by Open.
+type Option func(*options)
+
+type options struct {
+	network string
+	address string
+
+	maxIdle     int
+	maxActive   int
+	wait        bool
+	idleTimeout time.Duration
+
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	keepAlive      time.Duration
+
+	useTLS        bool
+	tlsConfig     *tls.Config
+	tlsServerName string
+	tlsCAFile     string
+	tlsCertFile   string
+	tlsKeyFile    string
+	tlsSkipVerify bool
+	tls           *tls.Config
+
+	db         int
+	username   string
+	password   string
+	clientName string
+
+	sentinelMaster   string
+	sentinelAddrs    []string
+	sentinelPassword string
+	replicaReads     bool
+
+	clusterAddrs []string
+
+	codec Codec
+}
+
+func defaultOptions(address string) *options {
+	return &options{
+		network:     "tcp",
+		address:     address,
+		maxIdle:     10,
+		idleTimeout: 240 * time.Second,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithNetwork sets the network to dial, "tcp" (default), "tcp4", "tcp6" or "unix".
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithNetwork(network string) Option {
+	return func(o *options) { o.network = network }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithMaxIdle sets the maximum number of idle connections in the pool. Defaults to 10 and is
+// capped by WithMaxActive.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithMaxIdle(n int) Option {
+	return func(o *options) { o.maxIdle = n }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithMaxActive sets the maximum number of connections allocated by the pool at a given time.
+// Zero means no limit.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithMaxActive(n int) Option {
+	return func(o *options) { o.maxActive = n }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithWait makes the pool wait for a free connection once MaxActive is reached instead of
+// failing with redis.ErrPoolExhausted. Requires WithMaxActive.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithWait(wait bool) Option {
+	return func(o *options) { o.wait = wait }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithIdleTimeout closes connections that stay idle for longer than d. Defaults to 240s,
+// zero disables idle connection closing.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithIdleTimeout(d time.Duration) Option {
+	return func(o *options) { o.idleTimeout = d }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithDialTimeout sets the timeout for establishing a connection.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithDialTimeout(d time.Duration) Option {
+	return func(o *options) { o.connectTimeout = d }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithReadTimeout sets the timeout for reading a single command reply.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithReadTimeout(d time.Duration) Option {
+	return func(o *options) { o.readTimeout = d }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithWriteTimeout sets the timeout for writing a single command.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithWriteTimeout(d time.Duration) Option {
+	return func(o *options) { o.writeTimeout = d }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithKeepAlive sets the TCP keep-alive period. Defaults to the redigo default of 5 minutes.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithKeepAlive(d time.Duration) Option {
+	return func(o *options) { o.keepAlive = d }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithDB selects the redis database index. Defaults to 0.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithDB(db int) Option {
+	return func(o *options) { o.db = db }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithPassword authenticates every connection with the given password.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithPassword(password string) Option {
+	return func(o *options) { o.password = password }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithAuth authenticates every connection as an ACL user (redis 6 and later).
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithAuth(username, password string) Option {
+	return func(o *options) {
+		o.username = username
+		o.password = password
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithClientName sets the connection name reported by CLIENT LIST.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithClientName(name string) Option {
+	return func(o *options) { o.clientName = name }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithCodec sets the Codec of the store. Defaults to DefaultCodec.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithCodec(codec Codec) Option {
+	return func(o *options) { o.codec = codec }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// validate checks the options for consistency.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (o *options) validate() error {
+	switch {
+	case o.address == "" && o.sentinelMaster == "" && o.clusterAddrs == nil:
+		return errors.New("store: address must not be empty")
+	case o.network != "tcp" && o.network != "tcp4" && o.network != "tcp6" && o.network != "unix":
+		return fmt.Errorf("store: unsupported network %q", o.network)
+	case o.maxIdle < 0:
+		return fmt.Errorf("store: max idle connections must not be negative, got %d", o.maxIdle)
+	case o.maxActive < 0:
+		return fmt.Errorf("store: max active connections must not be negative, got %d", o.maxActive)
+	case o.wait && o.maxActive == 0:
+		return errors.New("store: WithWait requires WithMaxActive")
+	case o.idleTimeout < 0, o.connectTimeout < 0, o.readTimeout < 0,
+		o.writeTimeout < 0, o.keepAlive < 0:
+		return errors.New("store: timeouts must not be negative")
+	case o.db < 0:
+		return fmt.Errorf("store: database index must not be negative, got %d", o.db)
+	case o.username != "" && o.password == "":
+		return fmt.Errorf("store: user %q requires a password", o.username)
+	case strings.ContainsAny(o.clientName, " \n"):
+		return fmt.Errorf("store: client name %q must not contain spaces", o.clientName)
+	case o.sentinelMaster != "" && o.network == "unix":
+		return errors.New("store: sentinel requires a tcp network")
+	case o.replicaReads && o.sentinelMaster == "":
+		return errors.New("store: WithReplicaReads requires OpenSentinel")
+	case o.clusterAddrs != nil && o.network == "unix":
+		return errors.New("store: cluster requires a tcp network")
+	case o.clusterAddrs != nil && o.db != 0:
+		return errors.New("store: cluster only supports database 0")
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// dial opens and prepares a single connection.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (o *options) dial() (redis.Conn, error) {
+	return o.dialAddr(o.address)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// dialAddr opens and prepares a single connection to address.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (o *options) dialAddr(address string) (redis.Conn, error) {
+	c, err := o.connect(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := o.prepare(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// connect opens a plain connection to address honouring the dial and TLS settings.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (o *options) connect(address string) (redis.Conn, error) {
+	dialOpts := []redis.DialOption{
+		redis.DialConnectTimeout(o.connectTimeout),
+		redis.DialReadTimeout(o.readTimeout),
+		redis.DialWriteTimeout(o.writeTimeout),
+	}
+
+	if o.keepAlive > 0 {
+		dialOpts = append(dialOpts, redis.DialKeepAlive(o.keepAlive))
+	}
+
+	if o.tls != nil {
+		dialOpts = append(dialOpts, redis.DialUseTLS(true), redis.DialTLSConfig(o.tls))
+	}
+
+	return redis.Dial(o.network, address, dialOpts...)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// prepare authenticates, names and selects the database of a fresh connection.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (o *options) prepare(c redis.Conn) error {
+	if o.password != "" {
+		args := []interface{}{o.password}
+		if o.username != "" {
+			args = []interface{}{o.username, o.password}
+		}
+
+		if _, err := c.Do("AUTH", args...); err != nil {
+			return err
+		}
+	}
+
+	if o.clientName != "" {
+		if _, err := c.Do("CLIENT", "SETNAME", o.clientName); err != nil {
+			return err
+		}
+	}
+
+	if o.db != 0 {
+		if _, err := c.Do("SELECT", o.db); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// pool creates the connection pool for the options.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (o *options) pool() *redis.Pool {
+	return o.newPool(o.dial, func(c redis.Conn, t time.Time) error {
+		_, err := c.Do("PING")
+		return err
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// newPool creates a connection pool with the given dial and borrow test functions.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (o *options) newPool(dial func() (redis.Conn, error), testOnBorrow func(redis.Conn, time.Time) error) *redis.Pool {
+	maxIdle := o.maxIdle
+	if o.maxActive > 0 && maxIdle > o.maxActive {
+		maxIdle = o.maxActive
+	}
+
+	pool := &redis.Pool{
+		MaxIdle:     maxIdle,
+		MaxActive:   o.maxActive,
+		Wait:        o.wait,
+		IdleTimeout: o.idleTimeout,
+		Dial: func() (redis.Conn, error) {
+			c, err := dial()
+			if err != nil {
+				return nil, &dialError{err: err}
+			}
+
+			return &poolConn{Conn: c}, nil
+		},
+	}
+
+	if testOnBorrow != nil {
+		pool.TestOnBorrow = func(c redis.Conn, t time.Time) error {
+			return testOnBorrow(c.(*poolConn).Conn, t)
+		}
+	}
+
+	return pool
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Open returns a Store for the redis server at address, configured by opts.
+// The options are validated and the server is pinged before Open returns.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func Open(address string, opts ...Option) (*Store, error) {
+	o := defaultOptions(address)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return open(o)
+}
+
+func open(o *options) (*Store, error) {
+	st, target, err := newStore(o)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := st.connect(target); err != nil {
+		st.Close()
+		return nil, err
+	}
+
+	return st, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// openUnchecked is Open for the deprecated constructors, which return the store together with
+// the error if the server does not answer the initial ping, as they always did.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func openUnchecked(address string, opts ...Option) (*Store, error) {
+	o := defaultOptions(address)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	st, target, err := newStore(o)
+	if err != nil {
+		return nil, err
+	}
+
+	return st, st.connect(target)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// newStore validates the options and creates the pools of the store, without pinging the
+// server. It returns the store and the description of its target for errors.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func newStore(o *options) (*Store, string, error) {
+	if err := o.validate(); err != nil {
+		return nil, "", err
+	}
+
+	tlsConfig, err := o.buildTLSConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	o.tls = tlsConfig
+
+	st := &Store{Codec: o.codec}
+	target := o.network + " " + o.address
+
+	switch {
+	case o.clusterAddrs != nil:
+		st.cluster = newCluster(o)
+		if err := st.cluster.refresh(); err != nil {
+			st.Close()
+			return nil, "", err
+		}
+
+		target = fmt.Sprintf("cluster %s", strings.Join(o.clusterAddrs, ","))
+	case o.sentinelMaster != "":
+		st.sentinel = newSentinel(o)
+		st.Pool = o.newPool(st.sentinel.dialMaster, st.sentinel.testOnBorrow)
+		if o.replicaReads {
+			st.replicas = o.newPool(st.sentinel.dialReplica, st.sentinel.testOnBorrow)
+		}
+
+		target = fmt.Sprintf("sentinel master %q", o.sentinelMaster)
+	default:
+		st.Pool = o.pool()
+	}
+
+	return st, target, nil
+}
+
+// connect pings the server of the store described by target.
+func (s *Store) connect(target string) error {
+	if _, err := s.ping(); err != nil {
+		return fmt.Errorf("store: connect to %s: %w", target, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"errors"
+	"github.com/denkhaus/tcgl/asserts"
+	"testing"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestOpenValidation
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestOpenValidation(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	invalid := map[string][]Option{
+		"empty address":      nil,
+		"network":            {WithNetwork("udp")},
+		"negative idle":      {WithMaxIdle(-1)},
+		"negative active":    {WithMaxActive(-1)},
+		"wait without limit": {WithWait(true)},
+		"negative timeout":   {WithReadTimeout(-time.Second)},
+		"negative db":        {WithDB(-1)},
+		"user without pass":  {WithAuth("user", "")},
+		"client name":        {WithClientName("my client")},
+	}
+
+	for name, opts := range invalid {
+		addr := "127.0.0.1:0"
+		if name == "empty address" {
+			addr = ""
+		}
+
+		st, err := Open(addr, opts...)
+		assert.Nil(st, "Store should be nil for "+name)
+		assert.NotNil(err, "Error should not be nil for "+name)
+	}
+
+	st, err := NewStoreWithDB(10, "tcp", "127.0.0.1:0", "", "one")
+	assert.Nil(st, "Store should be nil.")
+	assert.ErrorMatch(err, "invalid database index", "NewStoreWithDB should fail on an invalid DB")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestOpenPrepareConnection
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestOpenPrepareConnection(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	srv := newFakeServer(t, okHandler)
+
+	st, err := Open(srv.addr(),
+		WithAuth("user", "secret"),
+		WithClientName("go-store"),
+		WithDB(3),
+		WithMaxActive(2),
+		WithWait(true),
+		WithDialTimeout(time.Second),
+		WithCodec(JSONCodec{}),
+	)
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	assert.Equal(st.Codec, JSONCodec{}, "codec option not applied")
+	assert.Equal(st.Pool.MaxIdle, 2, "max idle should be capped by max active")
+	assert.Equal(srv.received(), [][]string{
+		{"AUTH", "user", "secret"},
+		{"CLIENT", "SETNAME", "go-store"},
+		{"SELECT", "3"},
+		{"PING"},
+	}, "unexpected connection setup")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestOpenUnreachable
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestOpenUnreachable(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	st, err := Open("127.0.0.1:1", WithDialTimeout(time.Second))
+	assert.Nil(st, "Store should be nil.")
+	assert.ErrorMatch(err, "store: connect to tcp 127.0.0.1:1", "Open should fail fast")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestNewStoreUnreachable
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestNewStoreUnreachable(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	st, err := NewStore(1, "tcp", closedAddr(t), "")
+	assert.True(errors.Is(err, ErrConnection), "NewStore should report the failed ping")
+	assert.NotNil(st, "NewStore should return the store nonetheless")
+	defer st.Close()
+
+	_, err = st.Get("testKey1")
+	assert.True(errors.Is(err, ErrConnection), "the store should fail on use")
+
+	st, err = NewStoreWithDB(1, "tcp", closedAddr(t), "", "1")
+	assert.True(errors.Is(err, ErrConnection), "NewStoreWithDB should report the failed ping")
+	assert.NotNil(st, "NewStoreWithDB should return the store nonetheless")
+	st.Close()
+}
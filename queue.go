@@ -0,0 +1,541 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	defaultQueueMaxAttempts       = 5
+	defaultQueueVisibilityTimeout = 30 * time.Second
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// QueueOptions configure a Queue.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type QueueOptions struct {
+	// MaxAttempts is the number of deliveries after which a failed job is moved to the dead
+	// letters, 5 if zero.
+	MaxAttempts int
+
+	// VisibilityTimeout is the time after which the jobs of a consumer that stopped sending
+	// heartbeats are returned to the queue by Reap, 30 seconds if zero.
+	VisibilityTimeout time.Duration
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Queue is a reliable work queue of jobs of type T. Jobs are encoded by the codec of the store
+// and delivered to consumers through an atomic move into a processing list of the consumer,
+// so a job is never lost while it is processed. Acknowledged jobs are deleted, failed ones are
+// retried until they reach the maximum number of attempts and then moved to the dead letters.
+// The jobs of consumers that crashed are returned to the queue by Reap.
+//
+// All keys of a queue share the hash tag name, so a queue works on a cluster.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Queue[T any] struct {
+	st   *Store
+	name string
+	opts QueueOptions
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// NewQueue returns the queue name stored in st. Negative options are replaced by their
+// defaults.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func NewQueue[T any](st *Store, name string, opts QueueOptions) *Queue[T] {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultQueueMaxAttempts
+	}
+
+	if opts.VisibilityTimeout <= 0 {
+		opts.VisibilityTimeout = defaultQueueVisibilityTimeout
+	}
+
+	return &Queue[T]{st: st, name: name, opts: opts}
+}
+
+// Name returns the name of the queue.
+func (q *Queue[T]) Name() string {
+	return q.name
+}
+
+// key returns the key of a part of the queue.
+func (q *Queue[T]) key(part string) string {
+	return TaggedKey(q.name, part)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Job is a job received from a Queue.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Job[T any] struct {
+	ID   string
+	Data T
+
+	// Attempts is the number of deliveries of the job, including this one.
+	Attempts int
+
+	// Raw and Err are only set on dead letters whose data cannot be decoded, Raw holds the
+	// encoded data and Err the decode error. Data is the zero value then.
+	Raw []byte
+	Err error
+
+	consumer *Consumer[T]
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Enqueue adds a job with data value to the queue and returns its id.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) Enqueue(value T) (string, error) {
+	id, err := newJobID()
+	if err != nil {
+		return "", err
+	}
+
+	err = q.st.multi(q.key("jobs"), func(tx *Tx) {
+		tx.HashSet(q.key("jobs"), id, value)
+		tx.ListPushLeft(q.key("pending"), id)
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func newJobID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("store: generate job id: %w", err)
+	}
+
+	return hex.EncodeToString(b[:]), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Len returns the number of jobs waiting to be received.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) Len() (int, error) {
+	return q.st.ListLen(q.key("pending"))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// DeadLetters returns the jobs that failed MaxAttempts times, the most recent first. Attempts
+// of the returned jobs is the number of failed deliveries. Jobs whose data cannot be decoded
+// are returned with Raw and Err set.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) DeadLetters() ([]*Job[T], error) {
+	var ids []string
+	if err := q.st.ListRangeInto(q.key("dead"), 0, -1, &ids); err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job[T], 0, len(ids))
+	for _, id := range ids {
+		job, err := q.load(q.st, id)
+		if errors.Is(err, ErrDecode) {
+			job, err = q.loadRaw(id, err)
+		}
+
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// load reads the data and the number of failed deliveries of the job id.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) load(st Backend, id string) (*Job[T], error) {
+	job := &Job[T]{ID: id}
+	if err := st.HashGetInto(q.key("jobs"), id, &job.Data); err != nil {
+		return nil, err
+	}
+
+	failures, err := q.failures(st, id)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Attempts = failures
+	return job, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// loadRaw reads the encoded data and the number of failed deliveries of the job id, whose data
+// failed to decode with decodeErr.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) loadRaw(id string, decodeErr error) (*Job[T], error) {
+	raw, err := redis.Bytes(q.st.do("HGET", q.key("jobs"), id))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	failures, err := q.failures(q.st, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Job[T]{ID: id, Attempts: failures, Raw: raw, Err: decodeErr}, nil
+}
+
+// failures returns the number of failed deliveries of the job id.
+func (q *Queue[T]) failures(st Backend, id string) (int, error) {
+	var failures int
+	err := st.HashGetInto(q.key("attempts"), id, &failures)
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+
+	return failures, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Consumer returns the consumer name of the queue. Every process or goroutine receiving jobs
+// needs a consumer of its own. A process restarted with the same name receives the jobs it has
+// not finished again, the jobs of a consumer that is not used anymore are returned by Reap.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) Consumer(name string) *Consumer[T] {
+	return &Consumer[T]{
+		q:          q,
+		name:       name,
+		processing: q.key("processing:" + name),
+		delivered:  make(map[string]struct{}),
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Consumer receives jobs from a Queue. A Consumer must not be used concurrently.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Consumer[T any] struct {
+	q          *Queue[T]
+	name       string
+	processing string
+
+	// delivered holds the ids returned by Receive and not finished yet
+	delivered map[string]struct{}
+}
+
+// Name returns the name of the consumer.
+func (c *Consumer[T]) Name() string {
+	return c.name
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Heartbeat marks the consumer alive. Receive sends heartbeats while it waits; a consumer
+// processing a job for longer than the visibility timeout has to call Heartbeat, otherwise
+// its jobs are returned to the queue.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *Consumer[T]) Heartbeat() error {
+	return c.heartbeat(c.q.st)
+}
+
+func (c *Consumer[T]) heartbeat(st *Store) error {
+	_, err := st.SortedSetSet(c.q.key("consumers"), float64(time.Now().UnixMilli()), c.name)
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Receive takes the oldest job from the queue, waiting up to timeout for one to be enqueued. A
+// timeout of 0 waits until ctx is done. ErrNotFound is returned once the timeout has passed
+// and ctx.Err() as soon as ctx is done.
+//
+// The job stays in the processing list of the consumer until it is passed to Ack or Nack.
+// Jobs left in the processing list by a Receive that failed after taking them, or by an earlier
+// process using the same consumer name, are returned first. A job whose data can't be decoded
+// is moved to the dead letters and the *DecodeError is returned.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *Consumer[T]) Receive(ctx context.Context, timeout time.Duration) (*Job[T], error) {
+	if timeout < 0 {
+		return nil, fmt.Errorf("store: invalid timeout %v", timeout)
+	}
+
+	st := c.q.st.WithContext(ctx)
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	if job, err := c.redeliver(st); job != nil || err != nil {
+		return job, err
+	}
+
+	for {
+		if err := c.heartbeat(st); err != nil {
+			return nil, err
+		}
+
+		// wait at most half of the visibility timeout to keep the heartbeat fresh
+		wait := c.q.opts.VisibilityTimeout / 2
+		if !deadline.IsZero() {
+			left := time.Until(deadline)
+			if left <= 0 {
+				return nil, ErrNotFound
+			}
+
+			wait = min(wait, left)
+		}
+
+		wait = max(wait, time.Millisecond)
+
+		var id string
+		err := st.ListBlockingMoveInto(ctx, wait, c.q.key("pending"), c.processing, ListRight, ListLeft, &id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// an error leaves the job in the processing list for redeliver
+		if job, err := c.deliver(st, id); job != nil || err != nil {
+			return job, err
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// redeliver returns the oldest job of the processing list that has not been returned by
+// Receive, nil if there is none.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *Consumer[T]) redeliver(st *Store) (*Job[T], error) {
+	var ids []string
+	if err := st.ListRangeInto(c.processing, 0, -1, &ids); err != nil {
+		return nil, err
+	}
+
+	for i := len(ids) - 1; i >= 0; i-- {
+		if _, delivered := c.delivered[ids[i]]; delivered {
+			continue
+		}
+
+		if job, err := c.deliver(st, ids[i]); job != nil || err != nil {
+			return job, err
+		}
+	}
+
+	return nil, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// deliver loads the job id of the processing list. A job whose data is gone is dropped and nil
+// is returned.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *Consumer[T]) deliver(st *Store, id string) (*Job[T], error) {
+	job, err := c.q.load(st, id)
+	switch {
+	case err == nil:
+		job.Attempts++
+		job.consumer = c
+		c.delivered[id] = struct{}{}
+		return job, nil
+	case errors.Is(err, ErrNotFound):
+		_, err := st.ListRemove(c.processing, 1, id)
+		return nil, err
+	case errors.Is(err, ErrDecode):
+		if err := st.multi(c.processing, func(tx *Tx) {
+			tx.ListRemove(c.processing, 1, id)
+			tx.ListPushLeft(c.q.key("dead"), id)
+		}); err != nil {
+			return nil, err
+		}
+
+		return nil, err
+	}
+
+	return nil, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Ack finishes a job and deletes it. ErrNotFound is returned if the job is no longer processed
+// by the consumer, because it has been returned to the queue by Reap.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *Consumer[T]) Ack(job *Job[T]) error {
+	return c.finish(job, func(tx *Tx) {
+		tx.HashDeleteField(c.q.key("jobs"), job.ID)
+		tx.HashDeleteField(c.q.key("attempts"), job.ID)
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Nack fails a job. It is enqueued again unless it has been delivered MaxAttempts times, then
+// it is moved to the dead letters. ErrNotFound is returned if the job is no longer processed by
+// the consumer, see Ack.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *Consumer[T]) Nack(job *Job[T]) error {
+	return c.finish(job, func(tx *Tx) {
+		c.q.fail(tx, job.ID, job.Attempts, false)
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// finish removes job from the processing list of the consumer and queues the writes of fn in
+// the same transaction.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (c *Consumer[T]) finish(job *Job[T], fn func(tx *Tx)) error {
+	if job.consumer != c {
+		return errors.New("store: job has not been received by this consumer")
+	}
+
+	err := c.q.st.Tx([]string{c.processing}, func(tx *Tx) error {
+		var ids []string
+		if err := tx.ListRangeInto(c.processing, 0, -1, &ids); err != nil {
+			return err
+		}
+
+		if !slices.Contains(ids, job.ID) {
+			return ErrNotFound
+		}
+
+		tx.ListRemove(c.processing, 1, job.ID)
+		fn(tx)
+		return nil
+	})
+
+	if err == nil || errors.Is(err, ErrNotFound) {
+		delete(c.delivered, job.ID)
+	}
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// fail queues the writes recording a failed delivery of the job id, which has failed failures
+// times including this one. Retried jobs are enqueued at the back, or at the front if front is
+// set, so they are received next.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) fail(tx *Tx, id string, failures int, front bool) {
+	tx.HashIncrBy(q.key("attempts"), id, 1)
+
+	switch {
+	case failures >= q.opts.MaxAttempts:
+		tx.ListPushLeft(q.key("dead"), id)
+	case front:
+		tx.ListPushRight(q.key("pending"), id)
+	default:
+		tx.ListPushLeft(q.key("pending"), id)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Reap returns the jobs of consumers without a heartbeat for longer than the visibility timeout
+// to the front of the queue and returns their number. A returned job counts as failed
+// delivery, so jobs that crash their consumers end up in the dead letters. Reap is safe to
+// call from many processes at once.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) Reap() (int, error) {
+	cutoff := float64(time.Now().Add(-q.opts.VisibilityTimeout).UnixMilli())
+
+	var stale []string
+	if err := q.st.SortedSetGetAscInto(q.key("consumers"), math.Inf(-1), cutoff, &stale); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, name := range stale {
+		n, err := q.reapConsumer(name, cutoff)
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+	}
+
+	return total, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// reapConsumer returns the jobs of the consumer name, if it is still stale at cutoff, and
+// removes the consumer.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) reapConsumer(name string, cutoff float64) (int, error) {
+	consumers := q.key("consumers")
+	processing := q.Consumer(name).processing
+
+	var n int
+	err := q.st.Tx([]string{consumers, processing}, func(tx *Tx) error {
+		var stale []string
+		if err := tx.SortedSetGetAscInto(consumers, math.Inf(-1), cutoff, &stale); err != nil {
+			return err
+		}
+
+		n = 0
+		if !slices.Contains(stale, name) {
+			return nil
+		}
+
+		var ids []string
+		if err := tx.ListRangeInto(processing, 0, -1, &ids); err != nil {
+			return err
+		}
+
+		failures := make([]int, len(ids))
+		for i, id := range ids {
+			f, err := q.failures(tx.st, id)
+			if err != nil {
+				return err
+			}
+
+			failures[i] = f + 1
+		}
+
+		// the processing list holds the most recent job first, the oldest is pushed last to
+		// be received first
+		for i, id := range ids {
+			q.fail(tx, id, failures[i], true)
+		}
+
+		tx.Delete(processing)
+		tx.batch.queueValue(consumers, "", name, "ZREM", consumers)
+		n = len(ids)
+		return nil
+	})
+
+	return n, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// RunReaper calls Reap every interval until ctx is done and returns ctx.Err() or the first
+// error of Reap.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) RunReaper(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("store: invalid reaper interval %v", interval)
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+
+		if _, err := q.Reap(); err != nil {
+			return err
+		}
+	}
+}
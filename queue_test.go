@@ -0,0 +1,219 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"github.com/denkhaus/tcgl/asserts"
+	"testing"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestQueue
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestQueue(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	ctx := context.Background()
+	q := NewQueue[codecTestItem](st, "testQueue", QueueOptions{MaxAttempts: 2})
+	assert.Nil(st.Delete(q.key("pending"), q.key("jobs"), q.key("attempts"), q.key("dead"),
+		q.key("consumers"), q.key("processing:c1"), q.key("processing:c2")), "Error should be nil.")
+
+	id1, err := q.Enqueue(codecTestItem{Name: "one", Count: 1})
+	assert.Nil(err, "Error should be nil.")
+	id2, err := q.Enqueue(codecTestItem{Name: "two", Count: 2})
+	assert.Nil(err, "Error should be nil.")
+	assert.Different(id1, id2, "Enqueue should return unique ids")
+
+	n, err := q.Len()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "Len should count the pending jobs")
+
+	c1 := q.Consumer("c1")
+	job, err := c1.Receive(ctx, time.Second)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(job.ID, id1, "Receive should return the oldest job")
+	assert.Equal(job.Data.Name, "one", "Receive should decode the job")
+	assert.Equal(job.Attempts, 1, "the first delivery should be attempt 1")
+	assert.Nil(c1.Ack(job), "Error should be nil.")
+	assert.True(errors.Is(c1.Ack(job), ErrNotFound), "a second Ack should return ErrNotFound")
+
+	c2 := q.Consumer("c2")
+	assert.NotNil(c2.Ack(job), "Ack of a job of another consumer should fail")
+
+	job, err = c1.Receive(ctx, time.Second)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(job.ID, id2, "Receive should return the next job")
+	assert.Nil(c1.Nack(job), "Error should be nil.")
+
+	job, err = c2.Receive(ctx, time.Second)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(job.ID, id2, "Nack should enqueue the job again")
+	assert.Equal(job.Attempts, 2, "the retry should be attempt 2")
+	assert.Nil(c2.Nack(job), "Error should be nil.")
+
+	_, err = c2.Receive(ctx, 50*time.Millisecond)
+	assert.True(errors.Is(err, ErrNotFound), "a timeout should return ErrNotFound")
+
+	dead, err := q.DeadLetters()
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(dead, 1, "the job should be a dead letter after MaxAttempts")
+	assert.Equal(dead[0].ID, id2, "DeadLetters should return the failed job")
+	assert.Equal(dead[0].Data.Count, 2, "DeadLetters should decode the job")
+	assert.Equal(dead[0].Attempts, 2, "DeadLetters should report the failed deliveries")
+
+	res := make(chan *Job[codecTestItem], 1)
+	go func() {
+		job, err := c1.Receive(ctx, 0)
+		assert.Nil(err, "Error should be nil.")
+		res <- job
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	id3, err := q.Enqueue(codecTestItem{Name: "three"})
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal((<-res).ID, id3, "Receive should wait for a job")
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c1.Receive(cctx, 0)
+	assert.True(errors.Is(err, context.Canceled), "a cancelled context should end Receive")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestQueueReap
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestQueueReap(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	ctx := context.Background()
+	q := NewQueue[string](st, "testQueueReap", QueueOptions{MaxAttempts: 2, VisibilityTimeout: 100 * time.Millisecond})
+	assert.Nil(st.Delete(q.key("pending"), q.key("jobs"), q.key("attempts"), q.key("dead"),
+		q.key("consumers"), q.key("processing:crashed"), q.key("processing:alive")), "Error should be nil.")
+
+	id1, err := q.Enqueue("one")
+	assert.Nil(err, "Error should be nil.")
+	id2, err := q.Enqueue("two")
+	assert.Nil(err, "Error should be nil.")
+
+	crashed := q.Consumer("crashed")
+	job1, err := crashed.Receive(ctx, time.Second)
+	assert.Nil(err, "Error should be nil.")
+	_, err = crashed.Receive(ctx, time.Second)
+	assert.Nil(err, "Error should be nil.")
+
+	n, err := q.Reap()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "Reap should keep the jobs of live consumers")
+
+	time.Sleep(150 * time.Millisecond)
+	alive := q.Consumer("alive")
+	assert.Nil(alive.Heartbeat(), "Error should be nil.")
+
+	n, err = q.Reap()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "Reap should return the jobs of stale consumers")
+	assert.True(errors.Is(crashed.Ack(job1), ErrNotFound), "Ack of a reaped job should return ErrNotFound")
+
+	job, err := alive.Receive(ctx, time.Second)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(job.ID, id1, "reaped jobs should keep their order")
+	assert.Equal(job.Attempts, 2, "a reaped job should count as failed delivery")
+	assert.Nil(alive.Ack(job), "Error should be nil.")
+
+	job, err = alive.Receive(ctx, time.Second)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(job.ID, id2, "reaped jobs should keep their order")
+
+	rctx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancel()
+	err = q.RunReaper(rctx, 20*time.Millisecond)
+	assert.True(errors.Is(err, context.DeadlineExceeded), "RunReaper should run until ctx is done")
+
+	dead, err := q.DeadLetters()
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(dead, 1, "a job reaped MaxAttempts times should be a dead letter")
+	assert.Equal(dead[0].Data, "two", "DeadLetters should decode the job")
+
+	n, err = q.Len()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "the dead letter should not be enqueued again")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestQueueRedeliver
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestQueueRedeliver(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	ctx := context.Background()
+	q := NewQueue[string](st, "testQueueRedeliver", QueueOptions{})
+	c := q.Consumer("c")
+	assert.Nil(st.Delete(q.key("pending"), q.key("jobs"), q.key("attempts"), q.key("consumers"),
+		c.processing), "Error should be nil.")
+
+	id1, err := q.Enqueue("one")
+	assert.Nil(err, "Error should be nil.")
+	id2, err := q.Enqueue("two")
+	assert.Nil(err, "Error should be nil.")
+
+	// A Receive failing after the move leaves the job in the processing list.
+	_, err = st.ListMove(q.key("pending"), c.processing, ListRight, ListLeft)
+	assert.Nil(err, "Error should be nil.")
+
+	job, err := c.Receive(ctx, 50*time.Millisecond)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(job.ID, id1, "Receive should redeliver a job left in the processing list")
+	assert.Equal(job.Attempts, 1, "a redelivered job should not count as failed delivery")
+
+	second, err := c.Receive(ctx, 50*time.Millisecond)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(second.ID, id2, "Receive should not redeliver a job being processed")
+
+	restarted := q.Consumer("c")
+	job, err = restarted.Receive(ctx, 50*time.Millisecond)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(job.ID, id1, "a restarted consumer should receive its unfinished jobs")
+	assert.Nil(restarted.Ack(job), "Error should be nil.")
+	assert.Nil(c.Ack(second), "Error should be nil.")
+
+	_, err = c.Receive(ctx, 50*time.Millisecond)
+	assert.True(errors.Is(err, ErrNotFound), "finished jobs should not be redelivered")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestQueueUndecodable
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestQueueUndecodable(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	ctx := context.Background()
+	q := NewQueue[int](st, "testQueueUndecodable", QueueOptions{})
+	c := q.Consumer("c")
+	assert.Nil(st.Delete(q.key("pending"), q.key("jobs"), q.key("attempts"), q.key("dead"),
+		q.key("consumers"), c.processing), "Error should be nil.")
+
+	id, err := NewQueue[string](st, q.Name(), QueueOptions{}).Enqueue("not a number")
+	assert.Nil(err, "Error should be nil.")
+
+	_, err = c.Receive(ctx, 50*time.Millisecond)
+	assert.True(errors.Is(err, ErrDecode), "Receive should report the decode error")
+
+	dead, err := q.DeadLetters()
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(dead, 1, "an undecodable job should be a dead letter")
+	assert.Equal(dead[0].ID, id, "DeadLetters should return the undecodable job")
+	assert.True(errors.Is(dead[0].Err, ErrDecode), "DeadLetters should report the decode error")
+
+	var data string
+	assert.Nil(st.codec().Unmarshal(dead[0].Raw, &data), "Error should be nil.")
+	assert.Equal(data, "not a number", "DeadLetters should return the encoded data")
+}
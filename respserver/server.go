@@ -0,0 +1,328 @@
+// Package respserver provides a RESP2 server backed by in-process data structures. It
+// executes the commands of go-store with the semantics of redis and is meant for hermetic
+// integration tests.
+//
+//	srv, err := respserver.Start()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer srv.Close()
+//
+//	st, err := store.Open(srv.Addr())
+package respserver
+
+import (
+	"bufio"
+	"errors"
+	"github.com/denkhaus/go-store/internal/memdb"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBulkLen is the largest bulk string accepted, like proto-max-bulk-len of redis.
+const maxBulkLen = 512 << 20
+
+var errProtocol = errors.New("ERR Protocol error")
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Server is a RESP2 server. All connections share the same databases.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Server struct {
+	ln net.Listener
+	db *memdb.DB
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Start starts a server on a random port of the loopback interface.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func Start() (*Server, error) {
+	return Listen("tcp", "127.0.0.1:0")
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Listen starts a server listening on the given network and address, see net.Listen.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func Listen(network, address string) (*Server, error) {
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return Serve(ln), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Serve starts a server accepting connections from ln. The server owns ln and closes it on
+// Close.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func Serve(ln net.Listener) *Server {
+	s := &Server{
+		ln:    ln,
+		db:    memdb.New(),
+		conns: make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.accept()
+	return s
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Addr returns the address the server listens on.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// URL returns a connection URL for the server, see store.OpenURL.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Server) URL() string {
+	if s.ln.Addr().Network() == "unix" {
+		return "unix://" + s.Addr()
+	}
+
+	return "redis://" + s.Addr()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// FastForward moves the clock of the server by d, expiring keys whose time to live passes.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Server) FastForward(d time.Duration) {
+	s.db.FastForward(d)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// FlushAll removes all keys of all databases.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Server) FlushAll() {
+	s.db.FlushAll()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Close stops the server, closes all client connections and waits for them to finish.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.closed = true
+	err := s.ln.Close()
+	for c := range s.conns {
+		c.Close()
+	}
+
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go s.serve(c)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// serve executes the commands of a client. Replies are buffered while pipelined commands are
+// pending and flushed once the client waits.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Server) serve(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+
+	r := bufio.NewReader(c)
+	w := bufio.NewWriter(c)
+	session := s.db.Conn()
+	defer session.Close()
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if err == errProtocol {
+				writeReply(w, memdb.Error(errProtocol.Error()))
+				w.Flush()
+			}
+
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := strings.EqualFold(string(args[0]), "QUIT")
+		switch {
+		case quit:
+			writeReply(w, memdb.Status("OK"))
+		case memdb.Blocking(args):
+			writeReply(w, doBlocking(c, r, session, args))
+		default:
+			writeReply(w, session.Do(args))
+		}
+
+		if quit || r.Buffered() == 0 {
+			if err := w.Flush(); err != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// doBlocking executes a blocking command while watching the client. A client disconnecting
+// closes the session and ends the command like redis does, so that it does not consume an
+// element nobody is waiting for.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func doBlocking(c net.Conn, r *bufio.Reader, session *memdb.Conn, args [][]byte) interface{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		if _, err := r.Peek(1); err != nil {
+			if nerr, isNet := err.(net.Error); !isNet || !nerr.Timeout() {
+				session.Close()
+			}
+		}
+	}()
+
+	reply := session.Do(args)
+
+	// Interrupt the watch before the reader is used again.
+	c.SetReadDeadline(time.Now())
+	<-done
+	c.SetReadDeadline(time.Time{})
+
+	return reply
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// readCommand reads a multi bulk request or an inline command.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func readCommand(r *bufio.Reader) ([][]byte, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 || line[0] != '*' {
+		var args [][]byte
+		for _, field := range strings.Fields(string(line)) {
+			args = append(args, []byte(field))
+		}
+
+		return args, nil
+	}
+
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n > 1024*1024 {
+		return nil, errProtocol
+	}
+
+	// like redis, an empty or null multi bulk is ignored
+	if n <= 0 {
+		return nil, nil
+	}
+
+	args := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol
+		}
+
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil || size < 0 || size > maxBulkLen {
+			return nil, errProtocol
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+
+		args = append(args, buf[:size])
+	}
+
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, errProtocol
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	line = line[:len(line)-1]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+
+	return line, nil
+}
+
+func writeReply(w *bufio.Writer, reply interface{}) {
+	switch reply := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case memdb.Status:
+		w.WriteString("+" + string(reply) + "\r\n")
+	case memdb.Error:
+		w.WriteString("-" + string(reply) + "\r\n")
+	case int64:
+		w.WriteString(":" + strconv.FormatInt(reply, 10) + "\r\n")
+	case []byte:
+		w.WriteString("$" + strconv.Itoa(len(reply)) + "\r\n")
+		w.Write(reply)
+		w.WriteString("\r\n")
+	case []interface{}:
+		w.WriteString("*" + strconv.Itoa(len(reply)) + "\r\n")
+		for _, r := range reply {
+			writeReply(w, r)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package respserver
+
+import (
+	"bufio"
+	"github.com/denkhaus/tcgl/asserts"
+	"github.com/garyburd/redigo/redis"
+	"net"
+	"testing"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestServer
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestServer(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	srv, err := Start()
+	assert.Nil(err, "Error should be nil.")
+	defer srv.Close()
+
+	c, err := redis.Dial("tcp", srv.Addr())
+	assert.Nil(err, "Error should be nil.")
+	defer c.Close()
+
+	res, err := redis.String(c.Do("SET", "key", "value", "EX", 10))
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(res, "OK", "SET should return OK")
+
+	val, err := redis.String(c.Do("GET", "key"))
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "value", "GET should return the value")
+
+	_, err = c.Do("HGET", "key", "field")
+	assert.ErrorMatch(err, "^WRONGTYPE", "HGET on a string should fail")
+
+	_, err = redis.String(c.Do("GET", "missing"))
+	assert.Equal(err, redis.ErrNil, "GET of a missing key should return nil")
+
+	n, err := redis.Int(c.Do("ZADD", "zset", 1, "a", 2, "b"))
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "ZADD should add 2 members")
+
+	members, err := redis.Strings(c.Do("ZRANGEBYSCORE", "zset", "-inf", "+inf", "WITHSCORES"))
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(members, []string{"a", "1", "b", "2"}, "ZRANGEBYSCORE should return members and scores")
+
+	srv.FastForward(10 * time.Second)
+	_, err = redis.String(c.Do("GET", "key"))
+	assert.Equal(err, redis.ErrNil, "an expired key should be gone")
+
+	_, err = c.Do("NOSUCHCOMMAND")
+	assert.ErrorMatch(err, "unknown command", "an unknown command should fail")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestServerPipeline
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestServerPipeline(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	srv, err := Start()
+	assert.Nil(err, "Error should be nil.")
+	defer srv.Close()
+
+	c, err := redis.Dial("tcp", srv.Addr(), redis.DialDatabase(2))
+	assert.Nil(err, "Error should be nil.")
+	defer c.Close()
+
+	for i := 0; i < 100; i++ {
+		assert.Nil(c.Send("RPUSH", "list", i), "Error should be nil.")
+	}
+
+	assert.Nil(c.Flush(), "Error should be nil.")
+	for i := 0; i < 100; i++ {
+		n, err := redis.Int(c.Receive())
+		assert.Nil(err, "Error should be nil.")
+		assert.Equal(n, i+1, "RPUSH should return the length")
+	}
+
+	other, err := redis.Dial("tcp", srv.Addr())
+	assert.Nil(err, "Error should be nil.")
+	defer other.Close()
+
+	n, err := redis.Int(other.Do("LLEN", "list"))
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "databases should be separated")
+
+	n, err = redis.Int(other.Do("DBSIZE"))
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "database 0 should be empty")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestServerInline
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestServerInline(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	srv, err := Start()
+	assert.Nil(err, "Error should be nil.")
+	defer srv.Close()
+
+	c, err := net.Dial("tcp", srv.Addr())
+	assert.Nil(err, "Error should be nil.")
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+	for _, tc := range []struct{ cmd, reply string }{
+		{"PING\r\n", "+PONG\r\n"},
+		{"SET key value\r\n", "+OK\r\n"},
+		{"*-1\r\n*0\r\nPING\r\n", "+PONG\r\n"},
+		{"*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n", "$5\r\n"},
+		{"", "value\r\n"},
+		{"*1\r\n$x\r\n", "-ERR Protocol error\r\n"},
+	} {
+		_, err := c.Write([]byte(tc.cmd))
+		assert.Nil(err, "Error should be nil.")
+
+		line, err := r.ReadString('\n')
+		assert.Nil(err, "Error should be nil.")
+		assert.Equal(line, tc.reply, "unexpected reply to "+tc.cmd)
+	}
+
+	_, err = r.ReadString('\n')
+	assert.NotNil(err, "a protocol error should close the connection")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestServerBlocking
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestServerBlocking(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	srv, err := Start()
+	assert.Nil(err, "Error should be nil.")
+	defer srv.Close()
+
+	c, err := redis.Dial("tcp", srv.Addr())
+	assert.Nil(err, "Error should be nil.")
+	defer c.Close()
+
+	consumer, err := redis.Dial("tcp", srv.Addr())
+	assert.Nil(err, "Error should be nil.")
+	defer consumer.Close()
+
+	reply := make(chan []string, 1)
+	go func() {
+		res, _ := redis.Strings(consumer.Do("BLPOP", "list", 0))
+		reply <- res
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	_, err = c.Do("RPUSH", "list", "a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(<-reply, []string{"list", "a"}, "BLPOP should return the pushed element")
+
+	_, err = consumer.Do("PING")
+	assert.Nil(err, "a connection should be usable after a blocking command")
+
+	gone, err := net.Dial("tcp", srv.Addr())
+	assert.Nil(err, "Error should be nil.")
+	_, err = gone.Write([]byte("BLPOP list 0\r\n"))
+	assert.Nil(err, "Error should be nil.")
+
+	time.Sleep(50 * time.Millisecond)
+	gone.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	_, err = c.Do("RPUSH", "list", "b")
+	assert.Nil(err, "Error should be nil.")
+	n, err := redis.Int(c.Do("LLEN", "list"))
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "a disconnected client should not pop elements")
+
+	go consumer.Do("BLPOP", "missing", 0)
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(srv.Close(), "Close should end blocking commands")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestServerClose
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestServerClose(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	srv, err := Start()
+	assert.Nil(err, "Error should be nil.")
+
+	c, err := redis.Dial("tcp", srv.Addr())
+	assert.Nil(err, "Error should be nil.")
+	defer c.Close()
+
+	_, err = c.Do("PING")
+	assert.Nil(err, "Error should be nil.")
+
+	assert.Nil(srv.Close(), "Error should be nil.")
+	assert.Nil(srv.Close(), "closing twice should not fail")
+
+	_, err = c.Do("PING")
+	assert.NotNil(err, "Close should close client connections")
+
+	_, err = redis.Dial("tcp", srv.Addr())
+	assert.NotNil(err, "a closed server should not accept connections")
+}
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"time"
+)
+
+// schedulePromoteBatch is the number of due jobs moved by a single run of promoteScript.
+const schedulePromoteBatch = 100
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// promoteScript moves up to ARGV[2] jobs of the schedule KEYS[1] due at ARGV[1] to the pending
+// list KEYS[2], the earliest first, and returns their number.
+////////////////////////////////////////////////////////////////////////////////////////////////
+var promoteScript = newScript(2, `
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, id in ipairs(due) do
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('LPUSH', KEYS[2], id)
+end
+return #due
+`, func(call func(args ...interface{}) interface{}, keys, argv [][]byte) interface{} {
+	reply := call("ZRANGEBYSCORE", keys[0], "-inf", argv[0], "LIMIT", 0, argv[1])
+	due, isList := reply.([]interface{})
+	if !isList {
+		return reply
+	}
+
+	for _, id := range due {
+		call("ZREM", keys[0], id)
+		call("LPUSH", keys[1], id)
+	}
+
+	return int64(len(due))
+})
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// cancelScript removes the member ARGV[1] from the schedule KEYS[1] and, if it has been
+// scheduled, the job data ARGV[2] from the hash KEYS[2]. It returns 1 if the job has been
+// scheduled.
+////////////////////////////////////////////////////////////////////////////////////////////////
+var cancelScript = newScript(2, `
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[2], ARGV[2])
+return 1
+`, func(call func(args ...interface{}) interface{}, keys, argv [][]byte) interface{} {
+	if reply := call("ZREM", keys[0], argv[0]); reply != int64(1) {
+		return reply
+	}
+
+	call("HDEL", keys[1], argv[1])
+	return int64(1)
+})
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// rescheduleScript sets the score of the member ARGV[1] of the schedule KEYS[1] to ARGV[2] if
+// it is still scheduled and returns 1 in that case.
+////////////////////////////////////////////////////////////////////////////////////////////////
+var rescheduleScript = newScript(1, `
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+return 1
+`, func(call func(args ...interface{}) interface{}, keys, argv [][]byte) interface{} {
+	if call("ZSCORE", keys[0], argv[0]) == nil {
+		return int64(0)
+	}
+
+	call("ZADD", keys[0], argv[1], argv[0])
+	return int64(1)
+})
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// ScheduledJob is a job of a Queue waiting for its due time.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type ScheduledJob[T any] struct {
+	ID   string
+	Data T
+	Due  time.Time
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Schedule adds a job with data value that is enqueued once due has passed and returns its
+// id. Scheduled jobs are kept in a sorted set with the due time as score and enqueued by
+// PromoteDue, so due is compared with the clock of the process calling it.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) Schedule(value T, due time.Time) (string, error) {
+	id, err := newJobID()
+	if err != nil {
+		return "", err
+	}
+
+	err = q.st.multi(q.key("jobs"), func(tx *Tx) {
+		tx.HashSet(q.key("jobs"), id, value)
+		tx.SortedSetSet(q.key("scheduled"), float64(due.UnixMilli()), id)
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Cancel removes the scheduled job id. ErrNotFound is returned if the job is not scheduled,
+// also if it has been enqueued already.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) Cancel(id string) error {
+	member, err := q.st.encode(id)
+	if err != nil {
+		return err
+	}
+
+	n, err := redis.Int(q.st.eval(cancelScript, q.key("scheduled"), q.key("jobs"), member, id))
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Reschedule moves the due time of the scheduled job id. ErrNotFound is returned if the job is
+// not scheduled, see Cancel.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) Reschedule(id string, due time.Time) error {
+	member, err := q.st.encode(id)
+	if err != nil {
+		return err
+	}
+
+	n, err := redis.Int(q.st.eval(rescheduleScript, q.key("scheduled"), member, due.UnixMilli()))
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Upcoming returns up to limit scheduled jobs, the earliest first, all of them if limit is
+// zero.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) Upcoming(limit int) ([]*ScheduledJob[T], error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("store: invalid limit %d", limit)
+	}
+
+	scheduled := q.key("scheduled")
+	vals, err := redis.Values(q.st.do("ZRANGE", scheduled, 0, limit-1, "WITHSCORES"))
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*ScheduledJob[T], 0, len(vals)/2)
+	for i := 0; i+1 < len(vals); i += 2 {
+		job := &ScheduledJob[T]{}
+		if err := q.st.decode(scheduled, "", vals[i], &job.ID); err != nil {
+			return nil, err
+		}
+
+		due, err := redis.Float64(vals[i+1], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		err = q.st.HashGetInto(q.key("jobs"), job.ID, &job.Data)
+		if errors.Is(err, ErrNotFound) {
+			// cancelled or enqueued and finished meanwhile
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		job.Due = time.UnixMilli(int64(due))
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// PromoteDue enqueues all scheduled jobs whose due time has passed and returns their number.
+// Jobs are moved atomically by a Lua script, so many processes may call PromoteDue at once
+// and every job is enqueued exactly once.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) PromoteDue() (int, error) {
+	now := time.Now().UnixMilli()
+
+	total := 0
+	for {
+		n, err := redis.Int(q.st.eval(promoteScript, q.key("scheduled"), q.key("pending"), now, schedulePromoteBatch))
+		total += n
+		if err != nil || n < schedulePromoteBatch {
+			return total, err
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// RunScheduler calls PromoteDue every interval until ctx is done and returns ctx.Err() or the
+// first error of PromoteDue.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (q *Queue[T]) RunScheduler(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("store: invalid scheduler interval %v", interval)
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+
+		if _, err := q.PromoteDue(); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"github.com/denkhaus/tcgl/asserts"
+	"sync"
+	"testing"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestQueueSchedule
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestQueueSchedule(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	ctx := context.Background()
+	q := NewQueue[codecTestItem](st, "testSchedule", QueueOptions{})
+	assert.Nil(st.Delete(q.key("pending"), q.key("jobs"), q.key("scheduled"), q.key("consumers"),
+		q.key("processing:c")), "Error should be nil.")
+
+	now := time.Now()
+	later, err := q.Schedule(codecTestItem{Name: "later"}, now.Add(time.Hour))
+	assert.Nil(err, "Error should be nil.")
+	soon, err := q.Schedule(codecTestItem{Name: "soon"}, now.Add(time.Minute))
+	assert.Nil(err, "Error should be nil.")
+	cancelled, err := q.Schedule(codecTestItem{Name: "cancelled"}, now.Add(time.Minute))
+	assert.Nil(err, "Error should be nil.")
+
+	assert.Nil(q.Cancel(cancelled), "Error should be nil.")
+	assert.True(errors.Is(q.Cancel(cancelled), ErrNotFound), "a second Cancel should return ErrNotFound")
+
+	upcoming, err := q.Upcoming(0)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(upcoming, 2, "Upcoming should return the scheduled jobs")
+	assert.Equal(upcoming[0].ID, soon, "Upcoming should return the earliest job first")
+	assert.Equal(upcoming[0].Data.Name, "soon", "Upcoming should decode the job")
+	assert.Equal(upcoming[0].Due.UnixMilli(), now.Add(time.Minute).UnixMilli(), "Upcoming should return the due time")
+
+	n, err := q.PromoteDue()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "PromoteDue should not enqueue jobs before their time")
+
+	assert.Nil(q.Reschedule(later, now.Add(-time.Second)), "Error should be nil.")
+	assert.Nil(q.Reschedule(soon, now.Add(-time.Minute)), "Error should be nil.")
+	assert.True(errors.Is(q.Reschedule(cancelled, now), ErrNotFound), "Reschedule of a cancelled job should return ErrNotFound")
+
+	upcoming, err = q.Upcoming(1)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(upcoming, 1, "Upcoming should respect the limit")
+	assert.Equal(upcoming[0].ID, soon, "Reschedule should move the due time")
+
+	n, err = q.PromoteDue()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "PromoteDue should enqueue the due jobs")
+	assert.True(errors.Is(q.Cancel(soon), ErrNotFound), "Cancel of an enqueued job should return ErrNotFound")
+
+	c := q.Consumer("c")
+	job, err := c.Receive(ctx, time.Second)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(job.ID, soon, "the earliest due job should be received first")
+	assert.Nil(c.Ack(job), "Error should be nil.")
+
+	job, err = c.Receive(ctx, time.Second)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(job.Data.Name, "later", "the rescheduled job should be received")
+	assert.Nil(c.Ack(job), "Error should be nil.")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestQueueSchedulePollers
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestQueueSchedulePollers(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	q := NewQueue[int](st, "testSchedulePollers", QueueOptions{})
+	assert.Nil(st.Delete(q.key("pending"), q.key("jobs"), q.key("scheduled")), "Error should be nil.")
+
+	const jobs = 250
+	for i := 0; i < jobs; i++ {
+		_, err := q.Schedule(i, time.Now().Add(-time.Second))
+		assert.Nil(err, "Error should be nil.")
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total int
+	)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, err := q.PromoteDue()
+			assert.Nil(err, "Error should be nil.")
+
+			mu.Lock()
+			total += n
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	assert.Equal(total, jobs, "concurrent pollers should enqueue every job once")
+
+	n, err := q.Len()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, jobs, "every job should be pending once")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.True(errors.Is(q.RunScheduler(ctx, 10*time.Millisecond), context.DeadlineExceeded), "RunScheduler should run until ctx is done")
+}
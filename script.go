@@ -0,0 +1,50 @@
+package store
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"github.com/denkhaus/go-store/internal/memdb"
+	"github.com/garyburd/redigo/redis"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// script is a Lua script run with EVALSHA, which falls back to EVAL if the server does not know
+// the script yet. The in-memory engine does not interpret Lua, it runs the Go implementation
+// registered by newScript instead. Both have to be changed together, TestScripts checks that
+// they agree.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type script struct {
+	keys int
+	src  string
+	hash string
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// newScript returns the script src taking keys keys, implemented by emulate for the in-memory
+// engine. It must be called during initialization.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func newScript(keys int, src string, emulate memdb.ScriptFunc) *script {
+	memdb.RegisterScript(src, emulate)
+
+	sum := sha1.Sum([]byte(src))
+	return &script{keys: keys, src: src, hash: hex.EncodeToString(sum[:])}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// eval runs the script with keysAndArgs, the keys of the script first. On a cluster the script
+// is routed by its keys, which must map to the same slot.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) eval(sc *script, keysAndArgs ...interface{}) (interface{}, error) {
+	args := append([]interface{}{sc.hash, sc.keys}, keysAndArgs...)
+	reply, err := s.do("EVALSHA", args...)
+
+	var rerr redis.Error
+	if errors.As(err, &rerr) && strings.HasPrefix(string(rerr), "NOSCRIPT") {
+		args[0] = sc.src
+		return s.do("EVAL", args...)
+	}
+
+	return reply, err
+}
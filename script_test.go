@@ -0,0 +1,183 @@
+package store
+
+import (
+	"github.com/denkhaus/tcgl/asserts"
+	"github.com/garyburd/redigo/redis"
+	"os"
+	"testing"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// scriptCase runs a script against a fresh key space and checks its reply. Every case runs
+// against the Go emulation of the MemoryStore and, if REDIS_URL is set, against the Lua source
+// on redis, so both implementations are held to the same expectations.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+type scriptCase struct {
+	name   string
+	script *script
+	setup  [][]interface{}
+	args   []interface{}
+	reply  int
+	check  [][]interface{}
+	want   []interface{}
+}
+
+var scriptCases = []scriptCase{
+	{
+		name:   "PromoteDue",
+		script: promoteScript,
+		setup:  [][]interface{}{{"ZADD", "{s}:zset", 1, "a", 2, "b", 3, "c"}},
+		args:   []interface{}{"{s}:zset", "{s}:list", 2, 100},
+		reply:  2,
+		check:  [][]interface{}{{"LRANGE", "{s}:list", 0, -1}, {"ZRANGE", "{s}:zset", 0, -1}},
+		want:   []interface{}{[]string{"b", "a"}, []string{"c"}},
+	},
+	{
+		name:   "PromoteBatch",
+		script: promoteScript,
+		setup:  [][]interface{}{{"ZADD", "{s}:zset", 1, "a", 2, "b", 3, "c"}},
+		args:   []interface{}{"{s}:zset", "{s}:list", 3, 2},
+		reply:  2,
+		check:  [][]interface{}{{"ZRANGE", "{s}:zset", 0, -1}},
+		want:   []interface{}{[]string{"c"}},
+	},
+	{
+		name:   "PromoteNone",
+		script: promoteScript,
+		setup:  [][]interface{}{{"ZADD", "{s}:zset", 5, "a"}},
+		args:   []interface{}{"{s}:zset", "{s}:list", 4, 100},
+		reply:  0,
+		check:  [][]interface{}{{"EXISTS", "{s}:list"}},
+		want:   []interface{}{0},
+	},
+	{
+		name:   "Cancel",
+		script: cancelScript,
+		setup:  [][]interface{}{{"ZADD", "{s}:zset", 1, "m"}, {"HSET", "{s}:hash", "id", "data", "other", "data"}},
+		args:   []interface{}{"{s}:zset", "{s}:hash", "m", "id"},
+		reply:  1,
+		check:  [][]interface{}{{"EXISTS", "{s}:zset"}, {"HKEYS", "{s}:hash"}},
+		want:   []interface{}{0, []string{"other"}},
+	},
+	{
+		name:   "CancelMissing",
+		script: cancelScript,
+		setup:  [][]interface{}{{"HSET", "{s}:hash", "id", "data"}},
+		args:   []interface{}{"{s}:zset", "{s}:hash", "m", "id"},
+		reply:  0,
+		check:  [][]interface{}{{"HKEYS", "{s}:hash"}},
+		want:   []interface{}{[]string{"id"}},
+	},
+	{
+		name:   "Reschedule",
+		script: rescheduleScript,
+		setup:  [][]interface{}{{"ZADD", "{s}:zset", 1, "m"}},
+		args:   []interface{}{"{s}:zset", "m", 7},
+		reply:  1,
+		check:  [][]interface{}{{"ZSCORE", "{s}:zset", "m"}},
+		want:   []interface{}{"7"},
+	},
+	{
+		name:   "RescheduleMissing",
+		script: rescheduleScript,
+		args:   []interface{}{"{s}:zset", "m", 7},
+		reply:  0,
+		check:  [][]interface{}{{"EXISTS", "{s}:zset"}},
+		want:   []interface{}{0},
+	},
+	{
+		name:   "ExpireNX",
+		script: expireNXScript,
+		setup:  [][]interface{}{{"HSET", "{s}:hash", "f", "v"}},
+		args:   []interface{}{"{s}:hash", 60000},
+		reply:  1,
+		check:  [][]interface{}{{"PTTL", "{s}:hash"}},
+		want:   []interface{}{func(ttl int) bool { return ttl > 0 && ttl <= 60000 }},
+	},
+	{
+		name:   "ExpireNXKeepsTTL",
+		script: expireNXScript,
+		setup:  [][]interface{}{{"HSET", "{s}:hash", "f", "v"}, {"PEXPIRE", "{s}:hash", 600000}},
+		args:   []interface{}{"{s}:hash", 60000},
+		reply:  0,
+		check:  [][]interface{}{{"PTTL", "{s}:hash"}},
+		want:   []interface{}{func(ttl int) bool { return ttl > 60000 }},
+	},
+	{
+		name:   "ExpireNXMissing",
+		script: expireNXScript,
+		args:   []interface{}{"{s}:hash", 60000},
+		reply:  0,
+		check:  [][]interface{}{{"EXISTS", "{s}:hash"}},
+		want:   []interface{}{0},
+	},
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestScripts
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestScripts(t *testing.T) {
+	backends := map[string]func() *Store{
+		"MemoryStore": func() *Store { return NewMemoryStore().Store },
+	}
+
+	if os.Getenv(EnvURL) != "" {
+		backends["Redis"] = func() *Store {
+			st, err := FromEnv()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return st
+		}
+	}
+
+	for name, open := range backends {
+		for _, c := range scriptCases {
+			t.Run(name+"/"+c.name, func(t *testing.T) {
+				st := open()
+				defer st.Close()
+
+				testScriptCase(t, st, c)
+			})
+		}
+	}
+}
+
+func testScriptCase(t *testing.T, st *Store, c scriptCase) {
+	assert := asserts.NewTestingAsserts(t, true)
+	assert.Nil(st.Delete("{s}:zset", "{s}:list", "{s}:hash"), "Error should be nil.")
+
+	for _, cmd := range c.setup {
+		_, err := st.do(cmd[0].(string), cmd[1:]...)
+		assert.Nil(err, "Error should be nil.")
+	}
+
+	reply, err := redis.Int(st.eval(c.script, c.args...))
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(reply, c.reply, "the script should return the expected reply")
+
+	for i, cmd := range c.check {
+		res, err := st.do(cmd[0].(string), cmd[1:]...)
+		assert.Nil(err, "Error should be nil.")
+
+		switch want := c.want[i].(type) {
+		case []string:
+			got, err := redis.Strings(res, nil)
+			assert.Nil(err, "Error should be nil.")
+			assert.Equal(got, want, "the script should leave the expected data")
+		case string:
+			got, err := redis.String(res, nil)
+			assert.Nil(err, "Error should be nil.")
+			assert.Equal(got, want, "the script should leave the expected data")
+		case int:
+			got, err := redis.Int(res, nil)
+			assert.Nil(err, "Error should be nil.")
+			assert.Equal(got, want, "the script should leave the expected data")
+		case func(int) bool:
+			got, err := redis.Int(res, nil)
+			assert.Nil(err, "Error should be nil.")
+			assert.True(want(got), "the script should leave the expected data")
+		}
+	}
+}
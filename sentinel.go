@@ -0,0 +1,356 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// readOnlyCommands are routed to a replica by stores opened with WithReplicaReads.
+var readOnlyCommands = map[string]bool{
+	"GET":              true,
+	"HGET":             true,
+	"HGETALL":          true,
+	"HKEYS":            true,
+	"HLEN":             true,
+	"HVALS":            true,
+	"LINDEX":           true,
+	"LLEN":             true,
+	"LRANGE":           true,
+	"MGET":             true,
+	"PTTL":             true,
+	"SCAN":             true,
+	"SCARD":            true,
+	"SDIFF":            true,
+	"SINTER":           true,
+	"SISMEMBER":        true,
+	"SMEMBERS":         true,
+	"SRANDMEMBER":      true,
+	"SUNION":           true,
+	"ZCOUNT":           true,
+	"ZRANGE":           true,
+	"ZRANGEBYSCORE":    true,
+	"ZREVRANGE":        true,
+	"ZREVRANGEBYSCORE": true,
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// OpenSentinel returns a Store for the master masterName monitored by the given sentinels.
+// The current master is asked from the sentinels whenever a connection is dialed. Once a
+// command fails with a READONLY or a connection error, all pooled connections are dropped and
+// the master is resolved again. opts configure the connections to the master and replicas,
+// use WithSentinelPassword to authenticate against the sentinels.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func OpenSentinel(masterName string, sentinelAddrs []string, opts ...Option) (*Store, error) {
+	if masterName == "" {
+		return nil, errors.New("store: sentinel master name must not be empty")
+	}
+
+	if len(sentinelAddrs) == 0 {
+		return nil, errors.New("store: at least one sentinel address is required")
+	}
+
+	o := defaultOptions("")
+	o.sentinelMaster = masterName
+	o.sentinelAddrs = append([]string(nil), sentinelAddrs...)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return open(o)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithSentinelPassword authenticates the connections to the sentinels with password.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithSentinelPassword(password string) Option {
+	return func(o *options) { o.sentinelPassword = password }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithReplicaReads routes read only commands to a random healthy replica of a store opened
+// with OpenSentinel. The master is used if no replica is available. Replication is
+// asynchronous, so a read may not see a write made right before it.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithReplicaReads(enable bool) Option {
+	return func(o *options) { o.replicaReads = enable }
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// sentinel resolves the master and replicas of a monitored master. Every connection carries
+// the generation it was dialed in; invalidate starts a new generation which makes the pools
+// drop all older connections.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type sentinel struct {
+	gen uint64
+
+	master string
+	o      *options
+
+	mu    sync.Mutex
+	addrs []string
+}
+
+func newSentinel(o *options) *sentinel {
+	return &sentinel{
+		master: o.sentinelMaster,
+		o:      o,
+		addrs:  o.sentinelAddrs,
+	}
+}
+
+func (sn *sentinel) generation() uint64 {
+	return atomic.LoadUint64(&sn.gen)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// invalidate drops all connections dialed so far.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (sn *sentinel) invalidate() {
+	atomic.AddUint64(&sn.gen, 1)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// failover inspects the error of a command. It invalidates the connections on READONLY and
+// connection errors and reports whether the command is safe to retry, which is only the case
+// for READONLY because the command has been rejected by the server.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (sn *sentinel) failover(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var rerr redis.Error
+	if errors.As(err, &rerr) {
+		if strings.HasPrefix(string(rerr), "READONLY") {
+			sn.invalidate()
+			return true
+		}
+
+		return false
+	}
+
+	if isConnectionError(err) && !errors.Is(err, redis.ErrPoolExhausted) {
+		sn.invalidate()
+	}
+
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// query runs fn on the first reachable sentinel. A sentinel that answers is moved to the
+// front of the list so it is asked first next time.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (sn *sentinel) query(fn func(conn redis.Conn) error) error {
+	sn.mu.Lock()
+	addrs := append([]string(nil), sn.addrs...)
+	sn.mu.Unlock()
+
+	var lastErr error
+	for _, addr := range addrs {
+		err := sn.queryAddr(addr, fn)
+		if err == nil {
+			sn.promote(addr)
+			return nil
+		}
+
+		lastErr = fmt.Errorf("sentinel %s: %w", addr, err)
+	}
+
+	return fmt.Errorf("store: no sentinel answered for master %q: %w", sn.master, lastErr)
+}
+
+func (sn *sentinel) queryAddr(addr string, fn func(conn redis.Conn) error) error {
+	c, err := sn.o.connect(addr)
+	if err != nil {
+		return err
+	}
+
+	defer c.Close()
+
+	if sn.o.sentinelPassword != "" {
+		if _, err := c.Do("AUTH", sn.o.sentinelPassword); err != nil {
+			return err
+		}
+	}
+
+	return fn(c)
+}
+
+func (sn *sentinel) promote(addr string) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+
+	for i, a := range sn.addrs {
+		if a == addr {
+			copy(sn.addrs[1:i+1], sn.addrs[:i])
+			sn.addrs[0] = addr
+			return
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// masterAddr asks the sentinels for the address of the current master.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (sn *sentinel) masterAddr() (string, error) {
+	var addr string
+	err := sn.query(func(conn redis.Conn) error {
+		res, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", sn.master))
+		if err == redis.ErrNil {
+			return fmt.Errorf("unknown master %q", sn.master)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if len(res) != 2 {
+			return fmt.Errorf("invalid master address %q", res)
+		}
+
+		addr = net.JoinHostPort(res[0], res[1])
+		return nil
+	})
+
+	return addr, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// replicaAddrs asks the sentinels for the addresses of all healthy replicas.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (sn *sentinel) replicaAddrs() ([]string, error) {
+	var addrs []string
+	err := sn.query(func(conn redis.Conn) error {
+		res, err := redis.Values(conn.Do("SENTINEL", "replicas", sn.master))
+		if _, ok := err.(redis.Error); ok {
+			// sentinels before redis 5 only know the old command name
+			res, err = redis.Values(conn.Do("SENTINEL", "slaves", sn.master))
+		}
+
+		if err != nil {
+			return err
+		}
+
+		addrs = addrs[:0]
+		for _, r := range res {
+			info, err := redis.StringMap(r, nil)
+			if err != nil {
+				return err
+			}
+
+			if !healthyReplica(info["flags"]) {
+				continue
+			}
+
+			addrs = append(addrs, net.JoinHostPort(info["ip"], info["port"]))
+		}
+
+		return nil
+	})
+
+	return addrs, err
+}
+
+func healthyReplica(flags string) bool {
+	for _, flag := range strings.Split(flags, ",") {
+		switch flag {
+		case "s_down", "o_down", "disconnected":
+			return false
+		}
+	}
+
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// dialMaster dials the current master and verifies its role.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (sn *sentinel) dialMaster() (redis.Conn, error) {
+	gen := sn.generation()
+	addr, err := sn.masterAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	return sn.dialRole(addr, "master", gen)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// dialReplica dials a random healthy replica and falls back to the master.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (sn *sentinel) dialReplica() (redis.Conn, error) {
+	gen := sn.generation()
+	addrs, err := sn.replicaAddrs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range rand.Perm(len(addrs)) {
+		if c, err := sn.dialRole(addrs[i], "slave", gen); err == nil {
+			return c, nil
+		}
+	}
+
+	return sn.dialMaster()
+}
+
+func (sn *sentinel) dialRole(addr, role string, gen uint64) (redis.Conn, error) {
+	c, err := sn.o.dialAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := redis.Values(c.Do("ROLE"))
+	if err == nil && len(res) == 0 {
+		err = errors.New("empty ROLE reply")
+	}
+
+	if err == nil {
+		var actual string
+		if actual, err = redis.String(res[0], nil); err == nil && actual != role {
+			err = fmt.Errorf("%s has role %s, expected %s", addr, actual, role)
+		}
+	}
+
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return &sentinelConn{Conn: c, gen: gen}, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// testOnBorrow drops connections of an older generation and pings all others.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (sn *sentinel) testOnBorrow(c redis.Conn, t time.Time) error {
+	if sc, ok := c.(*sentinelConn); ok && sc.gen != sn.generation() {
+		return errors.New("store: connection dialed before failover")
+	}
+
+	_, err := c.Do("PING")
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// sentinelConn is a connection tagged with the generation it has been dialed in.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type sentinelConn struct {
+	redis.Conn
+	gen uint64
+}
+
+func (c *sentinelConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+func (c *sentinelConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
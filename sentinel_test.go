@@ -0,0 +1,254 @@
+package store
+
+import (
+	"fmt"
+	"github.com/denkhaus/tcgl/asserts"
+	"github.com/garyburd/redigo/redis"
+	"net"
+	"sync"
+	"testing"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// fakeSentinel reports a configurable master and replicas for the master "mymaster".
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+type fakeSentinel struct {
+	*fakeServer
+
+	mu       sync.Mutex
+	master   string
+	replicas [][]interface{}
+}
+
+func newFakeSentinel(t *testing.T, master string) *fakeSentinel {
+	sn := &fakeSentinel{master: master}
+	sn.fakeServer = newFakeServer(t, func(args []string) interface{} {
+		sn.mu.Lock()
+		defer sn.mu.Unlock()
+
+		if args[0] != "SENTINEL" || len(args) != 3 {
+			return okHandler(args)
+		}
+
+		if args[2] != "mymaster" {
+			return nil
+		}
+
+		switch args[1] {
+		case "get-master-addr-by-name":
+			host, port, _ := net.SplitHostPort(sn.master)
+			return []interface{}{[]byte(host), []byte(port)}
+		case "replicas":
+			res := []interface{}{}
+			for _, r := range sn.replicas {
+				res = append(res, r)
+			}
+
+			return res
+		}
+
+		return redis.Error("ERR unknown sentinel subcommand")
+	})
+
+	return sn
+}
+
+func (sn *fakeSentinel) setMaster(addr string) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+
+	sn.master = addr
+}
+
+func (sn *fakeSentinel) addReplica(addr, flags string) {
+	sn.mu.Lock()
+	defer sn.mu.Unlock()
+
+	host, port, _ := net.SplitHostPort(addr)
+	sn.replicas = append(sn.replicas, []interface{}{
+		[]byte("name"), []byte(addr),
+		[]byte("ip"), []byte(host),
+		[]byte("port"), []byte(port),
+		[]byte("flags"), []byte(flags),
+	})
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// Start a fake redis node reporting role. A node that is not a master rejects SET with READONLY.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func newFakeNode(t *testing.T, role *string, mu *sync.Mutex, get []byte) *fakeServer {
+	return newFakeServer(t, func(args []string) interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch args[0] {
+		case "ROLE":
+			return []interface{}{[]byte(*role)}
+		case "GET":
+			return get
+		case "SET":
+			if *role != "master" {
+				return redis.Error("READONLY You can't write against a read only replica.")
+			}
+		}
+
+		return okHandler(args)
+	})
+}
+
+func countCommands(srv *fakeServer, cmd string) int {
+	n := 0
+	for _, args := range srv.received() {
+		if args[0] == cmd {
+			n++
+		}
+	}
+
+	return n
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns an address nobody listens on.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func closedAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestSentinelFailover
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestSentinelFailover(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	var mu sync.Mutex
+	role1, role2 := "master", "slave"
+	master1 := newFakeNode(t, &role1, &mu, nil)
+	master2 := newFakeNode(t, &role2, &mu, nil)
+	sn := newFakeSentinel(t, master1.addr())
+
+	st, err := OpenSentinel("mymaster", []string{closedAddr(t), sn.addr()})
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	err = st.Set("testKey1", "value")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(countCommands(master1, "SET"), 1, "SET should go to the first master")
+
+	mu.Lock()
+	role1, role2 = "slave", "master"
+	mu.Unlock()
+	sn.setMaster(master2.addr())
+
+	err = st.Set("testKey1", "value")
+	assert.Nil(err, "SET should be retried on the new master")
+	assert.Equal(countCommands(master1, "SET"), 2, "the old master should have rejected SET")
+	assert.Equal(countCommands(master2, "SET"), 1, "SET should go to the new master")
+
+	err = st.Set("testKey1", "value")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(countCommands(master2, "SET"), 2, "SET should stay on the new master")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestSentinelBatchFailover
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestSentinelBatchFailover(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	var mu sync.Mutex
+	role1, role2 := "master", "master"
+	sets := 0
+	master1 := newFakeServer(t, func(args []string) interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch args[0] {
+		case "ROLE":
+			return []interface{}{[]byte(role1)}
+		case "SET":
+			// demoted in the middle of the second chunk
+			if sets++; sets > batchChunk+5 {
+				role1 = "slave"
+				return redis.Error("READONLY You can't write against a read only replica.")
+			}
+		}
+
+		return okHandler(args)
+	})
+
+	master2 := newFakeNode(t, &role2, &mu, nil)
+	sn := newFakeSentinel(t, master1.addr())
+
+	st, err := OpenSentinel("mymaster", []string{sn.addr()})
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	sn.setMaster(master2.addr())
+
+	b := st.NewBatch()
+	for i := 0; i < 2*batchChunk; i++ {
+		b.Set(fmt.Sprintf("testKey%d", i), i)
+	}
+
+	assert.Nil(b.Exec(), "the rejected commands should be retried on the new master")
+	assert.Equal(countCommands(master1, "SET"), 2*batchChunk, "the old master should have received all commands")
+	assert.Equal(countCommands(master2, "SET"), batchChunk-5, "only the rejected commands should be retried")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestSentinelReplicaReads
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestSentinelReplicaReads(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	data, err := DefaultCodec.Marshal("value")
+	assert.Nil(err, "Error should be nil.")
+
+	var mu sync.Mutex
+	masterRole, replicaRole := "master", "slave"
+	master := newFakeNode(t, &masterRole, &mu, nil)
+	replica := newFakeNode(t, &replicaRole, &mu, data)
+	sn := newFakeSentinel(t, master.addr())
+	sn.addReplica(closedAddr(t), "slave,s_down")
+	sn.addReplica(replica.addr(), "slave")
+
+	st, err := OpenSentinel("mymaster", []string{sn.addr()}, WithReplicaReads(true))
+	assert.Nil(err, "Error should be nil.")
+	defer st.Close()
+
+	err = st.Set("testKey1", "value")
+	assert.Nil(err, "Error should be nil.")
+
+	res, err := st.Get("testKey1")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(res, "value", "Get should read from the replica")
+	assert.Equal(countCommands(master, "SET"), 1, "SET should go to the master")
+	assert.Equal(countCommands(master, "GET"), 0, "GET should not go to the master")
+	assert.Equal(countCommands(replica, "GET"), 1, "GET should go to the replica")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestOpenSentinelValidation
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestOpenSentinelValidation(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	_, err := OpenSentinel("", []string{"127.0.0.1:26379"})
+	assert.ErrorMatch(err, "master name", "an empty master name should be rejected")
+
+	_, err = OpenSentinel("mymaster", nil)
+	assert.ErrorMatch(err, "sentinel address", "sentinel addresses are required")
+
+	_, err = Open("127.0.0.1:6379", WithReplicaReads(true))
+	assert.ErrorMatch(err, "requires OpenSentinel", "replica reads need a sentinel")
+
+	_, err = OpenSentinel("othermaster", []string{newFakeSentinel(t, "").addr()})
+	assert.ErrorMatch(err, "unknown master", "an unknown master should be reported")
+}
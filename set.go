@@ -1,37 +1,315 @@
 package store
 
 import (
+	"bytes"
+	"errors"
 	"github.com/garyburd/redigo/redis"
 	"github.com/vmihailenco/msgpack"
-	"strconv"
 )
 
+// errGobMembers is returned by the set functions of a store using GobCodec.
+var errGobMembers = errors.New("store: GobCodec does not encode set members deterministically")
+
 ////////////////////////////////////////////////////////////////////////////////////////////////
+// Adds the raw string member to a set and returns 1 if it has been added, 0 if it was a
+// member already.
 //
+// Deprecated: use SetAdd. Members added by SetSet are not encoded by the codec, so the other
+// set functions fail to decode them.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) SetSet(set string, member string) (int, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
+	data, err := s.do("SADD", set, member)
+	return redis.Int(data, err)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes the raw string member from a set and returns 1 if it has been removed, 0 if it was
+// not a member.
+//
+// Deprecated: use SetRemove, see SetSet.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetDelete(set string, member string) (int, error) {
+	data, err := s.do("SREM", set, member)
+	return redis.Int(data, err)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Adds members to a set and returns the number of members that have been added, not counting
+// existing ones. Members are encoded by the codec and compared by their encoding, see
+// encodeMember for the supported types.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetAdd(set string, members ...interface{}) (int, error) {
+	if len(members) == 0 {
+		return 0, nil
+	}
 
-	if err := conn.Err(); err != nil {
+	args, err := s.setArgs(set, members)
+	if err != nil {
 		return 0, err
 	}
 
-	data, err := conn.Do("SADD", set, member)
-	return redis.Int(data, err)
+	return redis.Int(s.do("SADD", args...))
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
-//
+// Removes members from a set and returns the number of members that have been removed. A set
+// without members is removed.
 ////////////////////////////////////////////////////////////////////////////////////////////////
-func (s *Store) SetDelete(set string, member string) (int, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
+func (s *Store) SetRemove(set string, members ...interface{}) (int, error) {
+	if len(members) == 0 {
+		return 0, nil
+	}
 
-	if err := conn.Err(); err != nil {
+	args, err := s.setArgs(set, members)
+	if err != nil {
+		return 0, err
+	}
+
+	return redis.Int(s.do("SREM", args...))
+}
+
+// setArgs returns set followed by the encoded members.
+func (s *Store) setArgs(set string, members []interface{}) ([]interface{}, error) {
+	args := make([]interface{}, 1, len(members)+1)
+	args[0] = set
+	for _, member := range members {
+		b, err := s.encodeMember(member)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, b)
+	}
+
+	return args, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// encodeMember encodes a set member. Members are compared by their encoding, so equal values
+// must encode to equal bytes. MsgpackCodec sorts the keys of map[string]string and
+// map[string]interface{} for members, JSONCodec sorts all map keys. Other map types, also
+// within structs, are not supported by MsgpackCodec. GobCodec encodes maps in random order and
+// is rejected.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) encodeMember(member interface{}) ([]byte, error) {
+	switch s.codec().(type) {
+	case MsgpackCodec:
+		var buf bytes.Buffer
+		if err := msgpack.NewEncoder(&buf).SortMapKeys(true).Encode(member); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	case GobCodec:
+		return nil, errGobMembers
+	}
+
+	return s.encode(member)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the members of a set in no particular order, an empty slice if it does not exist.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetMembers(set string) ([]interface{}, error) {
+	res, err := redis.Values(s.do("SMEMBERS", set))
+	if err != nil {
 		return nil, err
 	}
 
-	data, err := conn.Do("SREM", set, member)
-	return redis.Int(data, err)
+	return s.decodeValues(set, res)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like SetMembers but decodes the members into the slice pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetMembersInto(set string, dst interface{}) error {
+	res, err := redis.Values(s.do("SMEMBERS", set))
+	if err != nil {
+		return err
+	}
+
+	return s.decodeValuesInto(set, res, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Reports whether member is a member of a set.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetIsMember(set string, member interface{}) (bool, error) {
+	b, err := s.encodeMember(member)
+	if err != nil {
+		return false, err
+	}
+
+	return redis.Bool(s.do("SISMEMBER", set, b))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the number of members of a set, 0 if it does not exist.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetSize(set string) (int, error) {
+	return redis.Int(s.do("SCARD", set))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns a random member of a set without removing it. An empty set will return
+// ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetRandomMember(set string) (interface{}, error) {
+	var out interface{}
+	if err := s.SetRandomMemberInto(set, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like SetRandomMember but decodes the member into the value pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetRandomMemberInto(set string, dst interface{}) error {
+	return s.listGet(set, dst, "SRANDMEMBER", set)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes and returns a random member of a set. An empty set will return ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetPop(set string) (interface{}, error) {
+	var out interface{}
+	if err := s.SetPopInto(set, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like SetPop but decodes the member into the value pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetPopInto(set string, dst interface{}) error {
+	return s.listGet(set, dst, "SPOP", set)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Moves member from the set source to the set destination atomically. It reports false if
+// member is not a member of source. On a cluster both sets must map to the same slot.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetMove(source, destination string, member interface{}) (bool, error) {
+	b, err := s.encodeMember(member)
+	if err != nil {
+		return false, err
+	}
+
+	return redis.Bool(s.do("SMOVE", source, destination, b))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the members contained in all of the given sets. Missing sets are empty, so the
+// intersection with a missing set is empty.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetInter(sets ...string) ([]interface{}, error) {
+	return s.setAlgebra("SINTER", sets)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like SetInter but decodes the members into the slice pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetInterInto(dst interface{}, sets ...string) error {
+	return s.setAlgebraInto("SINTER", sets, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the members contained in any of the given sets.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetUnion(sets ...string) ([]interface{}, error) {
+	return s.setAlgebra("SUNION", sets)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like SetUnion but decodes the members into the slice pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetUnionInto(dst interface{}, sets ...string) error {
+	return s.setAlgebraInto("SUNION", sets, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the members of the first set that are not contained in any of the other sets.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetDiff(sets ...string) ([]interface{}, error) {
+	return s.setAlgebra("SDIFF", sets)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like SetDiff but decodes the members into the slice pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetDiffInto(dst interface{}, sets ...string) error {
+	return s.setAlgebraInto("SDIFF", sets, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Stores the intersection of sets in the set destination, replacing it, and returns the number
+// of its members. An empty result removes destination.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetInterStore(destination string, sets ...string) (int, error) {
+	return s.setStore("SINTERSTORE", destination, sets)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Stores the union of sets in the set destination, see SetInterStore.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetUnionStore(destination string, sets ...string) (int, error) {
+	return s.setStore("SUNIONSTORE", destination, sets)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Stores the difference of sets in the set destination, see SetInterStore.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SetDiffStore(destination string, sets ...string) (int, error) {
+	return s.setStore("SDIFFSTORE", destination, sets)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// setAlgebra runs SINTER, SUNION or SDIFF on sets. On a cluster all sets must map to the same
+// slot.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) setAlgebra(cmd string, sets []string) ([]interface{}, error) {
+	res, err := s.setAlgebraRaw(cmd, sets)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.decodeValues(sets[0], res)
+}
+
+func (s *Store) setAlgebraInto(cmd string, sets []string, dst interface{}) error {
+	res, err := s.setAlgebraRaw(cmd, sets)
+	if err != nil {
+		return err
+	}
+
+	return s.decodeValuesInto(sets[0], res, dst)
+}
+
+func (s *Store) setAlgebraRaw(cmd string, sets []string) ([]interface{}, error) {
+	if len(sets) == 0 {
+		return nil, errors.New("store: at least one set is required")
+	}
+
+	return redis.Values(s.do(cmd, setKeys(sets)...))
+}
+
+func (s *Store) setStore(cmd, destination string, sets []string) (int, error) {
+	if len(sets) == 0 {
+		return 0, errors.New("store: at least one set is required")
+	}
+
+	return redis.Int(s.do(cmd, append([]interface{}{destination}, setKeys(sets)...)...))
+}
+
+func setKeys(sets []string) []interface{} {
+	args := make([]interface{}, len(sets))
+	for i, set := range sets {
+		args[i] = set
+	}
+
+	return args
 }
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"github.com/denkhaus/tcgl/asserts"
+	"testing"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestSetMapMembers
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestSetMapMembers(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	assert.Nil(st.Delete("testSet"), "Error should be nil.")
+
+	member := func() map[string]interface{} {
+		m := make(map[string]interface{})
+		for i := 0; i < 20; i++ {
+			m[fmt.Sprintf("field%d", i)] = i
+		}
+
+		return m
+	}
+
+	for i := 0; i < 10; i++ {
+		_, err := st.SetAdd("testSet", member())
+		assert.Nil(err, "Error should be nil.")
+	}
+
+	size, err := st.SetSize("testSet")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(size, 1, "an equal map should be added once")
+
+	isMember, err := st.SetIsMember("testSet", member())
+	assert.Nil(err, "Error should be nil.")
+	assert.True(isMember, "SetIsMember should find an equal map")
+
+	n, err := st.SetRemove("testSet", member())
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "SetRemove should remove an equal map")
+
+	st.Codec = GobCodec{}
+	_, err = st.SetAdd("testSet", "member")
+	assert.True(errors.Is(err, errGobMembers), "sets should reject GobCodec")
+}
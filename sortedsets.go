@@ -1,47 +1,32 @@
-
 package store
 
 import (
 	"github.com/garyburd/redigo/redis"
-	"github.com/vmihailenco/msgpack"
+	"math"
 	"strconv"
+	"time"
 )
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
-// Sets score and value in a SortedSet
+// Sets score and value in a SortedSet and returns the number of added members. An optional
+// ttl lets a new set expire, see HashSet.
 ////////////////////////////////////////////////////////////////////////////////////////////////
-func (s *Store) SortedSetSet(set string, score float64, value interface{}) (int, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	if err := conn.Err(); err != nil {
-		return 0, err
-	}
-
-	b, err := msgpack.Marshal(value)
+func (s *Store) SortedSetSet(set string, score float64, value interface{}, ttl ...time.Duration) (int, error) {
+	b, err := s.encode(value)
 	if err != nil {
 		return 0, err
 	}
 
-	sc := strconv.FormatFloat(score, 'g', -1, 64)
-	data, err := conn.Do("ZADD", set, sc, b)
+	data, err := s.doWithExpiry(set, ttl, "ZADD", set, formatScore(score), b)
 	return redis.Int(data, err)
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
-// Sets score and value in a SortedSet
+// Returns the number of elements in the SortedSet with a score between scoreMin and scoreMax
+// (inclusive).
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) SortedSetSize(set string, scoreMin float64, scoreMax float64) (int, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	if err := conn.Err(); err != nil {
-		return 0, err
-	}
-
-	scMin := strconv.FormatFloat(scoreMin, 'g', -1, 64)
-	scMax := strconv.FormatFloat(scoreMax, 'g', -1, 64)
-	data, err := conn.Do("ZCOUNT", set, scMin, scMax)
+	data, err := s.do("ZCOUNT", set, formatScore(scoreMin), formatScore(scoreMax))
 	return redis.Int(data, err)
 }
 
@@ -52,37 +37,38 @@ func (s *Store) SortedSetGet(set string, scoreMin float64, scoreMax float64) ([]
 	return s.SortedSetGetAsc(set, scoreMin, scoreMax)
 }
 
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Decodes values from SortedSet between the specified scores into the slice pointed to by dst,
+// same as SortedSetGetAscInto.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SortedSetGetInto(set string, scoreMin float64, scoreMax float64, dst interface{}) error {
+	return s.SortedSetGetAscInto(set, scoreMin, scoreMax, dst)
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////
 // Returns the specified range of elements in the sorted set stored at set. The elements are considered
 // to be ordered from the lowest to the highest score. Ascending lexicographical
 // order is used for elements with equal score.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) SortedSetGetAsc(set string, scoreMin float64, scoreMax float64) ([]interface{}, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	if err := conn.Err(); err != nil {
-		return nil, err
-	}
-
-	scMin := strconv.FormatFloat(scoreMin, 'g', -1, 64)
-	scMax := strconv.FormatFloat(scoreMax, 'g', -1, 64)
-
-	data, err := conn.Do("ZRANGE", set, scMin, scMax)
+	res, err := s.sortedSetRange(false, set, scoreMin, scoreMax)
 	if err != nil {
 		return nil, err
 	}
 
-	if data == nil {
-		return nil, nil
-	}
+	return s.decodeValues(set, res)
+}
 
-	res, err := redis.Values(data, err)
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like SortedSetGetAsc but decodes the elements into the slice pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SortedSetGetAscInto(set string, scoreMin float64, scoreMax float64, dst interface{}) error {
+	res, err := s.sortedSetRange(false, set, scoreMin, scoreMax)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return s.DecodeValues(res)
+	return s.decodeValuesInto(set, res, dst)
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
@@ -91,31 +77,51 @@ func (s *Store) SortedSetGetAsc(set string, scoreMin float64, scoreMax float64)
 // order is used for elements with equal score.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) SortedSetGetDesc(set string, scoreMin float64, scoreMax float64) ([]interface{}, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	if err := conn.Err(); err != nil {
+	res, err := s.sortedSetRange(true, set, scoreMin, scoreMax)
+	if err != nil {
 		return nil, err
 	}
 
-	scMin := strconv.FormatFloat(scoreMin, 'g', -1, 64)
-	scMax := strconv.FormatFloat(scoreMax, 'g', -1, 64)
+	return s.decodeValues(set, res)
+}
 
-	data, err := conn.Do("ZREVRANGE", set, scMin, scMax)
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Like SortedSetGetDesc but decodes the elements into the slice pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SortedSetGetDescInto(set string, scoreMin float64, scoreMax float64, dst interface{}) error {
+	res, err := s.sortedSetRange(true, set, scoreMin, scoreMax)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if data == nil {
-		return nil, nil
+	return s.decodeValuesInto(set, res, dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// sortedSetRange returns the raw elements with a score between scoreMin and scoreMax
+// (inclusive), in descending order if rev is set.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) sortedSetRange(rev bool, set string, scoreMin float64, scoreMax float64) ([]interface{}, error) {
+	var (
+		data interface{}
+		err  error
+	)
+
+	if rev {
+		data, err = s.do("ZREVRANGEBYSCORE", set, formatScore(scoreMax), formatScore(scoreMin))
+	} else {
+		data, err = s.do("ZRANGEBYSCORE", set, formatScore(scoreMin), formatScore(scoreMax))
 	}
 
-	res, err := redis.Values(data, err)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.DecodeValues(res)
+	if data == nil {
+		return nil, nil
+	}
+
+	return redis.Values(data, err)
 }
 
 ///////////////////////////////////////////////////////////////////////////////////////////////
@@ -123,16 +129,7 @@ func (s *Store) SortedSetGetDesc(set string, scoreMin float64, scoreMax float64)
 // Returns the number of elements removed.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) SortedSetDeleteByScore(key string, scoreMin float64, scoreMax float64) (int, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	if err := conn.Err(); err != nil {
-		return 0, err
-	}
-
-	scMin := strconv.FormatFloat(scoreMin, 'g', -1, 64)
-	scMax := strconv.FormatFloat(scoreMax, 'g', -1, 64)
-	data, err := conn.Do("ZREMRANGEBYSCORE", key, scMin, scMax)
+	data, err := s.do("ZREMRANGEBYSCORE", key, formatScore(scoreMin), formatScore(scoreMax))
 	return redis.Int(data, err)
 }
 
@@ -141,13 +138,33 @@ func (s *Store) SortedSetDeleteByScore(key string, scoreMin float64, scoreMax fl
 // Returns the number of elements removed.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) SortedSetDeleteAll(key string) (int, error) {
-	return s.SortedSetDeleteByScore(key, 0, -1)
+	return s.SortedSetDeleteByScore(key, math.Inf(-1), math.Inf(1))
 }
 
 ///////////////////////////////////////////////////////////////////////////////////////////////
-// Get all elements in the sorted set stored at key.
-// Returns the number of elements removed.
+// Get all elements in the sorted set stored at key, ordered from the lowest to the highest score.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) SortedSetGetAll(key string) ([]interface{}, error) {
-	return s.SortedSetGet(key, 0, -1)
-}
\ No newline at end of file
+	return s.SortedSetGet(key, math.Inf(-1), math.Inf(1))
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////
+// Decodes all elements in the sorted set stored at key into the slice pointed to by dst.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) SortedSetGetAllInto(key string, dst interface{}) error {
+	return s.SortedSetGetInto(key, math.Inf(-1), math.Inf(1), dst)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// formatScore formats a score bound, infinite scores become -inf and +inf.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, -1):
+		return "-inf"
+	case math.IsInf(score, 1):
+		return "+inf"
+	}
+
+	return strconv.FormatFloat(score, 'g', -1, 64)
+}
@@ -4,6 +4,7 @@ package store
 import (
 	"fmt"
 	"github.com/denkhaus/tcgl/asserts"
+	"math"
 	"testing"
 )
 
@@ -66,4 +67,84 @@ func TestSortedSets(t *testing.T) {
 		t.Error(fmt.Sprintf("invalid SortedSetDeleteByScore response, expected 5, result ::%d ", sSize))
 		t.Fail()
 	}
-}
\ No newline at end of file
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestSortedSetGetInto
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestSortedSetGetInto(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	set := "testSetInto"
+
+	err := st.Delete(set)
+	assert.Nil(err, "Error should be nil.")
+
+	for i := 0; i < 3; i++ {
+		_, err := st.SortedSetSet(set, float64(i), codecTestItem{Name: "item", Count: i})
+		assert.Nil(err, "Error should be nil.")
+	}
+
+	var asc []codecTestItem
+	err = st.SortedSetGetAscInto(set, 0, 2, &asc)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(asc, 3, "SortedSetGetAscInto wrong length")
+	assert.Equal(asc[0].Count, 0, "SortedSetGetAscInto wrong order")
+
+	var desc []codecTestItem
+	err = st.SortedSetGetDescInto(set, 0, 2, &desc)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(desc, 3, "SortedSetGetDescInto wrong length")
+	assert.Equal(desc[0].Count, 2, "SortedSetGetDescInto wrong order")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestSortedSetScores
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestSortedSetScores(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	set := "testSetScores"
+
+	err := st.Delete(set)
+	assert.Nil(err, "Error should be nil.")
+
+	// Scores beyond the number of elements tell score and rank ranges apart.
+	for i, value := range []string{"a", "b", "c"} {
+		_, err := st.SortedSetSet(set, float64(10*(i+1)), value)
+		assert.Nil(err, "Error should be nil.")
+	}
+
+	res, err := st.SortedSetGet(set, 15, 30)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(res, []interface{}{"b", "c"}, "SortedSetGet should select by score")
+
+	res, err = st.SortedSetGetDesc(set, 0, 20)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(res, []interface{}{"b", "a"}, "SortedSetGetDesc should select by score")
+
+	res, err = st.SortedSetGetAsc(set, math.Inf(-1), 10)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(res, []interface{}{"a"}, "an infinite bound should be accepted")
+
+	res, err = st.SortedSetGetAll(set)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(res, 3, "SortedSetGetAll should return all elements")
+
+	var all []string
+	err = st.SortedSetGetAllInto(set, &all)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(all, []string{"a", "b", "c"}, "SortedSetGetAllInto should decode all elements")
+
+	n, err := st.SortedSetDeleteAll(set)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 3, "SortedSetDeleteAll should remove all elements")
+
+	n, err = st.SortedSetSize(set, math.Inf(-1), math.Inf(1))
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "the sorted set should be empty")
+}
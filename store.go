@@ -1,23 +1,38 @@
 package store
 
 import (
+	"context"
+	"fmt"
 	"github.com/garyburd/redigo/redis"
-	"time"
+	"strconv"
 )
 
 // Provides a redis backed Store.
 type Store struct {
+	// Pool is nil for stores opened with OpenCluster.
 	Pool *redis.Pool
+
+	// Codec encodes and decodes all stored values. DefaultCodec is used when nil.
+	Codec Codec
+
+	ctx context.Context
+
+	// set by OpenSentinel
+	sentinel *sentinel
+	replicas *redis.Pool
+
+	// set by OpenCluster
+	cluster *cluster
+
+	// set for the reads of a Tx, which run on its watching connection
+	conn redis.Conn
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
 // ping does an internal ping against a server to check if it is alive.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) ping() (bool, error) {
-	conn := s.Pool.Get()
-	defer conn.Close()
-
-	data, err := conn.Do("PING")
+	data, err := s.do("PING")
 
 	if err != nil || data == nil {
 		return false, err
@@ -26,68 +41,30 @@ func (s *Store) ping() (bool, error) {
 	return (data == "PONG"), nil
 }
 
-////////////////////////////////////////////////////////////////////////////////////////////////
-//
-////////////////////////////////////////////////////////////////////////////////////////////////
-func dial(network, address, password string) (redis.Conn, error) {
-	c, err := redis.Dial(network, address)
-
-	if err != nil {
-		return nil, err
-	}
-
-	if password != "" {
-		if _, err := c.Do("AUTH", password); err != nil {
-			c.Close()
-			return nil, err
-		}
-	}
-
-	return c, err
-}
-
 ////////////////////////////////////////////////////////////////////////////////////////////////
 // NewStore returns a new RediStore.
 // size: maximum number of idle connections.
+// If the server does not answer, the store is returned together with the error.
+//
+// Deprecated: use Open, which returns nil and the error in that case.
 ////////////////////////////////////////////////////////////////////////////////////////////////
-
 func NewStore(size int, network, address, password string) (*Store, error) {
-	return NewStoreWithPool(&redis.Pool{
-		MaxIdle:     size,
-		IdleTimeout: 240 * time.Second,
-		TestOnBorrow: func(c redis.Conn, t time.Time) error {
-			_, err := c.Do("PING")
-			return err
-		},
-		Dial: func() (redis.Conn, error) {
-			return dial(network, address, password)
-		},
-	})
+	return openUnchecked(address, WithNetwork(network), WithMaxIdle(size), WithPassword(password))
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
 // NewStoreWithDB - like NewRedisStore but accepts `DB` parameter to select
-// redis DB instead of using the default one ("0")
+// redis DB instead of using the default one ("0"). An invalid DB returns nil and the error.
+//
+// Deprecated: use Open with WithDB.
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func NewStoreWithDB(size int, network, address, password, DB string) (*Store, error) {
-	rs, _ := NewStore(size, network, address, password)
-	rs.Pool.Dial = func() (redis.Conn, error) {
-		c, err := dial(network, address, password)
-
-		if err != nil {
-			return c, err
-		}
-
-		if _, err := c.Do("SELECT", DB); err != nil {
-			c.Close()
-			return nil, err
-		}
-
-		return c, err
+	db, err := strconv.Atoi(DB)
+	if err != nil {
+		return nil, fmt.Errorf("store: invalid database index %q", DB)
 	}
 
-	_, err := rs.ping()
-	return rs, err
+	return openUnchecked(address, WithNetwork(network), WithMaxIdle(size), WithPassword(password), WithDB(db))
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////
@@ -103,5 +80,13 @@ func NewStoreWithPool(pool *redis.Pool) (*Store, error) {
 // Close closes the underlying *redis.Pool
 ////////////////////////////////////////////////////////////////////////////////////////////////
 func (s *Store) Close() error {
+	if s.cluster != nil {
+		return s.cluster.close()
+	}
+
+	if s.replicas != nil {
+		s.replicas.Close()
+	}
+
 	return s.Pool.Close()
 }
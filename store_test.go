@@ -3,16 +3,22 @@ package store
 
 import (
 	"github.com/denkhaus/tcgl/asserts"
+	"os"
 	"testing"
 )
 
 /////////////////////////////////////////////////////////////////////////////////////////////////////
-// Create a Basic Store for testing
+// Create a Basic Store for testing. The tests run against a MemoryStore unless REDIS_URL
+// points to a redis server.
 /////////////////////////////////////////////////////////////////////////////////////////////////////
 func createStore(t *testing.T) *Store {
 	assert := asserts.NewTestingAsserts(t, true)
 
-	st, err := NewStore(10, "tcp", ":6379", "")
+	if os.Getenv(EnvURL) == "" {
+		return NewMemoryStore().Store
+	}
+
+	st, err := FromEnv()
 	assert.Nil(err, "Error should be nil.")
 
 	return st
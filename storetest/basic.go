@@ -0,0 +1,400 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denkhaus/go-store"
+	"github.com/denkhaus/tcgl/asserts"
+	"sort"
+	"testing"
+	"time"
+)
+
+func testSetGet(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	_, err := b.Get("missing")
+	assert.True(errors.Is(err, store.ErrNotFound), "a missing key should return ErrNotFound")
+
+	assert.Nil(b.Set("key", "value"), "Error should be nil.")
+	val, err := b.Get("key")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "value", "Get should return the stored value")
+
+	assert.Nil(b.Set("key", "other"), "Error should be nil.")
+	val, err = b.Get("key")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "other", "Set should overwrite the value")
+
+	assert.Nil(b.Set("empty", ""), "Error should be nil.")
+	val, err = b.Get("empty")
+	assert.Nil(err, "an empty value should be found")
+	assert.Equal(val, "", "Get should return the empty value")
+
+	assert.Nil(b.HashSet("hash", "field", "value"), "Error should be nil.")
+	assert.Nil(b.Set("hash", "value"), "Set should overwrite a key of another type")
+	val, err = b.Get("hash")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "value", "Get should return the stored value")
+}
+
+func testGetInto(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	in := Item{Name: "item", Count: 3}
+	assert.Nil(b.Set("item", in), "Error should be nil.")
+
+	var out Item
+	assert.Nil(b.GetInto("item", &out), "Error should be nil.")
+	assert.Equal(out, in, "GetInto should decode the stored value")
+
+	untouched := Item{Name: "untouched"}
+	err := b.GetInto("missing", &untouched)
+	assert.True(errors.Is(err, store.ErrNotFound), "a missing key should return ErrNotFound")
+	assert.Equal(untouched, Item{Name: "untouched"}, "a missing key should leave dst untouched")
+
+	assert.Nil(b.Set("string", "value"), "Error should be nil.")
+	err = b.GetInto("string", &out)
+	assert.True(errors.Is(err, store.ErrDecode), "decoding into the wrong type should return ErrDecode")
+}
+
+func testDelete(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	assert.Nil(b.Delete("missing"), "deleting a missing key should not fail")
+
+	assert.Nil(b.Set("key", "value"), "Error should be nil.")
+	assert.Nil(b.Delete("key"), "Error should be nil.")
+	_, err := b.Get("key")
+	assert.True(errors.Is(err, store.ErrNotFound), "a deleted key should return ErrNotFound")
+
+	assert.Nil(b.HashSet("hash", "field", "value"), "Error should be nil.")
+	assert.Nil(b.Delete("hash"), "Error should be nil.")
+	n, err := b.HashSize("hash")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "Delete should remove keys of any type")
+}
+
+func testMultiKey(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	assert.Nil(b.MSet(map[string]interface{}{"a": Item{"a", 1}, "b": Item{"b", 2}, "c": nil}), "Error should be nil.")
+
+	var items []Item
+	found, err := b.MGetInto(&items, "b", "missing", "a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(found, []bool{true, false, true}, "MGetInto should report missing keys")
+	assert.Equal(items, []Item{{"b", 2}, {}, {"a", 1}}, "MGetInto should align the values with the keys")
+
+	vals, found, err := b.MGet("c", "missing")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(vals, []interface{}{nil, nil}, "MGet should return nil for missing keys")
+	assert.Equal(found, []bool{true, false}, "MGet should tell a stored nil from a missing key")
+
+	ok, err := b.MSetNX(map[string]interface{}{"a": 0, "d": 0})
+	assert.Nil(err, "Error should be nil.")
+	assert.False(ok, "MSetNX should fail if a key exists")
+	assert.False(exists(t, b, "d"), "MSetNX should not set any key if one exists")
+
+	assert.Nil(b.Delete("a", "b", "missing"), "Error should be nil.")
+	assert.Nil(b.Unlink("c"), "Error should be nil.")
+	_, found, err = b.MGet("a", "b", "c")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(found, []bool{false, false, false}, "Delete and Unlink should remove all keys")
+}
+
+func testTTL(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	assert.Nil(b.SetWithTTL("expiring", "value", 1), "Error should be nil.")
+	assert.Nil(b.SetWithTTL("persisted", "value", 1), "Error should be nil.")
+	assert.Nil(b.Set("persisted", "value"), "Error should be nil.")
+
+	val, err := b.Get("expiring")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "value", "a value should be found before its ttl passed")
+
+	advance(b, time.Second)
+
+	_, err = b.Get("expiring")
+	assert.True(errors.Is(err, store.ErrNotFound), "an expired key should return ErrNotFound")
+
+	_, err = b.Get("persisted")
+	assert.Nil(err, "Set should discard the ttl")
+
+	assert.False(exists(t, b, "expiring"), "an expired key should not be enumerated")
+
+	err = b.SetWithTTL("invalid", "value", 0)
+	assert.NotNil(err, "a ttl of 0 should fail")
+}
+
+func testConditionalSet(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	ok, err := b.SetXX("key", "value", 0)
+	assert.Nil(err, "Error should be nil.")
+	assert.False(ok, "SetXX should not store a missing key")
+
+	ok, err = b.SetNX("key", Item{"first", 1}, 0)
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "SetNX should store a missing key")
+
+	ok, err = b.SetNX("key", Item{"second", 2}, 0)
+	assert.Nil(err, "Error should be nil.")
+	assert.False(ok, "SetNX should not overwrite a key")
+
+	var old Item
+	found, err := b.GetSetInto("key", Item{"third", 3}, &old)
+	assert.Nil(err, "Error should be nil.")
+	assert.True(found, "GetSetInto should find the previous value")
+	assert.Equal(old, Item{"first", 1}, "GetSetInto should decode the previous value")
+
+	var item Item
+	assert.Nil(b.GetDelInto("key", &item), "Error should be nil.")
+	assert.Equal(item, Item{"third", 3}, "GetDelInto should decode the value")
+	assert.False(exists(t, b, "key"), "GetDelInto should delete the key")
+
+	assert.Nil(b.SetWithExpiry("expiring", "value", 1500*time.Millisecond), "Error should be nil.")
+	ok, err = b.SetXX("expiring", "updated", 0)
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "SetXX should overwrite an existing key")
+	assert.Nil(b.SetWithExpiry("kept", "value", 1500*time.Millisecond), "Error should be nil.")
+	assert.Nil(b.SetKeepTTL("kept", "updated"), "Error should be nil.")
+
+	advance(b, 1500*time.Millisecond)
+
+	assert.True(exists(t, b, "expiring"), "SetXX without ttl should discard the ttl")
+	assert.False(exists(t, b, "kept"), "SetKeepTTL should keep the ttl")
+}
+
+func testExpire(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	_, err := b.TTL("missing")
+	assert.True(errors.Is(err, store.ErrNotFound), "TTL of a missing key should return ErrNotFound")
+
+	assert.Nil(b.HashSet("hash", "a", 1, time.Second), "Error should be nil.")
+	assert.Nil(b.HashSet("hash", "b", 2, time.Hour), "Error should be nil.")
+	_, err = b.SortedSetSet("zset", 1, "member", time.Second)
+	assert.Nil(err, "Error should be nil.")
+	assert.Nil(b.Set("persisted", "value"), "Error should be nil.")
+
+	ttl, err := b.TTL("persisted")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(ttl, store.NoExpiry, "a key without ttl should return NoExpiry")
+
+	ok, err := b.Expire("persisted", time.Second)
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "Expire should set the ttl of an existing key")
+
+	ok, err = b.Persist("persisted")
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "Persist should remove the ttl")
+
+	assert.Nil(b.ListPush("list", "key", "value"), "Error should be nil.")
+	ok, err = b.ExpireAt("list", time.Now().Add(time.Second))
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "ExpireAt should set the ttl of a list")
+
+	ttl, err = b.TTL("hash")
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ttl > 0 && ttl <= time.Second, "the ttl of a hash should be set by its first write")
+
+	advance(b, time.Second)
+
+	assert.False(exists(t, b, "hash"), "the hash should expire")
+	assert.False(exists(t, b, "zset"), "the sorted set should expire")
+	assert.False(exists(t, b, "list"), "the list should expire")
+	assert.True(exists(t, b, "persisted"), "a persisted key should not expire")
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// populate stores n keys with the given prefix and returns them sorted.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func populate(t *testing.T, b store.Backend, prefix string, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%s%03d", prefix, i)
+		if err := b.Set(keys[i], i); err != nil {
+			t.Fatalf("Set %s: %v", keys[i], err)
+		}
+	}
+
+	return keys
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// exists reports whether key is enumerated.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func exists(t *testing.T, b store.Backend, key string) bool {
+	for k, err := range b.Keys(context.Background(), key, store.ScanOptions{}) {
+		if err != nil {
+			t.Fatalf("Keys %s: %v", key, err)
+		}
+
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func testEnumerate(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	want := populate(t, b, "enum:", 95)
+	populate(t, b, "other:", 20)
+
+	var got []string
+	cursor := 0
+	for steps := 0; ; steps++ {
+		next, keys, err := b.Enumerate(cursor, "enum:*", 10)
+		assert.Nil(err, "Error should be nil.")
+		got = append(got, keys...)
+
+		if cursor = next; cursor == 0 {
+			break
+		}
+
+		assert.True(steps < 1000, "Enumerate should terminate")
+	}
+
+	sort.Strings(got)
+	assert.Equal(got, want, "Enumerate should return every matching key exactly once")
+
+	_, keys, err := b.Enumerate(0, "none:*", 1000)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(keys, 0, "Enumerate should not return keys without a match")
+}
+
+func testEnumerateKeys(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	err := b.EnumerateKeys("*", func(idx int, key string) error {
+		t.Errorf("an empty backend should not enumerate %s", key)
+		return nil
+	})
+
+	assert.Nil(err, "Error should be nil.")
+
+	want := populate(t, b, "enum:", 95)
+	populate(t, b, "other:", 20)
+
+	var got []string
+	err = b.EnumerateKeys("enum:*", func(idx int, key string) error {
+		got = append(got, key)
+		return nil
+	})
+
+	assert.Nil(err, "Error should be nil.")
+	sort.Strings(got)
+	assert.Equal(got, want, "EnumerateKeys should return every matching key exactly once")
+
+	stop := errors.New("stop")
+	calls := 0
+	err = b.EnumerateKeys("*", func(idx int, key string) error {
+		calls++
+		return stop
+	})
+
+	assert.Equal(err, stop, "EnumerateKeys should return the error of enumFunc")
+	assert.Equal(calls, 1, "EnumerateKeys should stop at the first error")
+}
+
+func testKeys(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+	ctx := context.Background()
+
+	for key, err := range b.Keys(ctx, "*", store.ScanOptions{}) {
+		t.Errorf("an empty backend should not return %s, %v", key, err)
+	}
+
+	want := populate(t, b, "enum:", 95)
+	populate(t, b, "other:", 20)
+	assert.Nil(b.HashSet("enum:hash", "field", "value"), "Error should be nil.")
+
+	var got []string
+	for key, err := range b.Keys(ctx, "enum:*", store.ScanOptions{Count: 7, Type: "string"}) {
+		assert.Nil(err, "Error should be nil.")
+		got = append(got, key)
+	}
+
+	sort.Strings(got)
+	assert.Equal(got, want, "Keys should return every matching key of the type exactly once")
+
+	got = nil
+	for key, err := range b.Keys(ctx, "enum:*", store.ScanOptions{Type: "hash"}) {
+		assert.Nil(err, "Error should be nil.")
+		got = append(got, key)
+	}
+
+	assert.Equal(got, []string{"enum:hash"}, "Keys should filter by type")
+
+	calls := 0
+	for range b.Keys(ctx, "", store.ScanOptions{Count: 1}) {
+		if calls++; calls == 3 {
+			break
+		}
+	}
+
+	assert.Equal(calls, 3, "breaking out of the loop should end the iteration")
+}
+
+func testDecodeValues(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	vals, err := b.DecodeValues(nil)
+	assert.Nil(err, "Error should be nil.")
+	assert.Nil(vals, "decoding no values should return nil")
+
+	items := []Item{{Name: "stale"}}
+	assert.Nil(b.DecodeValuesInto(nil, &items), "Error should be nil.")
+	assert.Length(items, 0, "decoding no values should reset dst")
+
+	err = b.DecodeValuesInto(nil, items)
+	assert.NotNil(err, "decoding into a non pointer should fail")
+
+	assert.NotNil(b.Context(), "Context should not be nil")
+}
+
+func testWrongType(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	assert.Nil(b.Set("string", "value"), "Error should be nil.")
+	assert.Nil(b.HashSet("hash", "field", "value"), "Error should be nil.")
+
+	wrong := func(err error, op string) {
+		assert.True(errors.Is(err, store.ErrWrongType), op+" should return ErrWrongType")
+	}
+
+	_, err := b.Get("hash")
+	wrong(err, "Get on a hash")
+	wrong(b.HashSet("string", "field", "value"), "HashSet on a string")
+	_, err = b.HashGet("string", "field")
+	wrong(err, "HashGet on a string")
+	_, err = b.HashSize("string")
+	wrong(err, "HashSize on a string")
+	_, err = b.HashGetFields("string")
+	wrong(err, "HashGetFields on a string")
+	_, err = b.HashGetValues("string")
+	wrong(err, "HashGetValues on a string")
+	wrong(b.ListPush("hash", "key", "value"), "ListPush on a hash")
+	_, err = b.SetSet("string", "member")
+	wrong(err, "SetSet on a string")
+	_, err = b.SetDelete("hash", "member")
+	wrong(err, "SetDelete on a hash")
+	_, err = b.SetAdd("string", "member")
+	wrong(err, "SetAdd on a string")
+	_, err = b.SetMembers("hash")
+	wrong(err, "SetMembers on a hash")
+	_, err = b.SetInter("hash", "set")
+	wrong(err, "SetInter with a hash")
+	_, err = b.SortedSetSet("string", 1, "value")
+	wrong(err, "SortedSetSet on a string")
+	_, err = b.SortedSetGet("hash", 0, 1)
+	wrong(err, "SortedSetGet on a hash")
+	_, err = b.SortedSetSize("hash", 0, 1)
+	wrong(err, "SortedSetSize on a hash")
+}
@@ -0,0 +1,41 @@
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/denkhaus/go-store"
+	"github.com/denkhaus/tcgl/asserts"
+	"testing"
+)
+
+func testBatch(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	assert.Nil(b.Set("string", "value"), "Error should be nil.")
+
+	batch := b.NewBatch()
+	for i := 0; i < 1500; i++ {
+		batch.HashSet("hash", fmt.Sprintf("field%d", i), Item{Name: "item", Count: i})
+	}
+
+	wrong := batch.SetSet("string", "member")
+	get := batch.HashGet("hash", "field1499")
+	missing := batch.Get("missing")
+	size := batch.HashSize("hash")
+
+	err := batch.Exec()
+	assert.True(errors.Is(err, store.ErrWrongType), "Exec should return the error of an operation")
+	assert.True(errors.Is(wrong.Err(), store.ErrWrongType), "the failed operation should report its error")
+
+	var item Item
+	assert.Nil(get.Into(&item), "Error should be nil.")
+	assert.Equal(item, Item{Name: "item", Count: 1499}, "HashGet should see the preceding writes")
+
+	_, err = missing.Value()
+	assert.True(errors.Is(err, store.ErrNotFound), "a missing value should return ErrNotFound")
+
+	n, err := size.Int()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1500, "all writes should be executed")
+	assert.Nil(batch.Exec(), "an empty batch should succeed")
+}
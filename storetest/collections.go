@@ -0,0 +1,251 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"github.com/denkhaus/go-store"
+	"github.com/denkhaus/tcgl/asserts"
+	"sort"
+	"testing"
+	"time"
+)
+
+func testList(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	assert.Nil(b.ListPush("list", "key", "value"), "Error should be nil.")
+	assert.Nil(b.ListPush("list", "key", Item{Name: "item"}), "Error should be nil.")
+
+	_, err := b.Get("list")
+	assert.True(errors.Is(err, store.ErrWrongType), "ListPush should create a list")
+
+	n, err := b.ListLen("list")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "ListPush should push the value only")
+
+	assert.Nil(b.Delete("list"), "Error should be nil.")
+	_, err = b.Get("list")
+	assert.True(errors.Is(err, store.ErrNotFound), "a deleted list should be gone")
+
+	n, err = b.ListLen("list")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "a missing list should be empty")
+
+	vals, err := b.ListRange("list", 0, -1)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(vals, 0, "a missing list should have no elements")
+
+	n, err = b.ListPushRight("list", "b", Item{Name: "c"})
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "ListPushRight should return the new length")
+
+	n, err = b.ListPushLeft("list", "a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 3, "ListPushLeft should return the new length")
+
+	var item Item
+	assert.Nil(b.ListIndexInto("list", -1, &item), "Error should be nil.")
+	assert.Equal(item, Item{Name: "c"}, "ListIndexInto should decode the element")
+
+	_, err = b.ListIndex("list", 3)
+	assert.True(errors.Is(err, store.ErrNotFound), "an index out of range should return ErrNotFound")
+
+	assert.Nil(b.ListSet("list", 1, "x"), "Error should be nil.")
+	n, err = b.ListInsert("list", false, "x", "y")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 4, "ListInsert should return the new length")
+
+	var got []interface{}
+	assert.Nil(b.ListRangeInto("list", 0, 2, &got), "Error should be nil.")
+	assert.Equal(got, []interface{}{"a", "x", "y"}, "ListRangeInto should decode the range")
+
+	n, err = b.ListRemove("list", 0, "y")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "ListRemove should return the number of removed elements")
+
+	val, err := b.ListPopLeft("list")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "a", "ListPopLeft should return the head")
+
+	assert.Nil(b.ListPopRightInto("list", &item), "Error should be nil.")
+	assert.Equal(item, Item{Name: "c"}, "ListPopRightInto should decode the tail")
+
+	assert.Nil(b.ListTrim("list", 1, -1), "Error should be nil.")
+	_, err = b.ListPopLeft("list")
+	assert.True(errors.Is(err, store.ErrNotFound), "a list trimmed to no elements should be removed")
+
+	assert.True(errors.Is(b.ListSet("list", 0, "x"), store.ErrNotFound), "ListSet on a missing list should return ErrNotFound")
+	_, err = b.ListInsert("list", true, "x", "y")
+	assert.True(errors.Is(err, store.ErrNotFound), "ListInsert on a missing list should return ErrNotFound")
+}
+
+func testListBlocking(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+	ctx := context.Background()
+
+	_, err := b.ListPushRight("list", Item{Name: "a"}, Item{Name: "b"})
+	assert.Nil(err, "Error should be nil.")
+
+	var item Item
+	list, err := b.ListBlockingPopInto(ctx, time.Second, &item, "empty", "list")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(list, "list", "ListBlockingPopInto should return the list")
+	assert.Equal(item, Item{Name: "a"}, "ListBlockingPopInto should decode the head")
+
+	val, err := b.ListBlockingMove(ctx, time.Second, "list", "other", store.ListLeft, store.ListLeft)
+	assert.Nil(err, "Error should be nil.")
+	assert.NotNil(val, "ListBlockingMove should return the moved value")
+
+	_, _, err = b.ListBlockingPop(ctx, 100*time.Millisecond, "list")
+	assert.True(errors.Is(err, store.ErrNotFound), "a timeout should return ErrNotFound")
+
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+
+	_, _, err = b.ListBlockingPopRight(ctx, 0, "list")
+	assert.Equal(err, context.DeadlineExceeded, "the context should end a pop without timeout")
+
+	// A server notices the closed connection of the pop asynchronously.
+	time.Sleep(50 * time.Millisecond)
+	_, err = b.ListPushRight("list", "c")
+	assert.Nil(err, "Error should be nil.")
+
+	n, err := b.ListLen("list")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "an ended pop should not remove a value pushed later")
+}
+
+func testSet(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	n, err := b.SetDelete("missing", "member")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "deleting from a missing set should return 0")
+
+	n, err = b.SetSet("set", "a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "SetSet should add a new member")
+
+	n, err = b.SetSet("set", "a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "SetSet should not add an existing member")
+
+	n, err = b.SetSet("set", "b")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "SetSet should add a new member")
+
+	n, err = b.SetDelete("set", "c")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "SetDelete should ignore a missing member")
+
+	for _, member := range []string{"a", "b"} {
+		n, err = b.SetDelete("set", member)
+		assert.Nil(err, "Error should be nil.")
+		assert.Equal(n, 1, "SetDelete should remove the member")
+	}
+
+	assert.False(exists(t, b, "set"), "a set without members should be removed")
+}
+
+func testSetMembers(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	var items []Item
+	assert.Nil(b.SetMembersInto("set", &items), "Error should be nil.")
+	assert.Length(items, 0, "a missing set should have no members")
+
+	n, err := b.SetAdd("set", Item{"a", 1}, Item{"b", 2}, Item{"a", 1})
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "SetAdd should count new members once")
+
+	n, err = b.SetAdd("set")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "SetAdd without members should add nothing")
+
+	n, err = b.SetSize("set")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "SetSize should count the members")
+
+	assert.Nil(b.SetMembersInto("set", &items), "Error should be nil.")
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	assert.Equal(items, []Item{{"a", 1}, {"b", 2}}, "SetMembersInto should decode the members")
+
+	found, err := b.SetIsMember("set", Item{"b", 2})
+	assert.Nil(err, "Error should be nil.")
+	assert.True(found, "SetIsMember should find a member")
+
+	found, err = b.SetIsMember("set", Item{"b", 3})
+	assert.Nil(err, "Error should be nil.")
+	assert.False(found, "SetIsMember should compare the encoding")
+
+	var item Item
+	assert.Nil(b.SetRandomMemberInto("set", &item), "Error should be nil.")
+	assert.True(item == Item{"a", 1} || item == Item{"b", 2}, "SetRandomMemberInto should return a member")
+
+	moved, err := b.SetMove("set", "other", Item{"a", 1})
+	assert.Nil(err, "Error should be nil.")
+	assert.True(moved, "SetMove should move a member")
+
+	moved, err = b.SetMove("set", "other", Item{"a", 1})
+	assert.Nil(err, "Error should be nil.")
+	assert.False(moved, "SetMove should report a missing member")
+
+	assert.Nil(b.SetPopInto("set", &item), "Error should be nil.")
+	assert.Equal(item, Item{"b", 2}, "SetPopInto should return the last member")
+	assert.False(exists(t, b, "set"), "a set without members should be removed")
+	assert.True(errors.Is(b.SetPopInto("set", &item), store.ErrNotFound), "SetPopInto of an empty set should return ErrNotFound")
+	_, err = b.SetRandomMember("set")
+	assert.True(errors.Is(err, store.ErrNotFound), "SetRandomMember of an empty set should return ErrNotFound")
+
+	n, err = b.SetRemove("other", Item{"a", 1}, Item{"c", 3})
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "SetRemove should count the removed members")
+	assert.False(exists(t, b, "other"), "a set without members should be removed")
+}
+
+func testSetAlgebra(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	_, err := b.SetAdd("{s}:a", 1, 2, 3)
+	assert.Nil(err, "Error should be nil.")
+	_, err = b.SetAdd("{s}:b", 2, 3, 4)
+	assert.Nil(err, "Error should be nil.")
+
+	sorted := func(vals []int) []int {
+		sort.Ints(vals)
+		return vals
+	}
+
+	var vals []int
+	assert.Nil(b.SetInterInto(&vals, "{s}:a", "{s}:b"), "Error should be nil.")
+	assert.Equal(sorted(vals), []int{2, 3}, "SetInterInto should return the common members")
+
+	assert.Nil(b.SetUnionInto(&vals, "{s}:a", "{s}:b"), "Error should be nil.")
+	assert.Equal(sorted(vals), []int{1, 2, 3, 4}, "SetUnionInto should return all members")
+
+	assert.Nil(b.SetDiffInto(&vals, "{s}:a", "{s}:b"), "Error should be nil.")
+	assert.Equal(vals, []int{1}, "SetDiffInto should return the members of the first set only")
+
+	assert.Nil(b.SetInterInto(&vals, "{s}:a", "{s}:missing"), "Error should be nil.")
+	assert.Length(vals, 0, "the intersection with a missing set should be empty")
+
+	res, err := b.SetUnion("{s}:a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(res, 3, "SetUnion should decode the members")
+
+	_, err = b.SetDiff()
+	assert.NotNil(err, "SetDiff without sets should fail")
+
+	n, err := b.SetUnionStore("{s}:dst", "{s}:a", "{s}:b")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 4, "SetUnionStore should return the size of the result")
+
+	n, err = b.SetInterStore("{s}:dst", "{s}:a", "{s}:b")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "SetInterStore should replace the destination")
+
+	n, err = b.SetDiffStore("{s}:dst", "{s}:a", "{s}:a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "SetDiffStore should return the size of the result")
+	assert.False(exists(t, b, "{s}:dst"), "an empty result should remove the destination")
+}
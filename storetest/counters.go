@@ -0,0 +1,58 @@
+package storetest
+
+import (
+	"github.com/denkhaus/go-store"
+	"github.com/denkhaus/tcgl/asserts"
+	"testing"
+)
+
+func testCounters(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	n, err := b.Incr("counter")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, int64(1), "Incr should start at 0")
+
+	n, err = b.IncrBy("counter", -5)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, int64(-4), "IncrBy should add n")
+
+	n, err = b.Decr("counter")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, int64(-5), "Decr should subtract one")
+
+	var i int
+	assert.Nil(b.GetInto("counter", &i), "Error should be nil.")
+	assert.Equal(i, -5, "GetInto should decode a counter")
+
+	f, err := b.IncrByFloat("float", 0.1)
+	assert.Nil(err, "Error should be nil.")
+	f, err = b.IncrByFloat("float", 0.2)
+	assert.Nil(err, "Error should be nil.")
+	assert.True(f > 0.29 && f < 0.31, "IncrByFloat should add f")
+
+	val, err := b.Get("float")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, f, "Get should decode a float counter")
+
+	_, err = b.HashIncrBy("hash", "hits", 2)
+	assert.Nil(err, "Error should be nil.")
+	n, err = b.HashIncrBy("hash", "hits", 40)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, int64(42), "HashIncrBy should add n")
+
+	_, err = b.HashIncrByFloat("hash", "score", 2.5)
+	assert.Nil(err, "Error should be nil.")
+
+	var hits int64
+	assert.Nil(b.HashGetInto("hash", "hits", &hits), "Error should be nil.")
+	assert.Equal(hits, int64(42), "HashGetInto should decode a counter")
+
+	val, err = b.HashGet("hash", "score")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, 2.5, "HashGet should decode a float counter")
+
+	assert.Nil(b.Set("small", 50), "Error should be nil.")
+	assert.Nil(b.GetInto("small", &i), "Error should be nil.")
+	assert.Equal(i, 50, "an encoded small integer should not be read as a counter")
+}
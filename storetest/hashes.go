@@ -0,0 +1,159 @@
+package storetest
+
+import (
+	"errors"
+	"github.com/denkhaus/go-store"
+	"github.com/denkhaus/tcgl/asserts"
+	"sort"
+	"testing"
+)
+
+func testHash(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	items := map[string]Item{
+		"a": {Name: "a", Count: 1},
+		"b": {Name: "b", Count: 2},
+		"c": {Name: "c", Count: 3},
+	}
+
+	for field, item := range items {
+		assert.Nil(b.HashSet("hash", field, item), "Error should be nil.")
+	}
+
+	n, err := b.HashSize("hash")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 3, "HashSize should count the fields")
+
+	var item Item
+	assert.Nil(b.HashGetInto("hash", "b", &item), "Error should be nil.")
+	assert.Equal(item, items["b"], "HashGetInto should decode the field")
+
+	all := map[string]Item{}
+	assert.Nil(b.HashGetAllInto("hash", &all), "Error should be nil.")
+	assert.Equal(all, items, "HashGetAllInto should decode all fields")
+
+	fields, err := b.HashGetFields("hash")
+	assert.Nil(err, "Error should be nil.")
+	sort.Strings(fields)
+	assert.Equal(fields, []string{"a", "b", "c"}, "HashGetFields should return all fields")
+
+	var values []Item
+	assert.Nil(b.HashGetValuesInto("hash", &values), "Error should be nil.")
+	sort.Slice(values, func(i, j int) bool { return values[i].Count < values[j].Count })
+	assert.Equal(values, []Item{items["a"], items["b"], items["c"]}, "HashGetValuesInto should decode all values")
+
+	assert.Nil(b.HashSet("hash", "a", "replaced"), "Error should be nil.")
+	val, err := b.HashGet("hash", "a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(val, "replaced", "HashSet should overwrite the field")
+
+	n, err = b.HashSize("hash")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 3, "overwriting a field should not change HashSize")
+
+	vals, err := b.HashGetValues("hash")
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(vals, 3, "HashGetValues should return all values")
+
+	n, err = b.HashDeleteField("hash", "a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "HashDeleteField should delete the field")
+
+	n, err = b.HashDeleteField("hash", "a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "HashDeleteField should ignore a missing field")
+
+	_, err = b.HashGet("hash", "a")
+	assert.True(errors.Is(err, store.ErrNotFound), "a deleted field should return ErrNotFound")
+
+	for _, field := range []string{"b", "c"} {
+		_, err = b.HashDeleteField("hash", field)
+		assert.Nil(err, "Error should be nil.")
+	}
+
+	assert.False(exists(t, b, "hash"), "a hash without fields should be removed")
+}
+
+func testHashMissing(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	_, err := b.HashGet("missing", "field")
+	assert.True(errors.Is(err, store.ErrNotFound), "a missing hash should return ErrNotFound")
+
+	item := Item{Name: "untouched"}
+	err = b.HashGetInto("missing", "field", &item)
+	assert.True(errors.Is(err, store.ErrNotFound), "a missing hash should return ErrNotFound")
+	assert.Equal(item, Item{Name: "untouched"}, "a missing field should leave dst untouched")
+
+	assert.Nil(b.HashSet("hash", "field", "value"), "Error should be nil.")
+	_, err = b.HashGet("hash", "missing")
+	assert.True(errors.Is(err, store.ErrNotFound), "a missing field should return ErrNotFound")
+
+	n, err := b.HashSize("missing")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "a missing hash should be empty")
+
+	fields, err := b.HashGetFields("missing")
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(fields, 0, "a missing hash should have no fields")
+
+	vals, err := b.HashGetValues("missing")
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(vals, 0, "a missing hash should have no values")
+
+	var all map[string]Item
+	assert.Nil(b.HashGetAllInto("missing", &all), "Error should be nil.")
+	assert.Length(all, 0, "a missing hash should have no fields")
+
+	n, err = b.HashDeleteField("missing", "field")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "deleting from a missing hash should return 0")
+
+	err = b.HashGetAllInto("hash", all)
+	assert.NotNil(err, "decoding into a non pointer should fail")
+}
+
+func testHashEnumerate(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	for _, field := range []string{"a", "b", "c"} {
+		assert.Nil(b.HashSet("hash", field, field+"-value"), "Error should be nil.")
+	}
+
+	var fields []string
+	err := b.HashEnumerateFields("hash", func(field string) error {
+		fields = append(fields, field)
+		return nil
+	})
+
+	assert.Nil(err, "Error should be nil.")
+	sort.Strings(fields)
+	assert.Equal(fields, []string{"a", "b", "c"}, "HashEnumerateFields should visit every field")
+
+	var values []string
+	err = b.HashEnumerateValues("hash", func(value interface{}) error {
+		values = append(values, value.(string))
+		return nil
+	})
+
+	assert.Nil(err, "Error should be nil.")
+	sort.Strings(values)
+	assert.Equal(values, []string{"a-value", "b-value", "c-value"}, "HashEnumerateValues should visit every value")
+
+	stop := errors.New("stop")
+	calls := 0
+	err = b.HashEnumerateFields("hash", func(field string) error {
+		calls++
+		return stop
+	})
+
+	assert.Equal(err, stop, "HashEnumerateFields should return the error of the callback")
+	assert.Equal(calls, 1, "HashEnumerateFields should stop at the first error")
+
+	err = b.HashEnumerateValues("missing", func(value interface{}) error {
+		return stop
+	})
+
+	assert.Nil(err, "a missing hash should not be enumerated")
+}
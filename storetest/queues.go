@@ -0,0 +1,92 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denkhaus/go-store"
+	"github.com/denkhaus/tcgl/asserts"
+	"testing"
+	"time"
+)
+
+func testQueueSchedule(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+	q := store.NewQueue[Item](unwrap(t, b), "queue", store.QueueOptions{})
+
+	now := time.Now()
+	id1, err := q.Schedule(Item{Name: "one"}, now.Add(-time.Minute))
+	assert.Nil(err, "Error should be nil.")
+	id2, err := q.Schedule(Item{Name: "two"}, now.Add(-2*time.Minute))
+	assert.Nil(err, "Error should be nil.")
+	id3, err := q.Schedule(Item{Name: "three"}, now.Add(time.Hour))
+	assert.Nil(err, "Error should be nil.")
+	id4, err := q.Schedule(Item{Name: "four"}, now.Add(2*time.Hour))
+	assert.Nil(err, "Error should be nil.")
+
+	upcoming, err := q.Upcoming(0)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(upcoming, 4, "Upcoming should return all scheduled jobs")
+	assert.Equal(upcoming[0].ID, id2, "Upcoming should return the earliest job first")
+	assert.Equal(upcoming[0].Data, Item{Name: "two"}, "Upcoming should decode the job")
+	assert.Equal(upcoming[0].Due.UnixMilli(), now.Add(-2*time.Minute).UnixMilli(), "Upcoming should return the due time")
+	assert.Equal(upcoming[3].ID, id4, "Upcoming should return the latest job last")
+
+	upcoming, err = q.Upcoming(1)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(upcoming, 1, "Upcoming should return up to limit jobs")
+
+	assert.Nil(q.Cancel(id4), "Error should be nil.")
+	assert.True(errors.Is(q.Cancel(id4), store.ErrNotFound), "a cancelled job should not be scheduled")
+	assert.Nil(q.Reschedule(id3, now.Add(-30*time.Second)), "Error should be nil.")
+	assert.True(errors.Is(q.Reschedule(id4, now), store.ErrNotFound), "a cancelled job should not be rescheduled")
+
+	n, err := q.PromoteDue()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 3, "PromoteDue should enqueue the due jobs")
+
+	n, err = q.PromoteDue()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "PromoteDue should enqueue every job once")
+
+	n, err = q.Len()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 3, "the promoted jobs should be pending")
+
+	upcoming, err = q.Upcoming(0)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(upcoming, 0, "promoted jobs should not be upcoming")
+	assert.True(errors.Is(q.Cancel(id1), store.ErrNotFound), "a promoted job should not be cancelled")
+
+	c := q.Consumer("consumer")
+	for _, id := range []string{id2, id1, id3} {
+		job, err := c.Receive(context.Background(), time.Second)
+		assert.Nil(err, "Error should be nil.")
+		assert.Equal(job.ID, id, "promoted jobs should be received in due order")
+		assert.Nil(c.Ack(job), "Error should be nil.")
+	}
+}
+
+func testQueuePromoteBatches(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+	q := store.NewQueue[string](unwrap(t, b), "queue", store.QueueOptions{})
+
+	due := time.Now().Add(-time.Hour)
+	for i := 0; i < 250; i++ {
+		_, err := q.Schedule(fmt.Sprint(i), due.Add(time.Duration(i)*time.Millisecond))
+		assert.Nil(err, "Error should be nil.")
+	}
+
+	n, err := q.PromoteDue()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 250, "PromoteDue should enqueue the due jobs of all batches")
+
+	n, err = q.Len()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 250, "the promoted jobs should be pending")
+
+	c := q.Consumer("consumer")
+	job, err := c.Receive(context.Background(), time.Second)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(job.Data, "0", "the earliest job should be received first")
+}
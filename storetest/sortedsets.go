@@ -0,0 +1,167 @@
+package storetest
+
+import (
+	"github.com/denkhaus/go-store"
+	"github.com/denkhaus/tcgl/asserts"
+	"math"
+	"testing"
+)
+
+var (
+	negInf = math.Inf(-1)
+	posInf = math.Inf(1)
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// members adds the members with their scores to set.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func members(t *testing.T, b store.Backend, set string, scores map[string]float64) {
+	for member, score := range scores {
+		n, err := b.SortedSetSet(set, score, member)
+		if err != nil {
+			t.Fatalf("SortedSetSet %s: %v", member, err)
+		}
+
+		if n != 1 {
+			t.Fatalf("SortedSetSet %s: added %d members, want 1", member, n)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// asStrings converts decoded string values. An error is returned as single element, so that
+// the assertion comparing the result reports it.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func asStrings(vals []interface{}, err error) []string {
+	if err != nil {
+		return []string{"error: " + err.Error()}
+	}
+
+	res := make([]string, len(vals))
+	for i, v := range vals {
+		res[i] = v.(string)
+	}
+
+	return res
+}
+
+func testSortedSet(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	members(t, b, "zset", map[string]float64{"a": 1, "b": 2, "c": 3, "d": -1.5})
+
+	n, err := b.SortedSetSet("zset", 5, "a")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "updating the score should not add a member")
+
+	asc := []string{"d", "b", "c", "a"}
+	desc := []string{"a", "c", "b", "d"}
+	assert.Equal(asStrings(b.SortedSetGetAsc("zset", negInf, posInf)), asc, "SortedSetGetAsc should order by score")
+	assert.Equal(asStrings(b.SortedSetGet("zset", negInf, posInf)), asc, "SortedSetGet should order by score")
+	assert.Equal(asStrings(b.SortedSetGetAll("zset")), asc, "SortedSetGetAll should order by score")
+	assert.Equal(asStrings(b.SortedSetGetDesc("zset", negInf, posInf)), desc, "SortedSetGetDesc should order by descending score")
+
+	members(t, b, "equal", map[string]float64{"y": 10, "x": 10, "z": 10})
+	assert.Equal(asStrings(b.SortedSetGetAsc("equal", 10, 10)), []string{"x", "y", "z"}, "equal scores should be ordered by member")
+	assert.Equal(asStrings(b.SortedSetGetDesc("equal", 10, 10)), []string{"z", "y", "x"}, "equal scores should be ordered by descending member")
+
+	for i := 0; i < 3; i++ {
+		_, err := b.SortedSetSet("items", float64(i), Item{Name: "item", Count: i})
+		assert.Nil(err, "Error should be nil.")
+	}
+
+	var items []Item
+	assert.Nil(b.SortedSetGetAscInto("items", 1, 2, &items), "Error should be nil.")
+	assert.Equal(items, []Item{{"item", 1}, {"item", 2}}, "SortedSetGetAscInto should decode the range")
+
+	assert.Nil(b.SortedSetGetDescInto("items", 0, 1, &items), "Error should be nil.")
+	assert.Equal(items, []Item{{"item", 1}, {"item", 0}}, "SortedSetGetDescInto should decode the range")
+
+	assert.Nil(b.SortedSetGetInto("items", 2, 2, &items), "Error should be nil.")
+	assert.Equal(items, []Item{{"item", 2}}, "SortedSetGetInto should decode the range")
+
+	assert.Nil(b.SortedSetGetAllInto("items", &items), "Error should be nil.")
+	assert.Length(items, 3, "SortedSetGetAllInto should decode all members")
+}
+
+func testSortedSetBoundaries(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	members(t, b, "zset", map[string]float64{"a": 1, "b": 2, "c": 3, "n": -2, "f": math.Nextafter(0.3, 1)})
+
+	get := func(min, max float64) []string {
+		return asStrings(b.SortedSetGet("zset", min, max))
+	}
+
+	assert.Equal(get(1, 3), []string{"a", "b", "c"}, "both bounds should be inclusive")
+	assert.Equal(get(1.5, 2.5), []string{"b"}, "fractional bounds should be respected")
+	assert.Equal(get(2, 2), []string{"b"}, "equal bounds should select a single score")
+	assert.Length(get(3, 1), 0, "a min above max should select nothing")
+	assert.Length(get(4, posInf), 0, "a range above all scores should select nothing")
+	assert.Equal(get(negInf, 0), []string{"n"}, "negative scores should be selected")
+	assert.Length(get(0.3, 0.3), 0, "scores should keep their full precision")
+	assert.Equal(get(math.Nextafter(0.3, 1), math.Nextafter(0.3, 1)), []string{"f"}, "scores should keep their full precision")
+
+	size := func(min, max float64) int {
+		n, err := b.SortedSetSize("zset", min, max)
+		assert.Nil(err, "Error should be nil.")
+		return n
+	}
+
+	assert.Equal(size(negInf, posInf), 5, "SortedSetSize should count all members")
+	assert.Equal(size(1, 3), 3, "SortedSetSize should include both bounds")
+	assert.Equal(size(3, 1), 0, "SortedSetSize of an empty range should be 0")
+	assert.Equal(asStrings(b.SortedSetGetDesc("zset", 1, 3)), []string{"c", "b", "a"}, "SortedSetGetDesc should include both bounds")
+}
+
+func testSortedSetMissing(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	vals, err := b.SortedSetGet("missing", negInf, posInf)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(vals, 0, "a missing sorted set should be empty")
+
+	vals, err = b.SortedSetGetDesc("missing", negInf, posInf)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(vals, 0, "a missing sorted set should be empty")
+
+	vals, err = b.SortedSetGetAll("missing")
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(vals, 0, "a missing sorted set should be empty")
+
+	items := []Item{{Name: "stale"}}
+	assert.Nil(b.SortedSetGetAllInto("missing", &items), "Error should be nil.")
+	assert.Length(items, 0, "a missing sorted set should reset dst")
+
+	n, err := b.SortedSetSize("missing", negInf, posInf)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "a missing sorted set should be empty")
+
+	n, err = b.SortedSetDeleteByScore("missing", negInf, posInf)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "deleting from a missing sorted set should return 0")
+
+	n, err = b.SortedSetDeleteAll("missing")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "deleting from a missing sorted set should return 0")
+}
+
+func testSortedSetDelete(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	members(t, b, "zset", map[string]float64{"a": -1, "b": 0, "c": 1, "d": 2, "e": 3})
+
+	n, err := b.SortedSetDeleteByScore("zset", 1, 2)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "SortedSetDeleteByScore should include both bounds")
+	assert.Equal(asStrings(b.SortedSetGetAll("zset")), []string{"a", "b", "e"}, "SortedSetDeleteByScore should keep the other members")
+
+	n, err = b.SortedSetDeleteByScore("zset", 5, 10)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 0, "SortedSetDeleteByScore of an empty range should return 0")
+
+	n, err = b.SortedSetDeleteAll("zset")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 3, "SortedSetDeleteAll should delete members with negative scores too")
+	assert.False(exists(t, b, "zset"), "a sorted set without members should be removed")
+}
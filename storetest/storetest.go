@@ -0,0 +1,124 @@
+// Package storetest provides a conformance suite for implementations of store.Backend.
+//
+//	func TestMemoryStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Backend {
+//			return store.NewMemoryStore()
+//		})
+//	}
+package storetest
+
+import (
+	"github.com/denkhaus/go-store"
+	"testing"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Factory returns an empty backend for a single test. The suite closes it when the test is
+// done.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Factory func(t *testing.T) store.Backend
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Clock is implemented by backends whose clock can be moved, like store.MemoryStore. Expiry
+// of backends without a Clock is tested by sleeping.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Clock interface {
+	FastForward(d time.Duration)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Wrapper is implemented by backends wrapping a store.Store. The tests of the types built on a
+// Store, like store.Queue, run on the Store returned by Unwrap and are skipped for backends
+// that are neither a Store nor a Wrapper.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Wrapper interface {
+	Unwrap() *store.Store
+}
+
+// Item is the struct value stored by the suite.
+type Item struct {
+	Name  string
+	Count int
+}
+
+var tests = []struct {
+	name string
+	fn   func(t *testing.T, b store.Backend)
+}{
+	{"SetGet", testSetGet},
+	{"GetInto", testGetInto},
+	{"Delete", testDelete},
+	{"MultiKey", testMultiKey},
+	{"TTL", testTTL},
+	{"ConditionalSet", testConditionalSet},
+	{"Expire", testExpire},
+	{"Counters", testCounters},
+	{"Enumerate", testEnumerate},
+	{"EnumerateKeys", testEnumerateKeys},
+	{"Keys", testKeys},
+	{"DecodeValues", testDecodeValues},
+	{"WrongType", testWrongType},
+	{"Hash", testHash},
+	{"HashMissing", testHashMissing},
+	{"HashEnumerate", testHashEnumerate},
+	{"List", testList},
+	{"ListBlocking", testListBlocking},
+	{"Set", testSet},
+	{"SetMembers", testSetMembers},
+	{"SetAlgebra", testSetAlgebra},
+	{"SortedSet", testSortedSet},
+	{"SortedSetBoundaries", testSortedSetBoundaries},
+	{"SortedSetMissing", testSortedSetMissing},
+	{"SortedSetDelete", testSortedSetDelete},
+	{"Batch", testBatch},
+	{"Tx", testTx},
+	{"TxConflict", testTxConflict},
+	{"QueueSchedule", testQueueSchedule},
+	{"QueuePromoteBatches", testQueuePromoteBatches},
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Run runs the conformance suite against the backends returned by factory. Every test runs as
+// a subtest on a backend of its own.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func Run(t *testing.T, factory Factory) {
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			b := factory(t)
+			defer b.Close()
+
+			tc.fn(t, b)
+		})
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// advance lets d pass on the clock of b.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func advance(b store.Backend, d time.Duration) {
+	if c, isClock := b.(Clock); isClock {
+		c.FastForward(d)
+		return
+	}
+
+	time.Sleep(d + 100*time.Millisecond)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// unwrap returns the Store b is built on or skips the test.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func unwrap(t *testing.T, b store.Backend) *store.Store {
+	switch b := b.(type) {
+	case *store.Store:
+		return b
+	case *store.MemoryStore:
+		return b.Store
+	case Wrapper:
+		return b.Unwrap()
+	}
+
+	t.Skipf("%T is not built on a store.Store", b)
+	return nil
+}
@@ -0,0 +1,85 @@
+package storetest
+
+import (
+	"github.com/denkhaus/go-store"
+	"github.com/denkhaus/go-store/respserver"
+	"os"
+	"testing"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestMemoryStore
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestMemoryStore(t *testing.T) {
+	Run(t, func(t *testing.T) store.Backend {
+		return store.NewMemoryStore()
+	})
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestRedis runs the suite against the redis server in REDIS_URL, whose database is flushed
+// before every test.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestRedis(t *testing.T) {
+	if os.Getenv(store.EnvURL) == "" {
+		t.Skipf("%s is not set", store.EnvURL)
+	}
+
+	Run(t, func(t *testing.T) store.Backend {
+		st, err := store.FromEnv()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		conn := st.Pool.Get()
+		defer conn.Close()
+
+		if _, err := conn.Do("FLUSHDB"); err != nil {
+			t.Fatal(err)
+		}
+
+		return st
+	})
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// serverStore is a Store talking to a respserver whose clock is moved by FastForward.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+type serverStore struct {
+	*store.Store
+	srv *respserver.Server
+}
+
+func (s serverStore) FastForward(d time.Duration) {
+	s.srv.FastForward(d)
+}
+
+func (s serverStore) Unwrap() *store.Store {
+	return s.Store
+}
+
+func (s serverStore) Close() error {
+	s.Store.Close()
+	return s.srv.Close()
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestRESPServer
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestRESPServer(t *testing.T) {
+	Run(t, func(t *testing.T) store.Backend {
+		srv, err := respserver.Start()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		st, err := store.OpenURL(srv.URL())
+		if err != nil {
+			srv.Close()
+			t.Fatal(err)
+		}
+
+		return serverStore{st, srv}
+	})
+}
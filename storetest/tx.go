@@ -0,0 +1,76 @@
+package storetest
+
+import (
+	"errors"
+	"github.com/denkhaus/go-store"
+	"github.com/denkhaus/tcgl/asserts"
+	"testing"
+)
+
+func testTx(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	assert.Nil(b.HashSet("todo", "task", Item{Name: "task", Count: 1}), "Error should be nil.")
+
+	err := b.Tx([]string{"todo", "done"}, func(tx *store.Tx) error {
+		var item Item
+		if err := tx.HashGetInto("todo", "task", &item); err != nil {
+			return err
+		}
+
+		item.Count++
+		tx.HashDeleteField("todo", "task")
+		tx.HashSet("done", "task", item)
+		tx.SortedSetSet("index", 1, "task")
+		return nil
+	})
+
+	assert.Nil(err, "Error should be nil.")
+	assert.False(exists(t, b, "todo"), "the field should have been moved")
+
+	var item Item
+	assert.Nil(b.HashGetInto("done", "task", &item), "Error should be nil.")
+	assert.Equal(item, Item{"task", 2}, "the moved value should be written")
+	assert.Equal(asStrings(b.SortedSetGetAll("index")), []string{"task"}, "the index should be written")
+
+	failed := errors.New("failed")
+	err = b.Tx([]string{"done"}, func(tx *store.Tx) error {
+		tx.Delete("done")
+		return failed
+	})
+
+	assert.Equal(err, failed, "the error of fn should be returned")
+	assert.True(exists(t, b, "done"), "an error of fn should discard the writes")
+}
+
+func testTxConflict(t *testing.T, b store.Backend) {
+	assert := asserts.NewTestingAsserts(t, true)
+
+	assert.Nil(b.Set("counter", 1), "Error should be nil.")
+
+	attempts := 0
+	err := b.Tx([]string{"counter"}, func(tx *store.Tx) error {
+		attempts++
+
+		var n int
+		if err := tx.GetInto("counter", &n); err != nil {
+			return err
+		}
+
+		if attempts == 1 {
+			if err := b.Set("counter", 10); err != nil {
+				return err
+			}
+		}
+
+		tx.Set("counter", n+1)
+		return nil
+	})
+
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(attempts, 2, "a modified watched key should retry the transaction")
+
+	var n int
+	assert.Nil(b.GetInto("counter", &n), "Error should be nil.")
+	assert.Equal(n, 11, "the retry should see the concurrent write")
+}
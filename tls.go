@@ -0,0 +1,120 @@
+package store
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithTLS enables TLS. cfg is used as the base configuration for all connections and may be
+// nil. The other TLS options are applied on top of a copy of cfg.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithTLS(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.useTLS = true
+		o.tlsConfig = cfg
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithTLSServerName enables TLS and overrides the server name used for SNI and certificate
+// verification. It defaults to the host of the address.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithTLSServerName(name string) Option {
+	return func(o *options) {
+		o.useTLS = true
+		o.tlsServerName = name
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithTLSCAFile enables TLS and verifies the server against the PEM encoded CA certificates
+// in file instead of the system roots.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithTLSCAFile(file string) Option {
+	return func(o *options) {
+		o.useTLS = true
+		o.tlsCAFile = file
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithTLSClientCert enables TLS and presents the PEM encoded client certificate and key to
+// the server (mutual TLS).
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithTLSClientCert(certFile, keyFile string) Option {
+	return func(o *options) {
+		o.useTLS = true
+		o.tlsCertFile = certFile
+		o.tlsKeyFile = keyFile
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// WithTLSInsecureSkipVerify enables TLS and disables server certificate verification.
+// Only use this for testing.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func WithTLSInsecureSkipVerify(skip bool) Option {
+	return func(o *options) {
+		o.useTLS = true
+		o.tlsSkipVerify = skip
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// buildTLSConfig assembles the TLS configuration, nil if TLS is disabled.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (o *options) buildTLSConfig() (*tls.Config, error) {
+	if !o.useTLS {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{}
+	if o.tlsConfig != nil {
+		cfg = o.tlsConfig.Clone()
+	}
+
+	if o.tlsServerName != "" {
+		cfg.ServerName = o.tlsServerName
+	}
+
+	if o.tlsSkipVerify {
+		cfg.InsecureSkipVerify = true
+	}
+
+	if o.tlsCAFile != "" {
+		pem, err := ioutil.ReadFile(o.tlsCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("store: read tls ca file: %v", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("store: no certificates found in tls ca file %s", o.tlsCAFile)
+		}
+
+		cfg.RootCAs = pool
+	}
+
+	if o.tlsCertFile != "" || o.tlsKeyFile != "" {
+		if o.tlsCertFile == "" || o.tlsKeyFile == "" {
+			return nil, errors.New("store: tls client certificate requires both a certificate and a key file")
+		}
+
+		cert, err := tls.LoadX509KeyPair(o.tlsCertFile, o.tlsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("store: load tls client certificate: %v", err)
+		}
+
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+
+	if cfg.ServerName == "" && o.network == "unix" && !cfg.InsecureSkipVerify {
+		return nil, errors.New("store: tls over a unix socket requires a server name")
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/denkhaus/tcgl/asserts"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// testPKI holds a throwaway CA with a server and a client certificate written to disk.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+type testPKI struct {
+	caFile, certFile, keyFile string
+
+	caPool *x509.CertPool
+	server tls.Certificate
+}
+
+func newTestPKI(t *testing.T, serverIPs []net.IP, serverNames ...string) *testPKI {
+	dir := t.TempDir()
+	caKey, caCert, caDER := createTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "go-store test ca"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil, nil)
+
+	serverKey, _, serverDER := createTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "go-store test server"},
+		IPAddresses: serverIPs,
+		DNSNames:    serverNames,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, caCert, caKey)
+
+	clientKey, _, clientDER := createTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "go-store test client"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, caCert, caKey)
+
+	pki := &testPKI{
+		caFile:   filepath.Join(dir, "ca.pem"),
+		certFile: filepath.Join(dir, "client.pem"),
+		keyFile:  filepath.Join(dir, "client-key.pem"),
+		caPool:   x509.NewCertPool(),
+		server:   tls.Certificate{Certificate: [][]byte{serverDER}, PrivateKey: serverKey},
+	}
+
+	pki.caPool.AddCert(caCert)
+	writeTestPEM(t, pki.caFile, "CERTIFICATE", caDER)
+	writeTestPEM(t, pki.certFile, "CERTIFICATE", clientDER)
+
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestPEM(t, pki.keyFile, "EC PRIVATE KEY", keyDER)
+	return pki
+}
+
+func createTestCert(t *testing.T, tmpl *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*ecdsa.PrivateKey, *x509.Certificate, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, cert, der
+}
+
+func writeTestPEM(t *testing.T, file, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// Start a fake server that terminates TLS with the server certificate of pki.
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func newFakeTLSServer(t *testing.T, pki *testPKI, clientAuth bool) *fakeServer {
+	cfg := &tls.Config{Certificates: []tls.Certificate{pki.server}}
+	if clientAuth {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.ClientCAs = pki.caPool
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return serveFake(t, ln, okHandler)
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestTLS
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestTLS(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	pki := newTestPKI(t, []net.IP{net.ParseIP("127.0.0.1")})
+	srv := newFakeTLSServer(t, pki, false)
+
+	_, err := Open(srv.addr(), WithTLS(nil), WithDialTimeout(time.Second))
+	assert.NotNil(err, "an unknown CA must be rejected")
+
+	st, err := Open(srv.addr(), WithTLSCAFile(pki.caFile))
+	assert.Nil(err, "Error should be nil.")
+	st.Close()
+
+	st, err = Open(srv.addr(), WithTLS(&tls.Config{RootCAs: pki.caPool}))
+	assert.Nil(err, "Error should be nil.")
+	st.Close()
+
+	st, err = OpenURL("rediss://" + srv.addr() + "?tls_ca=" + pki.caFile)
+	assert.Nil(err, "Error should be nil.")
+	st.Close()
+
+	_, err = Open(srv.addr(), WithTLSCAFile(filepath.Join(t.TempDir(), "missing.pem")))
+	assert.ErrorMatch(err, "tls ca file", "a missing CA file should fail fast")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestTLSServerName
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestTLSServerName(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	pki := newTestPKI(t, nil, "redis.test")
+	srv := newFakeTLSServer(t, pki, false)
+
+	_, err := Open(srv.addr(), WithTLSCAFile(pki.caFile))
+	assert.NotNil(err, "the certificate is not valid for 127.0.0.1")
+
+	st, err := Open(srv.addr(), WithTLSCAFile(pki.caFile), WithTLSServerName("redis.test"))
+	assert.Nil(err, "Error should be nil.")
+	st.Close()
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestMutualTLS
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestMutualTLS(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	pki := newTestPKI(t, []net.IP{net.ParseIP("127.0.0.1")})
+	srv := newFakeTLSServer(t, pki, true)
+
+	_, err := Open(srv.addr(), WithTLSCAFile(pki.caFile))
+	assert.NotNil(err, "a missing client certificate must be rejected")
+
+	st, err := Open(srv.addr(), WithTLSCAFile(pki.caFile), WithTLSClientCert(pki.certFile, pki.keyFile))
+	assert.Nil(err, "Error should be nil.")
+	st.Close()
+
+	st, err = OpenURL("rediss://" + srv.addr() + "?tls_ca=" + pki.caFile +
+		"&tls_cert=" + pki.certFile + "&tls_key=" + pki.keyFile)
+	assert.Nil(err, "Error should be nil.")
+	st.Close()
+
+	_, err = Open(srv.addr(), WithTLSClientCert(pki.certFile, ""))
+	assert.ErrorMatch(err, "certificate and a key", "a client certificate needs a key")
+}
@@ -0,0 +1,386 @@
+package store
+
+import (
+	"errors"
+	"github.com/garyburd/redigo/redis"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	// txMaxAttempts is the number of times Tx runs a transaction whose watched keys change.
+	txMaxAttempts = 10
+
+	txMinBackoff = time.Millisecond
+	txMaxBackoff = 100 * time.Millisecond
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Tx is a transaction passed to the callback of Store.Tx. Reads are executed right away on the
+// connection watching the keys, writes are queued and executed atomically with MULTI/EXEC
+// once the callback has returned. The results of queued writes are available after Store.Tx
+// has returned.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Tx struct {
+	st    *Store
+	batch *Batch
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Tx runs fn in a transaction. The watchKeys are watched before fn is called, so fn is able
+// to read their current values and queue writes depending on them. If a watched key is
+// modified before the writes are executed, the transaction is discarded and fn is called again
+// after a short backoff. ErrTxConflict is returned once all attempts have failed.
+//
+//	err := st.Tx([]string{"todo", "done"}, func(tx *store.Tx) error {
+//		var task Task
+//		if err := tx.HashGetInto("todo", id, &task); err != nil {
+//			return err
+//		}
+//
+//		tx.HashDeleteField("todo", id)
+//		tx.HashSet("done", id, task)
+//		tx.SortedSetSet("done:index", float64(time.Now().Unix()), id)
+//		return nil
+//	})
+//
+// An error returned by fn discards the transaction and is returned as is. Otherwise Tx returns
+// the first error of the queued writes; like redis, EXEC does not roll back the other writes.
+// On a cluster all watched and written keys must map to the same slot, see TaggedKey.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) Tx(watchKeys []string, fn func(tx *Tx) error) error {
+	keys := make([]interface{}, len(watchKeys))
+	for i, key := range watchKeys {
+		keys[i] = key
+	}
+
+	return s.runTx(keys, keys, fn)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// multi executes the writes queued by fn atomically, without watching any key. On a cluster
+// the transaction is sent to the node owning key.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) multi(key string, fn func(tx *Tx)) error {
+	return s.runTx([]interface{}{key}, nil, func(tx *Tx) error {
+		fn(tx)
+		return nil
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// runTx runs a transaction watching the keys watch until it succeeds or all attempts have
+// failed. On a cluster it is routed by the keys route.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) runTx(route, watch []interface{}, fn func(tx *Tx) error) error {
+	for attempt := 1; ; attempt++ {
+		pool := s.Pool
+		if s.cluster != nil {
+			if len(route) == 0 {
+				return errors.New("store: a transaction on a cluster needs watch keys to be routed")
+			}
+
+			addr, err := s.cluster.route("WATCH", route)
+			if err != nil {
+				return err
+			}
+
+			pool = s.cluster.pool(addr)
+		}
+
+		var result error
+		err := s.execPool(pool, func(conn redis.Conn) (err error) {
+			result, err = s.tx(conn, watch, fn)
+			return err
+		})
+
+		if s.cluster != nil {
+			if kind, slot, target, redirected := parseRedirect(err, ""); redirected && kind == "MOVED" {
+				s.cluster.moved(slot, target)
+				err, result = nil, ErrTxConflict
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if result != ErrTxConflict || attempt == txMaxAttempts {
+			return result
+		}
+
+		if err := s.txBackoff(attempt); err != nil {
+			return err
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// tx runs a single attempt of a transaction on conn. It returns the outcome of the transaction
+// as result and errors of the connection, including READONLY, as err.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) tx(conn redis.Conn, keys []interface{}, fn func(tx *Tx) error) (result, err error) {
+	if len(keys) > 0 {
+		if _, err := conn.Do("WATCH", keys...); err != nil {
+			return nil, err
+		}
+	}
+
+	st := *s
+	st.conn = conn
+	tx := &Tx{st: &st, batch: st.NewBatch()}
+
+	// Queued writes not executed fail with the error of the attempt.
+	executed := false
+	defer func() {
+		for _, op := range tx.batch.ops {
+			if !executed && op.err == nil {
+				op.err = result
+				if err != nil {
+					op.err = mapError(err)
+				}
+			}
+
+			op.done = true
+		}
+	}()
+
+	result = fn(tx)
+	ops := tx.batch.ops
+	for _, op := range ops {
+		if result == nil && op.err != nil {
+			result = op.err
+		}
+	}
+
+	if result != nil {
+		_, err = conn.Do("UNWATCH")
+		return result, err
+	}
+
+	if err := conn.Send("MULTI"); err != nil {
+		return nil, err
+	}
+
+	for _, op := range ops {
+		if err := conn.Send(op.cmd, op.args...); err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := conn.Do("EXEC")
+	if err != nil {
+		rerr, isReply := err.(redis.Error)
+		if !isReply || strings.HasPrefix(string(rerr), "READONLY") {
+			return nil, err
+		}
+
+		result = mapError(err)
+	} else if reply == nil {
+		result = ErrTxConflict
+	}
+
+	if result != nil {
+		return result, nil
+	}
+
+	replies, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	executed = true
+	for i, op := range ops {
+		if rerr, isReply := replies[i].(redis.Error); isReply {
+			op.err = mapError(rerr)
+			if result == nil {
+				result = op.err
+			}
+		} else {
+			op.reply = replies[i]
+		}
+	}
+
+	return result, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// txBackoff waits before attempt+1 of a transaction, exponentially longer with every attempt
+// and jittered so that competing clients do not collide again.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Store) txBackoff(attempt int) error {
+	d := txMinBackoff << uint(attempt)
+	if d > txMaxBackoff {
+		d = txMaxBackoff
+	}
+
+	t := time.NewTimer(d/2 + time.Duration(rand.Int63n(int64(d/2))))
+	defer t.Stop()
+
+	ctx := s.Context()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Get returns the current value of key, see Store.Get.
+func (tx *Tx) Get(key string) (interface{}, error) {
+	return tx.st.Get(key)
+}
+
+// GetInto decodes the current value of key, see Store.GetInto.
+func (tx *Tx) GetInto(key string, dst interface{}) error {
+	return tx.st.GetInto(key, dst)
+}
+
+// HashGet returns the current value of a hash field, see Store.HashGet.
+func (tx *Tx) HashGet(hash, key string) (interface{}, error) {
+	return tx.st.HashGet(hash, key)
+}
+
+// HashGetInto decodes the current value of a hash field, see Store.HashGetInto.
+func (tx *Tx) HashGetInto(hash, key string, dst interface{}) error {
+	return tx.st.HashGetInto(hash, key, dst)
+}
+
+// HashGetAllInto decodes the current values of a hash, see Store.HashGetAllInto.
+func (tx *Tx) HashGetAllInto(hash string, dst interface{}) error {
+	return tx.st.HashGetAllInto(hash, dst)
+}
+
+// HashSize returns the current number of fields of a hash, see Store.HashSize.
+func (tx *Tx) HashSize(hash string) (int, error) {
+	return tx.st.HashSize(hash)
+}
+
+// SortedSetSize returns the current number of members in a score range, see Store.SortedSetSize.
+func (tx *Tx) SortedSetSize(set string, scoreMin float64, scoreMax float64) (int, error) {
+	return tx.st.SortedSetSize(set, scoreMin, scoreMax)
+}
+
+// ListRangeInto decodes the current elements of a list range, see Store.ListRangeInto.
+func (tx *Tx) ListRangeInto(list string, start, stop int, dst interface{}) error {
+	return tx.st.ListRangeInto(list, start, stop, dst)
+}
+
+// ListLen returns the current length of a list, see Store.ListLen.
+func (tx *Tx) ListLen(list string) (int, error) {
+	return tx.st.ListLen(list)
+}
+
+// SetMembersInto decodes the current members of a set, see Store.SetMembersInto.
+func (tx *Tx) SetMembersInto(set string, dst interface{}) error {
+	return tx.st.SetMembersInto(set, dst)
+}
+
+// SetIsMember reports whether member is currently a member of a set, see Store.SetIsMember.
+func (tx *Tx) SetIsMember(set string, member interface{}) (bool, error) {
+	return tx.st.SetIsMember(set, member)
+}
+
+// SetSize returns the current number of members of a set, see Store.SetSize.
+func (tx *Tx) SetSize(set string) (int, error) {
+	return tx.st.SetSize(set)
+}
+
+// SortedSetGetAscInto decodes the current members of a score range, see Store.SortedSetGetAscInto.
+func (tx *Tx) SortedSetGetAscInto(set string, scoreMin float64, scoreMax float64, dst interface{}) error {
+	return tx.st.SortedSetGetAscInto(set, scoreMin, scoreMax, dst)
+}
+
+// Set queues Store.Set.
+func (tx *Tx) Set(key string, value interface{}) *BatchResult {
+	return tx.batch.Set(key, value)
+}
+
+// SetWithTTL queues Store.SetWithTTL.
+//
+// Deprecated: use SetWithExpiry.
+func (tx *Tx) SetWithTTL(key string, value interface{}, ttl int) *BatchResult {
+	return tx.batch.SetWithTTL(key, value, ttl)
+}
+
+// SetWithExpiry queues Store.SetWithExpiry.
+func (tx *Tx) SetWithExpiry(key string, value interface{}, ttl time.Duration) *BatchResult {
+	return tx.batch.SetWithExpiry(key, value, ttl)
+}
+
+// Delete queues Store.Delete.
+func (tx *Tx) Delete(key string) *BatchResult {
+	return tx.batch.Delete(key)
+}
+
+// HashSet queues Store.HashSet.
+func (tx *Tx) HashSet(hash, key string, value interface{}) *BatchResult {
+	return tx.batch.HashSet(hash, key, value)
+}
+
+// HashIncrBy queues Store.HashIncrBy, use Int of the result.
+func (tx *Tx) HashIncrBy(hash, field string, n int64) *BatchResult {
+	return tx.batch.HashIncrBy(hash, field, n)
+}
+
+// HashDeleteField queues Store.HashDeleteField, use Int of the result.
+func (tx *Tx) HashDeleteField(hash, field string) *BatchResult {
+	return tx.batch.HashDeleteField(hash, field)
+}
+
+// ListPush queues Store.ListPush.
+//
+// Deprecated: use ListPushLeft.
+func (tx *Tx) ListPush(list, key string, value interface{}) *BatchResult {
+	return tx.batch.ListPushLeft(list, value)
+}
+
+// ListRemove queues Store.ListRemove, use Int of the result.
+func (tx *Tx) ListRemove(list string, count int, value interface{}) *BatchResult {
+	return tx.batch.ListRemove(list, count, value)
+}
+
+// ListPushLeft queues Store.ListPushLeft, use Int of the result.
+func (tx *Tx) ListPushLeft(list string, values ...interface{}) *BatchResult {
+	return tx.batch.ListPushLeft(list, values...)
+}
+
+// ListPushRight queues Store.ListPushRight, use Int of the result.
+func (tx *Tx) ListPushRight(list string, values ...interface{}) *BatchResult {
+	return tx.batch.ListPushRight(list, values...)
+}
+
+// SetSet queues Store.SetSet, use Int of the result.
+//
+// Deprecated: use SetAdd.
+func (tx *Tx) SetSet(set string, member string) *BatchResult {
+	return tx.batch.SetSet(set, member)
+}
+
+// SetDelete queues Store.SetDelete, use Int of the result.
+//
+// Deprecated: use SetRemove.
+func (tx *Tx) SetDelete(set string, member string) *BatchResult {
+	return tx.batch.SetDelete(set, member)
+}
+
+// SetAdd queues Store.SetAdd, use Int of the result.
+func (tx *Tx) SetAdd(set string, members ...interface{}) *BatchResult {
+	return tx.batch.SetAdd(set, members...)
+}
+
+// SetRemove queues Store.SetRemove, use Int of the result.
+func (tx *Tx) SetRemove(set string, members ...interface{}) *BatchResult {
+	return tx.batch.SetRemove(set, members...)
+}
+
+// SortedSetSet queues Store.SortedSetSet, use Int of the result.
+func (tx *Tx) SortedSetSet(set string, score float64, value interface{}) *BatchResult {
+	return tx.batch.SortedSetSet(set, score, value)
+}
+
+// SortedSetDeleteByScore queues Store.SortedSetDeleteByScore, use Int of the result.
+func (tx *Tx) SortedSetDeleteByScore(key string, scoreMin float64, scoreMax float64) *BatchResult {
+	return tx.batch.SortedSetDeleteByScore(key, scoreMin, scoreMax)
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"github.com/denkhaus/tcgl/asserts"
+	"math"
+	"testing"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestTx
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestTx(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	assert.Nil(st.HashSet("testTxTodo", "task", codecTestItem{Name: "task", Count: 1}), "Error should be nil.")
+
+	var added *BatchResult
+	err := st.Tx([]string{"testTxTodo", "testTxDone"}, func(tx *Tx) error {
+		var item codecTestItem
+		if err := tx.HashGetInto("testTxTodo", "task", &item); err != nil {
+			return err
+		}
+
+		item.Count++
+		tx.HashDeleteField("testTxTodo", "task")
+		tx.HashSet("testTxDone", "task", item)
+		added = tx.SortedSetSet("testTxIndex", 1, "task")
+		return nil
+	})
+
+	assert.Nil(err, "Error should be nil.")
+	n, err := added.Int()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "the results of the writes should be available")
+
+	_, err = st.HashGet("testTxTodo", "task")
+	assert.True(errors.Is(err, ErrNotFound), "the field should have been moved")
+
+	var item codecTestItem
+	assert.Nil(st.HashGetInto("testTxDone", "task", &item), "Error should be nil.")
+	assert.Equal(item.Count, 2, "the moved value should be written")
+
+	vals, err := st.SortedSetGetAll("testTxIndex")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(vals, []interface{}{"task"}, "the index should be written")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestTxConflict
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestTxConflict(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	assert.Nil(st.Set("testTxCounter", 1), "Error should be nil.")
+
+	attempts := 0
+	err := st.Tx([]string{"testTxCounter"}, func(tx *Tx) error {
+		attempts++
+
+		var n int
+		if err := tx.GetInto("testTxCounter", &n); err != nil {
+			return err
+		}
+
+		if attempts == 1 {
+			// A concurrent client modifying the watched key.
+			if err := st.Set("testTxCounter", 10); err != nil {
+				return err
+			}
+		}
+
+		tx.Set("testTxCounter", n+1)
+		return nil
+	})
+
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(attempts, 2, "a conflict should retry the transaction")
+
+	var n int
+	assert.Nil(st.GetInto("testTxCounter", &n), "Error should be nil.")
+	assert.Equal(n, 11, "the retry should see the concurrent write")
+
+	attempts = 0
+	var res *BatchResult
+	err = st.Tx([]string{"testTxCounter"}, func(tx *Tx) error {
+		attempts++
+		if err := st.Set("testTxCounter", attempts); err != nil {
+			return err
+		}
+
+		res = tx.Set("testTxCounter", 0)
+		return nil
+	})
+
+	assert.Equal(err, ErrTxConflict, "Tx should give up after all attempts")
+	assert.Equal(attempts, txMaxAttempts, "Tx should retry up to txMaxAttempts")
+	assert.Equal(res.Err(), ErrTxConflict, "the writes should report the conflict")
+	assert.Nil(st.GetInto("testTxCounter", &n), "Error should be nil.")
+	assert.Equal(n, txMaxAttempts, "the writes of a conflicting transaction should not run")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestTxErrors
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestTxErrors(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	failed := errors.New("failed")
+	var res *BatchResult
+	err := st.Tx(nil, func(tx *Tx) error {
+		res = tx.Set("testTxDiscarded", "value")
+		return failed
+	})
+
+	assert.Equal(err, failed, "the error of fn should be returned")
+	assert.Equal(res.Err(), failed, "the writes should report the error of fn")
+	_, err = st.Get("testTxDiscarded")
+	assert.True(errors.Is(err, ErrNotFound), "the writes should be discarded")
+
+	err = st.Tx(nil, func(tx *Tx) error {
+		tx.Set("testTxDiscarded", "value")
+		tx.Set("testTxInvalid", make(chan int))
+		return nil
+	})
+
+	assert.NotNil(err, "an encoding error should be returned")
+	_, err = st.Get("testTxDiscarded")
+	assert.True(errors.Is(err, ErrNotFound), "an encoding error should discard the transaction")
+
+	assert.Nil(st.Set("testTxString", "value"), "Error should be nil.")
+	var before, after *BatchResult
+	err = st.Tx(nil, func(tx *Tx) error {
+		before = tx.Set("testTxBefore", "value")
+		tx.SortedSetSet("testTxString", math.Pi, "member")
+		after = tx.Set("testTxAfter", "value")
+		return nil
+	})
+
+	assert.True(errors.Is(err, ErrWrongType), "Tx should return the first error of the writes")
+	assert.Nil(before.Err(), "an error should not roll back the other writes")
+	assert.Nil(after.Err(), "an error should not roll back the other writes")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	err = st.WithContext(ctx).Tx(nil, func(tx *Tx) error {
+		called = true
+		return nil
+	})
+
+	assert.Equal(err, context.Canceled, "a cancelled context should fail Tx")
+	assert.False(called, "fn should not be called with a cancelled context")
+
+	fc := newFakeCluster(t)
+	cst, err := OpenCluster([]string{fc.nodes[0].addr()})
+	assert.Nil(err, "Error should be nil.")
+	defer cst.Close()
+
+	assert.NotNil(cst.Tx(nil, func(tx *Tx) error { return nil }), "a cluster transaction without keys should fail")
+}
@@ -0,0 +1,575 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Value is a typed handle for a single key.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Value[T any] struct {
+	store Backend
+	key   string
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// NewValue returns a handle for the value stored at key.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func NewValue[T any](st Backend, key string) *Value[T] {
+	return &Value[T]{store: st, key: key}
+}
+
+// Key returns the key of the value.
+func (v *Value[T]) Key() string {
+	return v.key
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Get the stored value. A missing value will return the zero value and ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (v *Value[T]) Get() (T, error) {
+	var out T
+	err := v.store.GetInto(v.key, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Set the value. Any previous time to live is discarded.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (v *Value[T]) Set(value T) error {
+	return v.store.Set(v.key, value)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Set the value with a ttl in seconds.
+//
+// Deprecated: use SetWithExpiry.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (v *Value[T]) SetWithTTL(value T, ttl int) error {
+	return v.store.SetWithTTL(v.key, value, ttl)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Set the value expiring after ttl, see Store.SetWithExpiry.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (v *Value[T]) SetWithExpiry(value T, ttl time.Duration) error {
+	return v.store.SetWithExpiry(v.key, value, ttl)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// SetNX sets the value only if it does not exist, see Store.SetNX.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (v *Value[T]) SetNX(value T, ttl time.Duration) (bool, error) {
+	return v.store.SetNX(v.key, value, ttl)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// SetXX sets the value only if it exists, see Store.SetXX.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (v *Value[T]) SetXX(value T, ttl time.Duration) (bool, error) {
+	return v.store.SetXX(v.key, value, ttl)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// SetKeepTTL sets the value and keeps its time to live.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (v *Value[T]) SetKeepTTL(value T) error {
+	return v.store.SetKeepTTL(v.key, value)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// GetSet replaces the value and returns the previous one, found is false if there was none.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (v *Value[T]) GetSet(value T) (old T, found bool, err error) {
+	found, err = v.store.GetSetInto(v.key, value, &old)
+	return old, found, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// GetDel gets and deletes the value. A missing value will return the zero value and
+// ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (v *Value[T]) GetDel() (T, error) {
+	var out T
+	err := v.store.GetDelInto(v.key, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Delete the value.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (v *Value[T]) Delete() error {
+	return v.store.Delete(v.key)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Hash is a typed handle for a hash whose values are all of type T.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Hash[T any] struct {
+	store Backend
+	name  string
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// NewHash returns a handle for the hash stored at name.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func NewHash[T any](st Backend, name string) *Hash[T] {
+	return &Hash[T]{store: st, name: name}
+}
+
+// Name returns the key of the hash.
+func (h *Hash[T]) Name() string {
+	return h.name
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Get a value from the hash. A missing value will return the zero value and ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (h *Hash[T]) Get(field string) (T, error) {
+	var out T
+	err := h.store.HashGetInto(h.name, field, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Set a field of the hash. An optional ttl lets a new hash expire, see Store.HashSet.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (h *Hash[T]) Set(field string, value T, ttl ...time.Duration) error {
+	return h.store.HashSet(h.name, field, value, ttl...)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Get all fields of the hash.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (h *Hash[T]) Fields() ([]string, error) {
+	return h.store.HashGetFields(h.name)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Get all values of the hash.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (h *Hash[T]) Values() ([]T, error) {
+	var out []T
+	err := h.store.HashGetValuesInto(h.name, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Get all fields and values of the hash.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (h *Hash[T]) All() (map[string]T, error) {
+	var out map[string]T
+	err := h.store.HashGetAllInto(h.name, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the number of fields contained in the hash.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (h *Hash[T]) Size() (int, error) {
+	return h.store.HashSize(h.name)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes a field from the hash. Returns the number of fields that were removed.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (h *Hash[T]) DeleteField(field string) (int, error) {
+	return h.store.HashDeleteField(h.name, field)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Enumerate all values of the hash.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (h *Hash[T]) Enumerate(enumerate func(value T) error) error {
+	vals, err := h.Values()
+	if err != nil {
+		return err
+	}
+
+	for _, val := range vals {
+		if err := enumerate(val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Delete the whole hash.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (h *Hash[T]) Delete() error {
+	return h.store.Delete(h.name)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// List is a typed handle for a list whose elements are all of type T.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type List[T any] struct {
+	store Backend
+	name  string
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// NewList returns a handle for the list stored at name.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func NewList[T any](st Backend, name string) *List[T] {
+	return &List[T]{store: st, name: name}
+}
+
+// Name returns the key of the list.
+func (l *List[T]) Name() string {
+	return l.name
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Pushes a value to the head of the list, see Store.ListPush.
+//
+// Deprecated: use PushLeft.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) Push(key string, value T) error {
+	return l.store.ListPush(l.name, key, value)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Pushes values to the head of the list and returns its new length, see Store.ListPushLeft.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) PushLeft(values ...T) (int, error) {
+	return l.store.ListPushLeft(l.name, listValues(values)...)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Appends values to the tail of the list and returns its new length.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) PushRight(values ...T) (int, error) {
+	return l.store.ListPushRight(l.name, listValues(values)...)
+}
+
+func listValues[T any](values []T) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, value := range values {
+		out[i] = value
+	}
+
+	return out
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes and returns the head of the list. An empty list will return the zero value and
+// ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) PopLeft() (T, error) {
+	var out T
+	err := l.store.ListPopLeftInto(l.name, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes and returns the tail of the list. An empty list will return the zero value and
+// ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) PopRight() (T, error) {
+	var out T
+	err := l.store.ListPopRightInto(l.name, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes and returns the head of the list, waiting up to timeout for a value to be pushed, see
+// Store.ListBlockingPop.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) BlockingPopLeft(ctx context.Context, timeout time.Duration) (T, error) {
+	var out T
+	_, err := l.store.ListBlockingPopInto(ctx, timeout, &out, l.name)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes and returns the tail of the list, waiting up to timeout for a value to be pushed.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) BlockingPopRight(ctx context.Context, timeout time.Duration) (T, error) {
+	var out T
+	_, err := l.store.ListBlockingPopRightInto(ctx, timeout, &out, l.name)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the element at index, see Store.ListIndex. An index out of range will return the
+// zero value and ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) Index(index int) (T, error) {
+	var out T
+	err := l.store.ListIndexInto(l.name, index, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the elements from start to stop (inclusive), see Store.ListRange.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) Range(start, stop int) ([]T, error) {
+	var out []T
+	err := l.store.ListRangeInto(l.name, start, stop, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns all elements of the list.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) All() ([]T, error) {
+	return l.Range(0, -1)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the length of the list.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) Len() (int, error) {
+	return l.store.ListLen(l.name)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Replaces the element at index, see Store.ListSet.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) Set(index int, value T) error {
+	return l.store.ListSet(l.name, index, value)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes elements equal to value and returns their number, see Store.ListRemove.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) Remove(count int, value T) (int, error) {
+	return l.store.ListRemove(l.name, count, value)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Trims the list to the elements from start to stop (inclusive), see Store.ListTrim.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) Trim(start, stop int) error {
+	return l.store.ListTrim(l.name, start, stop)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Inserts value before or after pivot and returns the new length, see Store.ListInsert.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) Insert(before bool, pivot, value T) (int, error) {
+	return l.store.ListInsert(l.name, before, pivot, value)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Delete the whole list.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (l *List[T]) Delete() error {
+	return l.store.Delete(l.name)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Set is a typed handle for a set whose members are all of type T.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type Set[T any] struct {
+	store Backend
+	name  string
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// NewSet returns a handle for the set stored at name.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func NewSet[T any](st Backend, name string) *Set[T] {
+	return &Set[T]{store: st, name: name}
+}
+
+// Name returns the key of the set.
+func (s *Set[T]) Name() string {
+	return s.name
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Adds members and returns the number of new members, see Store.SetAdd.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Set[T]) Add(members ...T) (int, error) {
+	return s.store.SetAdd(s.name, listValues(members)...)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes members and returns the number of removed members.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Set[T]) Remove(members ...T) (int, error) {
+	return s.store.SetRemove(s.name, listValues(members)...)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns all members in no particular order.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Set[T]) Members() ([]T, error) {
+	var out []T
+	err := s.store.SetMembersInto(s.name, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Reports whether member is a member of the set.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Set[T]) Contains(member T) (bool, error) {
+	return s.store.SetIsMember(s.name, member)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the number of members.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Set[T]) Len() (int, error) {
+	return s.store.SetSize(s.name)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns a random member. An empty set will return the zero value and ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Set[T]) Random() (T, error) {
+	var out T
+	err := s.store.SetRandomMemberInto(s.name, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes and returns a random member. An empty set will return the zero value and
+// ErrNotFound.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Set[T]) Pop() (T, error) {
+	var out T
+	err := s.store.SetPopInto(s.name, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Moves member to the set destination, see Store.SetMove.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Set[T]) Move(destination *Set[T], member T) (bool, error) {
+	return s.store.SetMove(s.name, destination.name, member)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the members contained in the set and all others.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Set[T]) Inter(others ...*Set[T]) ([]T, error) {
+	var out []T
+	err := s.store.SetInterInto(&out, s.with(others)...)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the members contained in the set or any of the others.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Set[T]) Union(others ...*Set[T]) ([]T, error) {
+	var out []T
+	err := s.store.SetUnionInto(&out, s.with(others)...)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the members of the set not contained in any of the others.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Set[T]) Diff(others ...*Set[T]) ([]T, error) {
+	var out []T
+	err := s.store.SetDiffInto(&out, s.with(others)...)
+	return out, err
+}
+
+// with returns the names of the set followed by the others.
+func (s *Set[T]) with(others []*Set[T]) []string {
+	names := make([]string, 0, len(others)+1)
+	names = append(names, s.name)
+	for _, other := range others {
+		names = append(names, other.name)
+	}
+
+	return names
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Delete the whole set.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (s *Set[T]) Delete() error {
+	return s.store.Delete(s.name)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// SortedSet is a typed handle for a sorted set whose elements are all of type T.
+////////////////////////////////////////////////////////////////////////////////////////////////
+type SortedSet[T any] struct {
+	store Backend
+	name  string
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// NewSortedSet returns a handle for the sorted set stored at name.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func NewSortedSet[T any](st Backend, name string) *SortedSet[T] {
+	return &SortedSet[T]{store: st, name: name}
+}
+
+// Name returns the key of the sorted set.
+func (z *SortedSet[T]) Name() string {
+	return z.name
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Sets score and value in the sorted set. An optional ttl lets a new set expire, see
+// Store.HashSet.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (z *SortedSet[T]) Set(score float64, value T, ttl ...time.Duration) (int, error) {
+	return z.store.SortedSetSet(z.name, score, value, ttl...)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns the number of elements between the specified scores.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (z *SortedSet[T]) Size(scoreMin float64, scoreMax float64) (int, error) {
+	return z.store.SortedSetSize(z.name, scoreMin, scoreMax)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns values between the specified scores, same as GetAsc.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (z *SortedSet[T]) Get(scoreMin float64, scoreMax float64) ([]T, error) {
+	return z.GetAsc(scoreMin, scoreMax)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns values between the specified scores from the lowest to the highest score.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (z *SortedSet[T]) GetAsc(scoreMin float64, scoreMax float64) ([]T, error) {
+	var out []T
+	err := z.store.SortedSetGetAscInto(z.name, scoreMin, scoreMax, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns values between the specified scores from the highest to the lowest score.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (z *SortedSet[T]) GetDesc(scoreMin float64, scoreMax float64) ([]T, error) {
+	var out []T
+	err := z.store.SortedSetGetDescInto(z.name, scoreMin, scoreMax, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Returns all values of the sorted set.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (z *SortedSet[T]) GetAll() ([]T, error) {
+	var out []T
+	err := z.store.SortedSetGetAllInto(z.name, &out)
+	return out, err
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Removes all elements with a score between min and max (inclusive).
+// Returns the number of elements removed.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (z *SortedSet[T]) DeleteByScore(scoreMin float64, scoreMax float64) (int, error) {
+	return z.store.SortedSetDeleteByScore(z.name, scoreMin, scoreMax)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// Delete the whole sorted set.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (z *SortedSet[T]) Delete() error {
+	return z.store.Delete(z.name)
+}
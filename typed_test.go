@@ -0,0 +1,208 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/denkhaus/tcgl/asserts"
+	"testing"
+	"time"
+)
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestTypedValue
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestTypedValue(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	val := NewValue[codecTestItem](st, "testTypedValue")
+
+	err := val.Set(codecTestItem{Name: "value", Count: 1})
+	assert.Nil(err, "Error should be nil.")
+
+	res, err := val.Get()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(res, codecTestItem{Name: "value", Count: 1}, "typed value: wrong value")
+
+	err = val.Delete()
+	assert.Nil(err, "Error should be nil.")
+
+	_, err = val.Get()
+	assert.NotNil(err, "typed value: missing value should fail")
+
+	ok, err := val.SetNX(codecTestItem{Name: "first"}, 0)
+	assert.Nil(err, "Error should be nil.")
+	assert.True(ok, "typed value: SetNX should store a missing value")
+
+	old, found, err := val.GetSet(codecTestItem{Name: "second"})
+	assert.Nil(err, "Error should be nil.")
+	assert.True(found, "typed value: GetSet should find the previous value")
+	assert.Equal(old.Name, "first", "typed value: GetSet should decode the previous value")
+
+	res, err = val.GetDel()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(res.Name, "second", "typed value: GetDel should decode the value")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestTypedHash
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestTypedHash(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	hash := NewHash[codecTestItem](st, "testTypedHash")
+
+	err := hash.Delete()
+	assert.Nil(err, "Error should be nil.")
+
+	for i := 0; i < 3; i++ {
+		field := fmt.Sprintf("hashfield%d", i)
+		err := hash.Set(field, codecTestItem{Name: field, Count: i})
+		assert.Nil(err, "Error should be nil.")
+	}
+
+	res, err := hash.Get("hashfield2")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(res, codecTestItem{Name: "hashfield2", Count: 2}, "typed hash: wrong value")
+
+	vals, err := hash.Values()
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(vals, 3, "typed hash: wrong number of values")
+
+	all, err := hash.All()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(all["hashfield0"], codecTestItem{Name: "hashfield0", Count: 0}, "typed hash: wrong map value")
+
+	count := 0
+	err = hash.Enumerate(func(value codecTestItem) error {
+		count += value.Count
+		return nil
+	})
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(count, 3, "typed hash: wrong enumeration")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestTypedList
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestTypedList(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	list := NewList[codecTestItem](st, "testTypedList")
+
+	err := list.Delete()
+	assert.Nil(err, "Error should be nil.")
+
+	n, err := list.PushRight(codecTestItem{Name: "b"}, codecTestItem{Name: "c"})
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "typed list: wrong length")
+
+	_, err = list.PushLeft(codecTestItem{Name: "a"})
+	assert.Nil(err, "Error should be nil.")
+
+	res, err := list.All()
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(res, 3, "typed list: wrong number of values")
+	assert.Equal(res[0].Name, "a", "typed list: wrong head")
+
+	item, err := list.PopRight()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(item.Name, "c", "typed list: wrong tail")
+
+	item, err = list.Index(1)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(item.Name, "b", "typed list: wrong element")
+
+	item, err = list.BlockingPopLeft(context.Background(), time.Second)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(item.Name, "a", "typed list: wrong head")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestTypedSet
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestTypedSet(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	a := NewSet[string](st, "{testTypedSet}:a")
+	b := NewSet[string](st, "{testTypedSet}:b")
+	assert.Nil(a.Delete(), "Error should be nil.")
+	assert.Nil(b.Delete(), "Error should be nil.")
+
+	n, err := a.Add("x", "y", "z")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 3, "typed set: wrong number of added members")
+
+	_, err = b.Add("y")
+	assert.Nil(err, "Error should be nil.")
+
+	found, err := a.Contains("x")
+	assert.Nil(err, "Error should be nil.")
+	assert.True(found, "typed set: member not found")
+
+	inter, err := a.Inter(b)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(inter, []string{"y"}, "typed set: wrong intersection")
+
+	moved, err := a.Move(b, "z")
+	assert.Nil(err, "Error should be nil.")
+	assert.True(moved, "typed set: member not moved")
+
+	diff, err := a.Diff(b)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(diff, []string{"x"}, "typed set: wrong difference")
+
+	union, err := a.Union(b)
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(union, 3, "typed set: wrong union")
+
+	member, err := b.Pop()
+	assert.Nil(err, "Error should be nil.")
+	assert.True(member == "y" || member == "z", "typed set: wrong popped member")
+
+	n, err = b.Len()
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 1, "typed set: Pop should remove the member")
+
+	n, err = a.Remove("x", "y")
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(n, 2, "typed set: wrong number of removed members")
+
+	members, err := a.Members()
+	assert.Nil(err, "Error should be nil.")
+	assert.Length(members, 0, "typed set: should be empty")
+
+	_, err = a.Random()
+	assert.True(errors.Is(err, ErrNotFound), "typed set: Random of an empty set should return ErrNotFound")
+}
+
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+// TestTypedSortedSet
+/////////////////////////////////////////////////////////////////////////////////////////////////////
+func TestTypedSortedSet(t *testing.T) {
+	assert := asserts.NewTestingAsserts(t, true)
+	st := createStore(t)
+	defer st.Close()
+
+	set := NewSortedSet[string](st, "testTypedSortedSet")
+
+	err := set.Delete()
+	assert.Nil(err, "Error should be nil.")
+
+	for i := 0; i < 3; i++ {
+		_, err := set.Set(float64(i), fmt.Sprintf("setvalue%d", i))
+		assert.Nil(err, "Error should be nil.")
+	}
+
+	res, err := set.GetDesc(0, 2)
+	assert.Nil(err, "Error should be nil.")
+	assert.Equal(res, []string{"setvalue2", "setvalue1", "setvalue0"}, "typed sorted set: wrong values")
+}
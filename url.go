@@ -0,0 +1,182 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvURL is the environment variable read by FromEnv.
+const EnvURL = "REDIS_URL"
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// OpenURL returns a Store configured by a connection URL of the form
+//
+//	redis://[user[:password]@][host][:port][/db][?param=value...]
+//	rediss://... (same as redis, over TLS)
+//	unix://[user[:password]@]/path/to/redis.sock[?db=N&param=value...]
+//
+// Supported parameters are db, pool_size (alias max_active), max_idle, wait, idle_timeout,
+// dial_timeout, read_timeout, write_timeout, keep_alive, client_name, codec and the TLS
+// parameters tls_ca, tls_cert, tls_key, tls_server_name and tls_insecure_skip_verify, which
+// take file names where needed and enable TLS. Timeouts are Go durations like "500ms" or a
+// number of seconds. opts are applied after the URL and override it.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func OpenURL(rawurl string, opts ...Option) (*Store, error) {
+	o, err := parseURL(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return open(o)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// FromEnv returns a Store configured by the connection URL in the REDIS_URL environment
+// variable, see OpenURL.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func FromEnv(opts ...Option) (*Store, error) {
+	rawurl := os.Getenv(EnvURL)
+	if rawurl == "" {
+		return nil, fmt.Errorf("store: environment variable %s is not set", EnvURL)
+	}
+
+	return OpenURL(rawurl, opts...)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// parseURL converts a connection URL into options.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func parseURL(rawurl string) (*options, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("store: invalid url: %v", err)
+	}
+
+	o := defaultOptions("")
+
+	switch u.Scheme {
+	case "redis", "rediss":
+		host, port := u.Hostname(), u.Port()
+		if host == "" {
+			host = "localhost"
+		}
+
+		if port == "" {
+			port = "6379"
+		}
+
+		o.address = net.JoinHostPort(host, port)
+		o.useTLS = u.Scheme == "rediss"
+
+		if path := strings.Trim(u.Path, "/"); path != "" {
+			if o.db, err = strconv.Atoi(path); err != nil {
+				return nil, fmt.Errorf("store: invalid database index %q in url", path)
+			}
+		}
+	case "unix":
+		o.network = "unix"
+		o.address = u.Path
+	default:
+		return nil, fmt.Errorf("store: unsupported url scheme %q", u.Scheme)
+	}
+
+	if u.User != nil {
+		password, ok := u.User.Password()
+		if ok {
+			o.username = u.User.Username()
+			o.password = password
+		} else {
+			o.password = u.User.Username()
+		}
+	}
+
+	for key, values := range u.Query() {
+		if err := o.setParam(key, values[len(values)-1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return o, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// setParam applies a single url query parameter.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func (o *options) setParam(key, value string) error {
+	var err error
+
+	switch key {
+	case "db":
+		o.db, err = strconv.Atoi(value)
+	case "pool_size", "max_active":
+		o.maxActive, err = strconv.Atoi(value)
+	case "max_idle":
+		o.maxIdle, err = strconv.Atoi(value)
+	case "wait":
+		o.wait, err = strconv.ParseBool(value)
+	case "idle_timeout":
+		o.idleTimeout, err = parseURLDuration(value)
+	case "dial_timeout":
+		o.connectTimeout, err = parseURLDuration(value)
+	case "read_timeout":
+		o.readTimeout, err = parseURLDuration(value)
+	case "write_timeout":
+		o.writeTimeout, err = parseURLDuration(value)
+	case "keep_alive":
+		o.keepAlive, err = parseURLDuration(value)
+	case "client_name":
+		o.clientName = value
+	case "tls_ca":
+		o.useTLS, o.tlsCAFile = true, value
+	case "tls_cert":
+		o.useTLS, o.tlsCertFile = true, value
+	case "tls_key":
+		o.useTLS, o.tlsKeyFile = true, value
+	case "tls_server_name":
+		o.useTLS, o.tlsServerName = true, value
+	case "tls_insecure_skip_verify":
+		o.useTLS = true
+		o.tlsSkipVerify, err = strconv.ParseBool(value)
+	case "codec":
+		codec, ok := LookupCodec(value)
+		if !ok {
+			return fmt.Errorf("store: unknown codec %q in url", value)
+		}
+
+		o.codec = codec
+	default:
+		return fmt.Errorf("store: unknown url parameter %q", key)
+	}
+
+	if err != nil {
+		return fmt.Errorf("store: invalid value %q for url parameter %s", value, key)
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////
+// parseURLDuration accepts a Go duration or a plain number of seconds.
+////////////////////////////////////////////////////////////////////////////////////////////////
+func parseURLDuration(value string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.New("invalid duration")
+	}
+
+	return d, nil
+}